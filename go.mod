@@ -4,8 +4,10 @@ go 1.16
 
 require (
 	github.com/blang/semver/v4 v4.0.0
+	github.com/deislabs/oras v0.8.1
 	github.com/fatih/structtag v1.1.0
 	github.com/go-logr/logr v0.3.0
+	github.com/hashicorp/golang-lru v0.5.4
 	github.com/iancoleman/strcase v0.0.0-20191112232945-16388991a334
 	github.com/kr/text v0.1.0
 	github.com/markbates/inflect v1.0.4
@@ -42,6 +44,7 @@ require (
 	sigs.k8s.io/controller-runtime v0.8.3
 	sigs.k8s.io/controller-tools v0.5.0
 	sigs.k8s.io/kubebuilder/v3 v3.0.0-beta.1
+	sigs.k8s.io/kustomize/api v0.8.0
 	sigs.k8s.io/yaml v1.2.0
 )
 
@@ -0,0 +1,112 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	ansiblestatus "github.com/operator-framework/operator-sdk/internal/ansible/controller/status"
+)
+
+// cancelCheckPeriod - the time between checks of whether the custom resource
+// of a running reconciliation has changed.
+var cancelCheckPeriod = 2 * time.Second
+
+// watchForCancellation - cancels the run for the custom resource nn, by
+// calling cancel, when the generation of the custom resource becomes greater
+// than the generation of u, or when it is marked for deletion while u is not.
+// The reason of the cancellation is sent on the returned channel before the
+// run is cancelled. Watching stops when ctx is done.
+func (r *AnsibleOperatorReconciler) watchForCancellation(ctx context.Context, nn types.NamespacedName,
+	u *unstructured.Unstructured, cancel context.CancelFunc) <-chan string {
+	reasons := make(chan string, 1)
+	generation := u.GetGeneration()
+	deleted := u.GetDeletionTimestamp() != nil
+	go func() {
+		logger := logf.Log.WithName("cancellation").WithValues("name", nn.Name, "namespace", nn.Namespace)
+		ticker := time.NewTicker(cancelCheckPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			// The cache may lag behind u, so only newer generations cancel the run.
+			current := &unstructured.Unstructured{}
+			current.SetGroupVersionKind(r.GVK)
+			var reason string
+			err := r.Client.Get(ctx, nn, current)
+			switch {
+			case apierrors.IsNotFound(err):
+				reason = "the resource was deleted"
+			case err != nil:
+				logger.Error(err, "Unable to get the resource to check for changes")
+				continue
+			case current.GetGeneration() > generation:
+				reason = fmt.Sprintf("the generation of the resource changed from %d to %d",
+					generation, current.GetGeneration())
+			case !deleted && current.GetDeletionTimestamp() != nil:
+				reason = "the resource was marked for deletion"
+			default:
+				continue
+			}
+			logger.Info("Cancelling reconciliation", "reason", reason)
+			reasons <- reason
+			cancel()
+			return
+		}
+	}()
+	return reasons
+}
+
+// markCancelled - used to record in the status that the running reconciliation
+// was cancelled, and why.
+func (r *AnsibleOperatorReconciler) markCancelled(ctx context.Context, nn types.NamespacedName,
+	u *unstructured.Unstructured, reason string) error {
+
+	logger := logf.Log.WithName("markCancelled")
+	// Get the latest resource to prevent updating a stale status.
+	if err := r.APIReader.Get(ctx, nn, u); err != nil {
+		if apierrors.IsNotFound(err) {
+			logger.Info("Resource not found, assuming it was deleted")
+			return nil
+		}
+		return err
+	}
+	crStatus := getStatus(u)
+
+	c := ansiblestatus.NewCondition(
+		ansiblestatus.RunningConditionType,
+		v1.ConditionFalse,
+		nil,
+		ansiblestatus.CancelledReason,
+		fmt.Sprintf("Reconciliation cancelled because %s", reason),
+	)
+	ansiblestatus.SetCondition(&crStatus, *c)
+	crStatus.Progress = nil
+	u.Object["status"] = crStatus.GetJSONMap()
+
+	return r.Client.Status().Update(ctx, u)
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	ansiblestatus "github.com/operator-framework/operator-sdk/internal/ansible/controller/status"
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner/fake"
+)
+
+func TestReconcileCancelled(t *testing.T) {
+	defer func(period time.Duration) { cancelCheckPeriod = period }(cancelCheckPeriod)
+	cancelCheckPeriod = time.Millisecond
+
+	gvk := schema.GroupVersionKind{Group: "operator-sdk", Version: "v1beta1", Kind: "Testing"}
+	nn := types.NamespacedName{Name: "reconcile", Namespace: "default"}
+	c := fakeclient.NewClientBuilder().WithObjects(&unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"name":       nn.Name,
+				"namespace":  nn.Namespace,
+				"generation": int64(1),
+			},
+			"apiVersion": "operator-sdk/v1beta1",
+			"kind":       "Testing",
+			"spec":       map[string]interface{}{},
+		},
+	}).Build()
+	r := &AnsibleOperatorReconciler{
+		GVK:          gvk,
+		Client:       c,
+		APIReader:    c,
+		Runner:       &fake.Runner{BlockUntilCancelled: true},
+		ManageStatus: true,
+	}
+
+	get := func() *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		if err := c.Get(context.TODO(), nn, u); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		return u
+	}
+
+	type reconcileResult struct {
+		result reconcile.Result
+		err    error
+	}
+	done := make(chan reconcileResult)
+	go func() {
+		result, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: nn})
+		done <- reconcileResult{result, err}
+	}()
+
+	// Change the spec once the run has started.
+	started := false
+	for i := 0; i < 100 && !started; i++ {
+		time.Sleep(10 * time.Millisecond)
+		if _, ok := get().Object["status"].(map[string]interface{}); ok {
+			started = true
+		}
+	}
+	if !started {
+		t.Fatal("Reconciliation did not start")
+	}
+	u := get()
+	u.SetGeneration(2)
+	if err := c.Update(context.TODO(), u); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			t.Fatalf("Unexpected error: %v", res.err)
+		}
+		if !res.result.Requeue {
+			t.Fatalf("Cancelled reconciliation should be requeued: %#v", res.result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reconciliation was not cancelled")
+	}
+
+	cond := ansiblestatus.GetCondition(getStatus(get()), ansiblestatus.RunningConditionType)
+	if cond == nil || cond.Status != v1.ConditionFalse || cond.Reason != ansiblestatus.CancelledReason {
+		t.Fatalf("Unexpected running condition: %#v", cond)
+	}
+}
+
+func TestReconcileTimedOut(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "operator-sdk", Version: "v1beta1", Kind: "Testing"}
+	nn := types.NamespacedName{Name: "reconcile", Namespace: "default"}
+	c := fakeclient.NewClientBuilder().WithObjects(&unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"metadata":   map[string]interface{}{"name": nn.Name, "namespace": nn.Namespace},
+			"apiVersion": "operator-sdk/v1beta1",
+			"kind":       "Testing",
+			"spec":       map[string]interface{}{},
+		},
+	}).Build()
+	r := &AnsibleOperatorReconciler{
+		GVK:          gvk,
+		Client:       c,
+		APIReader:    c,
+		Runner:       &fake.Runner{BlockUntilCancelled: true},
+		ManageStatus: true,
+		RunTimeout:   10 * time.Millisecond,
+	}
+
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: nn})
+	if err == nil {
+		t.Fatal("Timed out reconciliation should fail")
+	}
+
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	if err := c.Get(context.TODO(), nn, u); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cond := ansiblestatus.GetCondition(getStatus(u), ansiblestatus.FailureConditionType)
+	if cond == nil || cond.Status != v1.ConditionTrue || cond.Reason != ansiblestatus.TimeoutReason {
+		t.Fatalf("Unexpected failure condition: %#v", cond)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	r := &AnsibleOperatorReconciler{RunTimeout: time.Hour}
+	testCases := []struct {
+		annotation string
+		expected   time.Duration
+	}{
+		{"", time.Hour},
+		{"30m", 30 * time.Minute},
+		{"0s", 0},
+		{"invalid", time.Hour},
+	}
+	for _, tc := range testCases {
+		u := &unstructured.Unstructured{}
+		if tc.annotation != "" {
+			u.SetAnnotations(map[string]string{RunTimeoutAnnotation: tc.annotation})
+		}
+		if got := r.runTimeout(u); got != tc.expected {
+			t.Fatalf("Unexpected run timeout for annotation %q: %v expected %v", tc.annotation, got, tc.expected)
+		}
+	}
+}
@@ -52,6 +52,8 @@ type Options struct {
 	WatchClusterScopedResources bool
 	MaxConcurrentReconciles     int
 	Selector                    metav1.LabelSelector
+	ProgressUpdatePeriod        time.Duration
+	RunTimeout                  time.Duration
 }
 
 // Add - Creates a new ansible operator controller and adds it to the manager
@@ -72,6 +74,9 @@ func Add(mgr manager.Manager, options Options) *controller.Controller {
 		ManageStatus:     options.ManageStatus,
 		AnsibleDebugLogs: options.AnsibleDebugLogs,
 		APIReader:        mgr.GetAPIReader(),
+
+		ProgressUpdatePeriod: options.ProgressUpdatePeriod,
+		RunTimeout:           options.RunTimeout,
 	}
 
 	scheme := mgr.GetScheme()
@@ -0,0 +1,127 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	ansiblestatus "github.com/operator-framework/operator-sdk/internal/ansible/controller/status"
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner/eventapi"
+)
+
+// DefaultProgressUpdatePeriod - the default minimum time between updates of
+// the progress in the status of a custom resource.
+const DefaultProgressUpdatePeriod = 10 * time.Second
+
+// progressReporter - tracks the progress of an ansible run from its events,
+// and periodically writes it to the status of the custom resource.
+type progressReporter struct {
+	mu       sync.Mutex
+	progress ansiblestatus.Progress
+	changed  bool
+
+	stopOnce sync.Once
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+// startProgressReporter - starts reporting the progress of the run for the
+// custom resource nn, until the returned reporter is stopped.
+func (r *AnsibleOperatorReconciler) startProgressReporter(ctx context.Context, nn types.NamespacedName) *progressReporter {
+	p := &progressReporter{
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	period := r.ProgressUpdatePeriod
+	if period <= 0 {
+		period = DefaultProgressUpdatePeriod
+	}
+	go func() {
+		defer close(p.stopped)
+		logger := logf.Log.WithName("progressReporter").WithValues("name", nn.Name, "namespace", nn.Namespace)
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				progress, changed := p.take()
+				if !changed {
+					continue
+				}
+				if err := r.markProgress(ctx, nn, progress); err != nil {
+					logger.Error(err, "Unable to update the status with the progress of the run")
+				}
+			}
+		}
+	}()
+	return p
+}
+
+// observe - updates the progress with a job event.
+func (p *progressReporter) observe(event eventapi.JobEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.progress.Update(event) {
+		p.changed = true
+	}
+}
+
+// take - returns the progress, and whether it changed since it was last taken.
+func (p *progressReporter) take() (ansiblestatus.Progress, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	changed := p.changed
+	p.changed = false
+	return p.progress, changed
+}
+
+// stop - stops reporting progress, and waits for any status update in
+// flight, so that it does not overwrite later updates of the status.
+func (p *progressReporter) stop() {
+	p.stopOnce.Do(func() { close(p.done) })
+	<-p.stopped
+}
+
+// markProgress - sets the progress of the running reconciliation in the status.
+// The status is patched, rather than updated, so that progress updates do not
+// conflict with the status updates of the playbook, such as with k8s_status.
+func (r *AnsibleOperatorReconciler) markProgress(ctx context.Context, nn types.NamespacedName,
+	progress ansiblestatus.Progress) error {
+	progress.LastUpdateTime = metav1.Now()
+	patch, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{"progress": progress},
+	})
+	if err != nil {
+		return err
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(r.GVK)
+	u.SetNamespace(nn.Namespace)
+	u.SetName(nn.Name)
+	return r.Client.Status().Patch(ctx, u, client.RawPatch(types.MergePatchType, patch))
+}
@@ -0,0 +1,110 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner/eventapi"
+)
+
+func TestProgressReporter(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "operator-sdk", Version: "v1beta1", Kind: "Testing"}
+	nn := types.NamespacedName{Name: "reconcile", Namespace: "default"}
+	c := fakeclient.NewClientBuilder().WithObjects(&unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"metadata":   map[string]interface{}{"name": nn.Name, "namespace": nn.Namespace},
+			"apiVersion": "operator-sdk/v1beta1",
+			"kind":       "Testing",
+			"status":     map[string]interface{}{"custom": "value"},
+		},
+	}).Build()
+	r := &AnsibleOperatorReconciler{
+		GVK:                  gvk,
+		Client:               c,
+		APIReader:            c,
+		ProgressUpdatePeriod: time.Millisecond,
+	}
+
+	getStatus := func() map[string]interface{} {
+		u := &unstructured.Unstructured{}
+		u.SetGroupVersionKind(gvk)
+		if err := c.Get(context.TODO(), nn, u); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		status, _ := u.Object["status"].(map[string]interface{})
+		return status
+	}
+
+	p := r.startProgressReporter(context.TODO(), nn)
+	p.observe(eventapi.JobEvent{
+		Event:     eventapi.EventPlaybookOnTaskStart,
+		EventData: map[string]interface{}{"play": "localhost", "task": "create deployment"},
+	})
+
+	var progress map[string]interface{}
+	for i := 0; i < 100 && progress == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		progress, _ = getStatus()["progress"].(map[string]interface{})
+	}
+	p.stop()
+	if progress == nil {
+		t.Fatalf("Progress was not reported: %#v", getStatus())
+	}
+	if progress["task"] != "create deployment" || progress["play"] != "localhost" {
+		t.Fatalf("Unexpected progress: %#v", progress)
+	}
+	if getStatus()["custom"] != "value" {
+		t.Fatalf("Custom status should have been kept: %#v", getStatus())
+	}
+
+	// Stopping the reporter again does not block.
+	p.stop()
+}
+
+func TestMarkArtifacts(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "operator-sdk", Version: "v1beta1", Kind: "Testing"}
+	nn := types.NamespacedName{Name: "reconcile", Namespace: "default"}
+	c := fakeclient.NewClientBuilder().WithObjects(&unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"metadata":   map[string]interface{}{"name": nn.Name, "namespace": nn.Namespace},
+			"apiVersion": "operator-sdk/v1beta1",
+			"kind":       "Testing",
+			"status":     map[string]interface{}{"custom": "value"},
+		},
+	}).Build()
+	r := &AnsibleOperatorReconciler{GVK: gvk, Client: c, APIReader: c}
+
+	url := "file:///artifacts/operator-sdk/v1beta1/Testing/default/reconcile/1234"
+	if err := r.markArtifacts(context.TODO(), nn, url); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	if err := c.Get(context.TODO(), nn, u); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	status, _ := u.Object["status"].(map[string]interface{})
+	if status["artifactsURL"] != url || status["custom"] != "value" {
+		t.Fatalf("Unexpected status: %#v", status)
+	}
+}
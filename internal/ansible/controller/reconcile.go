@@ -49,6 +49,12 @@ const (
 	// To use create a CR with an annotation "ansible.sdk.operatorframework.io/reconcile-period: 30s" or some other valid
 	// Duration. This will override the operators/or controllers reconcile period for that particular CR.
 	ReconcilePeriodAnnotation = "ansible.sdk.operatorframework.io/reconcile-period"
+
+	// RunTimeoutAnnotation - annotation used by a user to specify the maximum duration of an ansible run
+	// for the CR. This will override the run timeout provided by the watches file for that particular CR.
+	// Setting this to zero disables the timeout.
+	// Example usage "ansible.sdk.operatorframework.io/run-timeout: 30m"
+	RunTimeoutAnnotation = "ansible.sdk.operatorframework.io/run-timeout"
 )
 
 // AnsibleOperatorReconciler - object to reconcile runner requests
@@ -61,6 +67,13 @@ type AnsibleOperatorReconciler struct {
 	ReconcilePeriod  time.Duration
 	ManageStatus     bool
 	AnsibleDebugLogs bool
+	// ProgressUpdatePeriod - the minimum time between updates of the progress
+	// in the status while ansible runs. DefaultProgressUpdatePeriod is used if
+	// it is not set.
+	ProgressUpdatePeriod time.Duration
+	// RunTimeout - the maximum duration of an ansible run, after which it is
+	// killed and the reconciliation fails. Runs do not time out if it is zero.
+	RunTimeout time.Duration
 }
 
 // Reconcile - handle the event.
@@ -157,7 +170,19 @@ func (r *AnsibleOperatorReconciler) Reconcile(ctx context.Context, request recon
 			logger.Error(err, "Failed to remove generated kubeconfig file")
 		}
 	}()
-	result, err := r.Runner.Run(ident, u, kc.Name())
+	// cancel the run if the resource changes or is deleted while it is running,
+	// or if it runs for longer than the run timeout
+	var runCtx context.Context
+	var cancel context.CancelFunc
+	if runTimeout := r.runTimeout(u); runTimeout > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, runTimeout)
+	} else {
+		runCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+	cancelled := r.watchForCancellation(runCtx, request.NamespacedName, u, cancel)
+
+	result, err := r.Runner.Run(runCtx, ident, u, kc.Name())
 	if err != nil {
 		errmark := r.markError(ctx, request.NamespacedName, u, "Unable to run reconciliation")
 		if errmark != nil {
@@ -167,6 +192,13 @@ func (r *AnsibleOperatorReconciler) Reconcile(ctx context.Context, request recon
 		return reconcileResult, err
 	}
 
+	// report the progress of the run in the status while it is running
+	var progress *progressReporter
+	if r.ManageStatus {
+		progress = r.startProgressReporter(ctx, request.NamespacedName)
+		defer progress.stop()
+	}
+
 	// iterate events from ansible, looking for the final one
 	statusEvent := eventapi.StatusJobEvent{}
 	failureMessages := eventapi.FailureMessages{}
@@ -174,6 +206,9 @@ func (r *AnsibleOperatorReconciler) Reconcile(ctx context.Context, request recon
 		for _, eHandler := range r.EventHandlers {
 			go eHandler.Handle(ident, u, event)
 		}
+		if progress != nil {
+			progress.observe(event)
+		}
 		if event.Event == eventapi.EventPlaybookOnStats {
 			// convert to StatusJobEvent; would love a better way to do this
 			data, err := json.Marshal(event)
@@ -209,6 +244,45 @@ func (r *AnsibleOperatorReconciler) Reconcile(ctx context.Context, request recon
 		}
 	}
 
+	if progress != nil {
+		progress.stop()
+	}
+	// record where the artifacts of the run were exported, whatever its outcome
+	if url := result.ArtifactsURL(); url != "" && r.ManageStatus {
+		if err := r.markArtifacts(ctx, request.NamespacedName, url); err != nil && !apierrors.IsNotFound(err) {
+			logger.Error(err, "Unable to update the status with the URL of the exported artifacts")
+		}
+	}
+	// the run timed out if it was cancelled on its deadline before it completed
+	timedOut := errors.Is(runCtx.Err(), context.DeadlineExceeded) && statusEvent.Event == ""
+	cancel()
+	select {
+	case reason := <-cancelled:
+		logger.Info("Reconciliation cancelled", "reason", reason)
+		if r.ManageStatus {
+			errmark := r.markCancelled(ctx, request.NamespacedName, u, reason)
+			if errmark != nil {
+				logger.Error(errmark, "Unable to update the status to mark cr as cancelled")
+			}
+		}
+		// reconcile the latest state of the resource right away
+		return reconcile.Result{Requeue: true}, nil
+	default:
+	}
+	if timedOut {
+		metrics.RunTimedOut(r.GVK.String())
+		timeoutErr := errors.New("ansible run timed out")
+		if r.ManageStatus {
+			errmark := r.markFailed(ctx, request.NamespacedName, u, ansiblestatus.TimeoutReason,
+				"Ansible run timed out and was killed")
+			if errmark != nil {
+				logger.Error(errmark, "Unable to update the status to mark cr as timed out")
+			}
+		}
+		logger.Error(timeoutErr, "Ansible run timed out and was killed")
+		return reconcileResult, timeoutErr
+	}
+
 	// To print the stats of the task
 	printEventStats(statusEvent, u)
 
@@ -273,6 +347,20 @@ func (r *AnsibleOperatorReconciler) Reconcile(ctx context.Context, request recon
 	return reconcileResult, nil
 }
 
+// runTimeout - returns the run timeout for u, which may be set by an annotation.
+func (r *AnsibleOperatorReconciler) runTimeout(u *unstructured.Unstructured) time.Duration {
+	runTimeout := r.RunTimeout
+	if rt, ok := u.GetAnnotations()[RunTimeoutAnnotation]; ok {
+		d, err := time.ParseDuration(rt)
+		if err != nil {
+			log.Info("Invalid run timeout annotation", "err", err, "value", rt)
+		} else {
+			runTimeout = d
+		}
+	}
+	return runTimeout
+}
+
 func printEventStats(statusEvent eventapi.StatusJobEvent, u *unstructured.Unstructured) {
 	if len(statusEvent.StdOut) > 0 {
 		str := fmt.Sprintf("Ansible Task Status Event StdOut (%s, %s/%s)", u.GroupVersionKind(), u.GetName(), u.GetNamespace())
@@ -314,6 +402,7 @@ func (r *AnsibleOperatorReconciler) markRunning(ctx context.Context, nn types.Na
 		ansiblestatus.RunningMessage,
 	)
 	ansiblestatus.SetCondition(&crStatus, *c)
+	crStatus.Progress = nil
 	u.Object["status"] = crStatus.GetJSONMap()
 
 	return r.Client.Status().Update(ctx, u)
@@ -323,6 +412,13 @@ func (r *AnsibleOperatorReconciler) markRunning(ctx context.Context, nn types.Na
 // i.e Annotations that could be incorrect
 func (r *AnsibleOperatorReconciler) markError(ctx context.Context, nn types.NamespacedName, u *unstructured.Unstructured,
 	failureMessage string) error {
+	return r.markFailed(ctx, nn, u, ansiblestatus.FailedReason, failureMessage)
+}
+
+// markFailed - used to set the failure condition with reason and failureMessage when a reconcile run fails
+// without the results of ansible, i.e. when the run times out.
+func (r *AnsibleOperatorReconciler) markFailed(ctx context.Context, nn types.NamespacedName, u *unstructured.Unstructured,
+	reason, failureMessage string) error {
 
 	logger := logf.Log.WithName("markError")
 	// Immediately update metrics with failed reconciliation, since Get()
@@ -348,10 +444,11 @@ func (r *AnsibleOperatorReconciler) markError(ctx context.Context, nn types.Name
 		ansiblestatus.FailureConditionType,
 		v1.ConditionTrue,
 		nil,
-		ansiblestatus.FailedReason,
+		reason,
 		failureMessage,
 	)
 	ansiblestatus.SetCondition(&crStatus, *c)
+	crStatus.Progress = nil
 	// This needs the status subresource to be enabled by default.
 	u.Object["status"] = crStatus.GetJSONMap()
 
@@ -403,12 +500,29 @@ func (r *AnsibleOperatorReconciler) markDone(ctx context.Context, nn types.Names
 		ansiblestatus.RemoveCondition(&crStatus, ansiblestatus.FailureConditionType)
 		ansiblestatus.SetCondition(&crStatus, *c)
 	}
+	crStatus.Progress = nil
 	// This needs the status subresource to be enabled by default.
 	u.Object["status"] = crStatus.GetJSONMap()
 
 	return r.Client.Status().Update(ctx, u)
 }
 
+// markArtifacts - sets the URL of the exported artifacts of the latest run in the status.
+// Like the progress, it is patched so that it does not conflict with other updates of the status.
+func (r *AnsibleOperatorReconciler) markArtifacts(ctx context.Context, nn types.NamespacedName, url string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{"artifactsURL": url},
+	})
+	if err != nil {
+		return err
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(r.GVK)
+	u.SetNamespace(nn.Namespace)
+	u.SetName(nn.Name)
+	return r.Client.Status().Patch(ctx, u, client.RawPatch(types.MergePatchType, patch))
+}
+
 // getStatus returns u's "status" block as a status.Status.
 func getStatus(u *unstructured.Unstructured) ansiblestatus.Status {
 	statusInterface := u.Object["status"]
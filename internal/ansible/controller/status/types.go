@@ -149,9 +149,58 @@ func createConditionFromMap(cm map[string]interface{}) Condition {
 	}
 }
 
+// Progress - the progress of a running reconciliation.
+type Progress struct {
+	Play           string      `json:"play,omitempty"`
+	Task           string      `json:"task,omitempty"`
+	Host           string      `json:"host,omitempty"`
+	TaskCount      int         `json:"taskCount"`
+	LastUpdateTime metav1.Time `json:"lastUpdateTime"`
+}
+
+// Update - updates the progress with a job event. It returns true if the
+// progress changed.
+func (p *Progress) Update(je eventapi.JobEvent) bool {
+	play, _ := je.EventData["play"].(string)
+	task, _ := je.EventData["task"].(string)
+	host, _ := je.EventData["host"].(string)
+	switch je.Event {
+	case eventapi.EventPlaybookOnPlayStart:
+		p.Play, p.Task, p.Host = play, "", ""
+		return true
+	case eventapi.EventPlaybookOnTaskStart:
+		p.Play, p.Task, p.Host = play, task, ""
+		p.TaskCount++
+		return true
+	}
+	if host != "" && host != p.Host {
+		p.Host = host
+		return true
+	}
+	return false
+}
+
+func createProgressFromMap(pm map[string]interface{}) *Progress {
+	b, err := json.Marshal(pm)
+	if err != nil {
+		log.Error(err, "Unable to marshal progress")
+		return nil
+	}
+	p := &Progress{}
+	if err := json.Unmarshal(b, p); err != nil {
+		log.Info("Unable to parse progress, removing progress", "Progress", pm)
+		return nil
+	}
+	return p
+}
+
 // Status - The status for custom resources managed by the operator-sdk.
 type Status struct {
-	Conditions   []Condition            `json:"conditions"`
+	Conditions []Condition `json:"conditions"`
+	// Progress - the progress of the running reconciliation, if any.
+	Progress *Progress `json:"progress,omitempty"`
+	// ArtifactsURL - the URL of the exported artifacts of the latest run, if any.
+	ArtifactsURL string                 `json:"artifactsURL,omitempty"`
 	CustomStatus map[string]interface{} `json:"-"`
 }
 
@@ -159,13 +208,18 @@ type Status struct {
 func CreateFromMap(statusMap map[string]interface{}) Status {
 	customStatus := make(map[string]interface{})
 	for key, value := range statusMap {
-		if key != "conditions" {
+		if key != "conditions" && key != "progress" && key != "artifactsURL" {
 			customStatus[key] = value
 		}
 	}
+	var progress *Progress
+	if pm, ok := statusMap["progress"].(map[string]interface{}); ok {
+		progress = createProgressFromMap(pm)
+	}
+	artifactsURL, _ := statusMap["artifactsURL"].(string)
 	conditionsInterface, ok := statusMap["conditions"].([]interface{})
 	if !ok {
-		return Status{Conditions: []Condition{}, CustomStatus: customStatus}
+		return Status{Conditions: []Condition{}, Progress: progress, ArtifactsURL: artifactsURL, CustomStatus: customStatus}
 	}
 	conditions := []Condition{}
 	for _, ci := range conditionsInterface {
@@ -176,7 +230,7 @@ func CreateFromMap(statusMap map[string]interface{}) Status {
 		}
 		conditions = append(conditions, createConditionFromMap(cm))
 	}
-	return Status{Conditions: conditions, CustomStatus: customStatus}
+	return Status{Conditions: conditions, Progress: progress, ArtifactsURL: artifactsURL, CustomStatus: customStatus}
 }
 
 // GetJSONMap - gets the map value for the status object.
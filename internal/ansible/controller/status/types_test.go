@@ -0,0 +1,123 @@
+// Copyright 2018 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner/eventapi"
+)
+
+func TestProgressUpdate(t *testing.T) {
+	events := []struct {
+		event          eventapi.JobEvent
+		expectChanged  bool
+		expectProgress Progress
+	}{
+		{
+			event: eventapi.JobEvent{
+				Event:     eventapi.EventPlaybookOnPlayStart,
+				EventData: map[string]interface{}{"play": "localhost"},
+			},
+			expectChanged:  true,
+			expectProgress: Progress{Play: "localhost"},
+		},
+		{
+			event: eventapi.JobEvent{
+				Event:     eventapi.EventPlaybookOnTaskStart,
+				EventData: map[string]interface{}{"play": "localhost", "task": "create deployment"},
+			},
+			expectChanged:  true,
+			expectProgress: Progress{Play: "localhost", Task: "create deployment", TaskCount: 1},
+		},
+		{
+			event: eventapi.JobEvent{
+				Event:     "runner_on_start",
+				EventData: map[string]interface{}{"play": "localhost", "task": "create deployment", "host": "localhost"},
+			},
+			expectChanged:  true,
+			expectProgress: Progress{Play: "localhost", Task: "create deployment", Host: "localhost", TaskCount: 1},
+		},
+		{
+			event: eventapi.JobEvent{
+				Event:     eventapi.EventRunnerOnOk,
+				EventData: map[string]interface{}{"play": "localhost", "task": "create deployment", "host": "localhost"},
+			},
+			expectChanged:  false,
+			expectProgress: Progress{Play: "localhost", Task: "create deployment", Host: "localhost", TaskCount: 1},
+		},
+		{
+			event: eventapi.JobEvent{
+				Event:     eventapi.EventPlaybookOnTaskStart,
+				EventData: map[string]interface{}{"play": "localhost", "task": "wait for deployment"},
+			},
+			expectChanged:  true,
+			expectProgress: Progress{Play: "localhost", Task: "wait for deployment", TaskCount: 2},
+		},
+	}
+
+	progress := &Progress{}
+	for _, e := range events {
+		if changed := progress.Update(e.event); changed != e.expectChanged {
+			t.Fatalf("Unexpected change for event %s: expected %v, got %v", e.event.Event, e.expectChanged, changed)
+		}
+		if !reflect.DeepEqual(*progress, e.expectProgress) {
+			t.Fatalf("Progress does not equal\nexpected: %#v\nactual: %#v", e.expectProgress, *progress)
+		}
+	}
+}
+
+func TestCreateFromMapProgress(t *testing.T) {
+	status := CreateFromMap(map[string]interface{}{
+		"progress": map[string]interface{}{
+			"play":           "localhost",
+			"task":           "create deployment",
+			"taskCount":      int64(3),
+			"lastUpdateTime": "2021-06-01T12:00:00Z",
+		},
+		"custom": "value",
+	})
+	if status.Progress == nil || status.Progress.Task != "create deployment" || status.Progress.TaskCount != 3 {
+		t.Fatalf("Unexpected progress: %#v", status.Progress)
+	}
+	if _, ok := status.CustomStatus["progress"]; ok {
+		t.Fatalf("Progress should not be in the custom status")
+	}
+
+	// Clearing the progress removes it from the status.
+	status.Progress = nil
+	statusMap := status.GetJSONMap()
+	if _, ok := statusMap["progress"]; ok {
+		t.Fatalf("Progress should have been removed from the status: %#v", statusMap)
+	}
+	if statusMap["custom"] != "value" {
+		t.Fatalf("Custom status should have been kept: %#v", statusMap)
+	}
+}
+
+func TestCreateFromMapArtifactsURL(t *testing.T) {
+	url := "s3://s3.example.com/bucket/example.com/v1/Example/default/example/1234/"
+	status := CreateFromMap(map[string]interface{}{"artifactsURL": url})
+	if status.ArtifactsURL != url {
+		t.Fatalf("Unexpected artifacts URL: %v", status.ArtifactsURL)
+	}
+	if _, ok := status.CustomStatus["artifactsURL"]; ok {
+		t.Fatalf("Artifacts URL should not be in the custom status")
+	}
+	if statusMap := status.GetJSONMap(); statusMap["artifactsURL"] != url {
+		t.Fatalf("Artifacts URL should have been kept: %#v", statusMap)
+	}
+}
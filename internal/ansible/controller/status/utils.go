@@ -28,6 +28,10 @@ const (
 	FailedReason = "Failed"
 	// UnknownFailedReason - Condition is unknown
 	UnknownFailedReason = "Unknown"
+	// CancelledReason - Condition is not running due to the reconciliation being cancelled
+	CancelledReason = "Cancelled"
+	// TimeoutReason - Condition is failed due to ansible running for longer than the run timeout
+	TimeoutReason = "Timeout"
 )
 
 const (
@@ -39,6 +39,8 @@ type Flags struct {
 	LeaderElectionNamespace string
 	GracefulShutdownTimeout time.Duration
 	AnsibleArgs             string
+	ProgressUpdatePeriod    time.Duration
+	ArtifactSink            string
 
 	// Path to a controller-runtime componentconfig file.
 	// If this is empty, use default values.
@@ -90,6 +92,13 @@ func (f *Flags) AddTo(flagSet *pflag.FlagSet) {
 		"",
 		"Ansible args. Allows user to specify arbitrary arguments for ansible-based operators.",
 	)
+	flagSet.StringVar(&f.ArtifactSink,
+		"artifact-sink",
+		"",
+		"URL of a sink to export the artifacts of ansible runs to, either file:///path for a directory such as "+
+			"a mounted volume, or s3://host/bucket[/prefix] for an S3-compatible endpoint (s3+http:// without TLS). "+
+			"If unset, artifacts are only kept in the operator pod.",
+	)
 
 	// Controller flags.
 	flagSet.DurationVar(&f.ReconcilePeriod,
@@ -102,6 +111,11 @@ func (f *Flags) AddTo(flagSet *pflag.FlagSet) {
 		runtime.NumCPU(),
 		"Maximum number of concurrent reconciles for controllers. Overridden by environment variable.",
 	)
+	flagSet.DurationVar(&f.ProgressUpdatePeriod,
+		"progress-update-period",
+		10*time.Second,
+		"Minimum time between updates of the progress of running reconciliations in the status of custom resources",
+	)
 
 	// Controller manager flags.
 	flagSet.StringVar(&f.ManagerConfigPath,
@@ -51,6 +51,16 @@ var (
 			"result",
 		})
 
+	runTimeouts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "run_timeouts_total",
+			Help:      "Total number of ansible runs killed for exceeding their run timeout.",
+		},
+		[]string{
+			"GVK",
+		})
+
 	reconciles = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Subsystem: subsystem,
@@ -65,6 +75,7 @@ var (
 func init() {
 	metrics.Registry.MustRegister(reconcileResults)
 	metrics.Registry.MustRegister(reconciles)
+	metrics.Registry.MustRegister(runTimeouts)
 }
 
 // We will never want to panic our app because of metric saving.
@@ -93,6 +104,11 @@ func ReconcileFailed(gvk string) {
 	reconcileResults.WithLabelValues(gvk, "failed").Inc()
 }
 
+func RunTimedOut(gvk string) {
+	defer recoverMetricPanic()
+	runTimeouts.WithLabelValues(gvk).Inc()
+}
+
 func ReconcileTimer(gvk string) *prometheus.Timer {
 	defer recoverMetricPanic()
 	return prometheus.NewTimer(prometheus.ObserverFunc(func(duration float64) {
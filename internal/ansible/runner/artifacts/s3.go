@@ -0,0 +1,217 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// AccessKeyIDEnvVar - environment variable with the access key ID of S3 sinks.
+	AccessKeyIDEnvVar = "AWS_ACCESS_KEY_ID"
+	// SecretAccessKeyEnvVar - environment variable with the secret access key of S3 sinks.
+	SecretAccessKeyEnvVar = "AWS_SECRET_ACCESS_KEY"
+	// SessionTokenEnvVar - environment variable with the optional session token of S3 sinks.
+	SessionTokenEnvVar = "AWS_SESSION_TOKEN"
+	// RegionEnvVar - environment variable with the region of S3 sinks.
+	RegionEnvVar = "AWS_REGION"
+
+	defaultRegion = "us-east-1"
+
+	// requestTimeout - time given to each request to the S3 endpoint,
+	// including reading its response.
+	requestTimeout = 30 * time.Second
+
+	amzDateFormat = "20060102T150405Z"
+	signAlgorithm = "AWS4-HMAC-SHA256"
+)
+
+// s3Sink - implements the Sink interface by uploading artifacts to a bucket of
+// an S3-compatible endpoint, with path-style requests. The runs of a key are
+// stored under <prefix>/<key>/<ident>/, and the object <prefix>/<key>/latest
+// holds the ident of the latest run. Requests are signed with AWS Signature
+// Version 4 when credentials are set in the environment. Runs are not
+// rotated; use the lifecycle rules of the bucket to expire them.
+type s3Sink struct {
+	scheme   string
+	endpoint *url.URL
+	bucket   string
+	prefix   string
+
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	region          string
+
+	client *http.Client
+	now    func() time.Time
+}
+
+func newS3Sink(u *url.URL) (*s3Sink, error) {
+	parts := strings.SplitN(strings.Trim(u.Path, "/"), "/", 2)
+	if u.Host == "" || parts[0] == "" {
+		return nil, fmt.Errorf("artifact sink URL %q must have a host and a bucket", u)
+	}
+	s := &s3Sink{
+		scheme:          u.Scheme,
+		endpoint:        &url.URL{Scheme: "https", Host: u.Host},
+		bucket:          parts[0],
+		accessKeyID:     os.Getenv(AccessKeyIDEnvVar),
+		secretAccessKey: os.Getenv(SecretAccessKeyEnvVar),
+		sessionToken:    os.Getenv(SessionTokenEnvVar),
+		region:          os.Getenv(RegionEnvVar),
+		client:          &http.Client{Timeout: requestTimeout},
+		now:             time.Now,
+	}
+	if u.Scheme == schemeS3HTTP {
+		s.endpoint.Scheme = "http"
+	}
+	if len(parts) == 2 {
+		s.prefix = strings.Trim(parts[1], "/")
+	}
+	if s.region == "" {
+		s.region = defaultRegion
+	}
+	return s, nil
+}
+
+// Export - uploads the artifacts in dir to the bucket, and then updates the
+// latest object of key. Objects are uploaded one at a time, each within
+// requestTimeout, and Export returns once they are all uploaded or the
+// deadline of ctx is exceeded.
+func (s *s3Sink) Export(ctx context.Context, key, ident, dir string, _ int) (string, error) {
+	runKey := path.Join(s.prefix, key, ident)
+	err := walkFiles(dir, func(file, rel string) error {
+		body, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		return s.putObject(ctx, path.Join(runKey, rel), body)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload artifacts to bucket %s: %w", s.bucket, err)
+	}
+	if err := s.putObject(ctx, path.Join(s.prefix, key, LatestRun), []byte(ident)); err != nil {
+		return "", fmt.Errorf("failed to upload latest run to bucket %s: %w", s.bucket, err)
+	}
+	return (&url.URL{Scheme: s.scheme, Host: s.endpoint.Host, Path: "/" + path.Join(s.bucket, runKey) + "/"}).String(), nil
+}
+
+// putObject - uploads body as the object key of the bucket.
+func (s *s3Sink) putObject(ctx context.Context, key string, body []byte) error {
+	u := *s.endpoint
+	u.Path = "/" + s.bucket + "/" + key
+	u.RawPath = "/" + uriEncode(s.bucket) + "/" + uriEncode(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if s.accessKeyID != "" {
+		s.sign(req, body)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %s uploading %s: %s", resp.Status, key, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// sign - signs req with AWS Signature Version 4. See
+// https://docs.aws.amazon.com/general/latest/gr/sigv4_signing.html
+func (s *s3Sink) sign(req *http.Request, body []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format(amzDateFormat)
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	headers := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	values := map[string]string{"host": req.URL.Host, "x-amz-content-sha256": payloadHash, "x-amz-date": amzDate}
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+		headers = append(headers, "x-amz-security-token")
+		values["x-amz-security-token"] = s.sessionToken
+	}
+	var canonicalHeaders strings.Builder
+	for _, h := range headers {
+		canonicalHeaders.WriteString(h + ":" + values[h] + "\n")
+	}
+	signedHeaders := strings.Join(headers, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := strings.Join([]string{date, s.region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{signAlgorithm, amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretAccessKey), date)
+	for _, part := range []string{s.region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		signAlgorithm, s.accessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// uriEncode - encodes every byte of s except unreserved characters and '/',
+// as required for the canonical URI of signed requests.
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,115 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// LatestRun - name under which sinks record the latest run of a custom resource.
+	LatestRun = "latest"
+
+	// schemeFile - scheme of the URLs of volume sinks.
+	schemeFile = "file"
+	// schemeS3 - scheme of the URLs of S3 sinks that use HTTPS.
+	schemeS3 = "s3"
+	// schemeS3HTTP - scheme of the URLs of S3 sinks that use plain HTTP.
+	schemeS3HTTP = "s3+http"
+)
+
+// Sink - stores the artifacts of ansible runs outside of the operator pod, so
+// that they outlive it.
+type Sink interface {
+	// Export - stores the artifacts of the run ident in dir under key, records
+	// the run as the latest run of key, and returns the URL of the stored run.
+	// Sinks that can list runs keep the latest maxRuns runs of key; setting
+	// maxRuns to zero keeps all runs.
+	Export(ctx context.Context, key, ident, dir string, maxRuns int) (string, error)
+}
+
+// NewSink - creates a Sink from its URL. Supported URLs are:
+//
+//	file:///path/to/dir                   - a directory, usually a mounted volume
+//	s3://host[:port]/bucket[/prefix]      - a bucket of an S3-compatible endpoint
+//	s3+http://host[:port]/bucket[/prefix] - same as s3://, over plain HTTP
+func NewSink(rawURL string) (Sink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid artifact sink URL %q: %w", rawURL, err)
+	}
+	switch u.Scheme {
+	case schemeFile:
+		return newVolumeSink(u)
+	case schemeS3, schemeS3HTTP:
+		return newS3Sink(u)
+	default:
+		return nil, fmt.Errorf("artifact sink URL %q must have scheme %q, %q or %q",
+			rawURL, schemeFile, schemeS3, schemeS3HTTP)
+	}
+}
+
+// Rotate - removes the oldest run directories in dir beyond maxRuns, like
+// ansible-runner does for its own artifacts. Setting maxRuns to zero keeps
+// all runs.
+func Rotate(dir string, maxRuns int) error {
+	if maxRuns <= 0 {
+		return nil
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var runs []os.FileInfo
+	for _, info := range infos {
+		if info.IsDir() {
+			runs = append(runs, info)
+		}
+	}
+	if len(runs) <= maxRuns {
+		return nil
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].ModTime().Before(runs[j].ModTime()) })
+	for _, info := range runs[:len(runs)-maxRuns] {
+		if err := os.RemoveAll(filepath.Join(dir, info.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkFiles - calls fn with the slash separated path relative to dir of each
+// regular file under dir.
+func walkFiles(dir string, fn func(path, rel string) error) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return fn(path, filepath.ToSlash(rel))
+	})
+}
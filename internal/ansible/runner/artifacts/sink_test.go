@@ -0,0 +1,153 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifacts
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeTestRun - writes the artifacts of a run like ansible-runner does, and
+// returns their directory.
+func writeTestRun(t *testing.T, root, ident string) string {
+	dir := filepath.Join(root, "artifacts", ident)
+	files := map[string]string{
+		"stdout":                  "PLAY RECAP",
+		"status":                  "successful",
+		"job_events/1-event.json": `{"event": "playbook_on_start"}`,
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestNewSink(t *testing.T) {
+	testCases := []struct {
+		name      string
+		url       string
+		shouldErr bool
+	}{
+		{name: "volume", url: "file:///artifacts"},
+		{name: "volume with host", url: "file://host/artifacts", shouldErr: true},
+		{name: "volume with relative path", url: "file:artifacts", shouldErr: true},
+		{name: "s3", url: "s3://s3.example.com/bucket/prefix"},
+		{name: "s3 over http", url: "s3+http://localhost:9000/bucket"},
+		{name: "s3 without bucket", url: "s3://s3.example.com", shouldErr: true},
+		{name: "unknown scheme", url: "ftp://example.com/artifacts", shouldErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewSink(tc.url)
+			if err != nil && !tc.shouldErr {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if err == nil && tc.shouldErr {
+				t.Fatal("Expected error but got none")
+			}
+		})
+	}
+}
+
+func TestVolumeSinkExport(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	root := filepath.Join(tmp, "sink")
+	sink, err := NewSink("file://" + root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := "example.com/v1alpha1/Example/default/example"
+	var urls []string
+	for _, ident := range []string{"1", "2", "3"} {
+		url, err := sink.Export(context.TODO(), key, ident, writeTestRun(t, tmp, ident), 2)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		urls = append(urls, url)
+	}
+
+	keyDir := filepath.Join(root, filepath.FromSlash(key))
+	if expected := "file://" + filepath.Join(keyDir, "3"); urls[2] != expected {
+		t.Fatalf("Unexpected URL %v expected %v", urls[2], expected)
+	}
+	stdout, err := ioutil.ReadFile(filepath.Join(keyDir, LatestRun, "stdout"))
+	if err != nil || string(stdout) != "PLAY RECAP" {
+		t.Fatalf("Unexpected latest stdout %q: %v", stdout, err)
+	}
+	if _, err := os.Stat(filepath.Join(keyDir, "3", "job_events", "1-event.json")); err != nil {
+		t.Fatalf("Job events were not exported: %v", err)
+	}
+	if target, err := os.Readlink(filepath.Join(keyDir, LatestRun)); err != nil || target != "3" {
+		t.Fatalf("Unexpected latest run %q: %v", target, err)
+	}
+	if _, err := os.Stat(filepath.Join(keyDir, "1")); !os.IsNotExist(err) {
+		t.Fatalf("Oldest run was not rotated: %v", err)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	for i, ident := range []string{"3", "1", "2"} {
+		path := filepath.Join(dir, ident)
+		if err := os.MkdirAll(path, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		modTime := now.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, "2"), filepath.Join(dir, LatestRun)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Rotate(dir, 2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	if expected := []string{"1", "2", LatestRun}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("Unexpected artifacts %v expected %v", names, expected)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// volumeSink - implements the Sink interface by copying artifacts to a
+// directory, usually a mounted persistent volume. The runs of a key are
+// stored in <root>/<key>/<ident>, and <root>/<key>/latest links to the
+// latest run.
+type volumeSink struct {
+	root string
+}
+
+func newVolumeSink(u *url.URL) (*volumeSink, error) {
+	if u.Host != "" || !filepath.IsAbs(u.Path) {
+		return nil, fmt.Errorf("artifact sink URL %q must have an absolute path and no host", u)
+	}
+	return &volumeSink{root: filepath.Clean(u.Path)}, nil
+}
+
+// Export - copies the artifacts in dir to the directory of the run ident.
+func (s *volumeSink) Export(ctx context.Context, key, ident, dir string, maxRuns int) (string, error) {
+	keyDir := filepath.Join(s.root, filepath.FromSlash(key))
+	runDir := filepath.Join(keyDir, ident)
+	err := walkFiles(dir, func(path, rel string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return copyFile(path, filepath.Join(runDir, filepath.FromSlash(rel)))
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to copy artifacts to %s: %w", runDir, err)
+	}
+
+	// Replace the latest link atomically, so that it always points to a run.
+	latest := filepath.Join(keyDir, LatestRun)
+	tmp := latest + "." + ident
+	if err := os.Symlink(ident, tmp); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, latest); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	if err := Rotate(keyDir, maxRuns); err != nil {
+		return "", fmt.Errorf("failed to rotate artifacts in %s: %w", keyDir, err)
+	}
+	return (&url.URL{Scheme: schemeFile, Path: filepath.ToSlash(runDir)}).String(), nil
+}
+
+// copyFile - copies the regular file src to dst, creating the parent
+// directories of dst.
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
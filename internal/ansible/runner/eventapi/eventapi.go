@@ -40,6 +40,10 @@ type EventReceiver struct {
 	// SocketPath is the path on the filesystem to a unix streaming socket
 	SocketPath string
 
+	// Addr is the address of the TCP listener of the event API, when it is
+	// served over TCP rather than a unix streaming socket.
+	Addr net.Addr
+
 	// URLPath is the path portion of the url at which events should be
 	// received. For example, "/events/"
 	URLPath string
@@ -71,12 +75,32 @@ func New(ident string, errChan chan<- error) (*EventReceiver, error) {
 		return nil, err
 	}
 
+	rec := newEventReceiver(ident, listener, "/events/", errChan)
+	rec.SocketPath = sockPath
+	return rec, nil
+}
+
+// NewTCP creates an EventReceiver that serves the event API over TCP at
+// address, such as ":0" to listen on a random port, for ansible-runner
+// processes that do not share a filesystem with the operator. Events are only
+// accepted at urlPath, which should be hard to guess.
+func NewTCP(ident, address, urlPath string, errChan chan<- error) (*EventReceiver, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := newEventReceiver(ident, listener, urlPath, errChan)
+	rec.Addr = listener.Addr()
+	return rec, nil
+}
+
+func newEventReceiver(ident string, listener net.Listener, urlPath string, errChan chan<- error) *EventReceiver {
 	rec := EventReceiver{
-		Events:     make(chan JobEvent, 1000),
-		SocketPath: sockPath,
-		URLPath:    "/events/",
-		ident:      ident,
-		logger:     logf.Log.WithName("eventapi").WithValues("job", ident),
+		Events:  make(chan JobEvent, 1000),
+		URLPath: urlPath,
+		ident:   ident,
+		logger:  logf.Log.WithName("eventapi").WithValues("job", ident),
 	}
 
 	mux := http.NewServeMux()
@@ -87,7 +111,7 @@ func New(ident string, errChan chan<- error) (*EventReceiver, error) {
 	go func() {
 		errChan <- srv.Serve(listener)
 	}()
-	return &rec, nil
+	return &rec
 }
 
 // Close ensures that appropriate resources are cleaned up, such as any unix
@@ -100,7 +124,9 @@ func (e *EventReceiver) Close() {
 	if err := e.server.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
 		e.logger.Error(err, "Failed to close event receiver")
 	}
-	os.Remove(e.SocketPath)
+	if e.SocketPath != "" {
+		os.Remove(e.SocketPath)
+	}
 	close(e.Events)
 }
 
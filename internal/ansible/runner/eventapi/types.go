@@ -23,6 +23,8 @@ import (
 const (
 	// Ansible Events
 
+	// EventPlaybookOnPlayStart - playbook is starting to run a play.
+	EventPlaybookOnPlayStart = "playbook_on_play_start"
 	// EventPlaybookOnTaskStart - playbook is starting to run a task.
 	EventPlaybookOnTaskStart = "playbook_on_task_start"
 	// EventRunnerOnOk - task finished with ok status.
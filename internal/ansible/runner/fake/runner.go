@@ -15,6 +15,7 @@
 package fake
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -37,11 +38,17 @@ type Runner struct {
 	JobEvents []eventapi.JobEvent
 	//Stdout standard out to reply if failure occurs.
 	Stdout string
+	// BlockUntilCancelled keeps runs going after the Job Events have been
+	// sent, until they are cancelled.
+	BlockUntilCancelled bool
+	// ArtifactsURL is the URL of the exported artifacts of runs.
+	ArtifactsURL string
 }
 
 type runResult struct {
-	events <-chan eventapi.JobEvent
-	stdout string
+	events       <-chan eventapi.JobEvent
+	stdout       string
+	artifactsURL string
 }
 
 func (r *runResult) Events() <-chan eventapi.JobEvent {
@@ -55,8 +62,12 @@ func (r *runResult) Stdout() (string, error) {
 	return r.stdout, fmt.Errorf("unable to find standard out")
 }
 
+func (r *runResult) ArtifactsURL() string {
+	return r.artifactsURL
+}
+
 // Run - runs the fake runner.
-func (r *Runner) Run(_ string, u *unstructured.Unstructured, _ string) (runner.RunResult, error) {
+func (r *Runner) Run(ctx context.Context, _ string, u *unstructured.Unstructured, _ string) (runner.RunResult, error) {
 	if r.Error != nil {
 		return nil, r.Error
 	}
@@ -65,9 +76,12 @@ func (r *Runner) Run(_ string, u *unstructured.Unstructured, _ string) (runner.R
 		for _, je := range r.JobEvents {
 			c <- je
 		}
+		if r.BlockUntilCancelled {
+			<-ctx.Done()
+		}
 		close(c)
 	}()
-	return &runResult{events: c, stdout: r.Stdout}, nil
+	return &runResult{events: c, stdout: r.Stdout, artifactsURL: r.ArtifactsURL}, nil
 }
 
 // GetReconcilePeriod - new reconcile period.
@@ -0,0 +1,451 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/operator-framework/operator-lib/handler"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	"github.com/operator-framework/operator-sdk/internal/ansible/metrics"
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner/artifacts"
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner/eventapi"
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner/internal/inputdir"
+	"github.com/operator-framework/operator-sdk/internal/ansible/watches"
+)
+
+const (
+	// PodIPEnvVar - environment variable with the IP of the operator pod, at which
+	// the pods of Jobs send events back to the operator. It is usually set from
+	// the status.podIP field with the downward API. When it is not set, the first
+	// IP address of the operator pod that is not a loopback address is used.
+	PodIPEnvVar = "POD_IP"
+
+	// jobInputDirPath - path of the input directory of ansible-runner in the pods of Jobs.
+	jobInputDirPath = "/runner"
+
+	// jobContainerName - name of the container that runs ansible-runner in the pods of Jobs.
+	jobContainerName = "ansible-runner"
+
+	// jobPollInterval - interval at which Jobs are checked for completion.
+	jobPollInterval = 2 * time.Second
+
+	// serviceAccountNamespaceFile - file with the namespace of the operator pod.
+	serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// NewJob - creates a Runner from a Watch struct that runs ansible-runner in the
+// pod of a Kubernetes Job, with the image and resources of the runner of the
+// watch. The Job is created in the namespace of the runner, by default the
+// namespace of the operator, with the client configured by cfg. If sink is not
+// nil, the artifacts of each run are exported to it.
+func NewJob(watch watches.Watch, runnerArgs string, sink artifacts.Sink, cfg *rest.Config) (Runner, error) {
+	if watch.Runner == nil || watch.Runner.Type != watches.RunnerTypeJob {
+		return nil, fmt.Errorf("runner of watch for GVK %v must be of type %q", watch.GroupVersionKind,
+			watches.RunnerTypeJob)
+	}
+	r, err := newRunner(watch, runnerArgs, sink)
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	namespace := watch.Runner.Namespace
+	if namespace == "" {
+		ns, err := ioutil.ReadFile(serviceAccountNamespaceFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to determine the namespace of Jobs, set the namespace of the runner: %w", err)
+		}
+		namespace = strings.TrimSpace(string(ns))
+	}
+	return &jobRunner{
+		runner:       r,
+		config:       *watch.Runner,
+		clientset:    clientset,
+		namespace:    namespace,
+		pollInterval: jobPollInterval,
+	}, nil
+}
+
+// jobRunner - implements the Runner interface by running ansible-runner in the
+// pod of a Kubernetes Job for each run. The pod sends events back to the
+// operator over HTTP, which are written to the job_events artifacts of the
+// run, and its logs are collected as the stdout artifact of the run once the
+// Job has finished.
+type jobRunner struct {
+	*runner
+	config       watches.Runner
+	clientset    kubernetes.Interface
+	namespace    string
+	pollInterval time.Duration
+}
+
+// Run - runs ansible-runner for u in the pod of a Job. Since the pod cannot
+// reach the proxy of the operator, kubeconfig is ignored, and ansible uses
+// the credentials of the service account of the pod. When ctx is cancelled,
+// the Job is deleted, which terminates its pod.
+func (r *jobRunner) Run(ctx context.Context, ident string, u *unstructured.Unstructured, _ string) (RunResult, error) {
+	timer := metrics.ReconcileTimer(r.GVK.String())
+	defer timer.ObserveDuration()
+
+	if u.GetDeletionTimestamp() != nil && !r.isFinalizerRun(u) {
+		return nil, errors.New("resource has been deleted, but no finalizer was matched, skipping reconciliation")
+	}
+	logger := log.WithValues(
+		"job", ident,
+		"name", u.GetName(),
+		"namespace", u.GetNamespace(),
+	)
+
+	host, err := eventHost()
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine the address of the event API: %w", err)
+	}
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	// start the event receiver on a random port. We'll check errChan for an
+	// error after the Job has finished.
+	errChan := make(chan error, 1)
+	receiver, err := eventapi.NewTCP(ident, ":0", fmt.Sprintf("/events/%s/", hex.EncodeToString(token)), errChan)
+	if err != nil {
+		return nil, err
+	}
+	port := receiver.Addr.(*net.TCPAddr).Port
+	inputDir := inputdir.InputDir{
+		Path:       r.inputDirPath(u),
+		Parameters: r.makeParameters(u),
+		Settings: map[string]string{
+			"runner_http_url":  "http://" + net.JoinHostPort(host, strconv.Itoa(port)),
+			"runner_http_path": receiver.URLPath,
+		},
+		CmdLine: r.ansibleArgs,
+	}
+	if err := inputDir.Write(); err != nil {
+		receiver.Close()
+		return nil, err
+	}
+	maxArtifacts := r.maxArtifacts(u)
+	cmd := r.command(ident, u, jobInputDirPath, maxArtifacts, r.verbosity(u))
+
+	job, err := r.createJob(ctx, ident, u, inputDir.Path, cmd.Args)
+	if err != nil {
+		receiver.Close()
+		return nil, err
+	}
+	logger = logger.WithValues("jobName", job.GetNamespace()+"/"+job.GetName())
+	logger.V(1).Info("Created Job to run ansible-runner")
+
+	artifactsDir := filepath.Join(inputDir.Path, "artifacts", ident)
+	events := make(chan eventapi.JobEvent)
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		writeJobEvents(logger, artifactsDir, receiver.Events, events)
+	}()
+	result := &runResult{
+		events:   events,
+		inputDir: &inputDir,
+		ident:    ident,
+	}
+
+	go func() {
+		succeeded, err := r.waitForJob(ctx, job.GetName())
+		// The Job is cleaned up even if the run was cancelled.
+		ctx := context.TODO()
+		if errors.Is(err, wait.ErrWaitTimeout) {
+			logger.Info("Run cancelled, deleting the ansible-runner Job")
+		} else if err != nil {
+			logger.Error(err, "Error waiting for the Job to finish")
+		} else if succeeded {
+			logger.Info("Ansible-runner Job succeeded")
+		} else {
+			logger.Info("Ansible-runner Job failed")
+		}
+
+		// The artifacts must be collected and exported before the events
+		// channel is closed, since they are read as soon as it is closed.
+		if err := r.collectArtifacts(ctx, artifactsDir, job.GetName(), succeeded); err != nil {
+			logger.Error(err, "Error collecting the artifacts of the Job")
+		}
+
+		receiver.Close()
+		err = <-errChan
+		// http.Server returns this in the case of being closed cleanly
+		if err != nil && err != http.ErrServerClosed {
+			logger.Error(err, "Error from event API")
+		}
+		<-forwarded
+
+		result.artifactsURL = r.exportArtifacts(logger, u, inputDir.Path, ident, maxArtifacts)
+		close(events)
+
+		linkLatestArtifacts(logger, inputDir.Path, ident)
+		if err := artifacts.Rotate(filepath.Join(inputDir.Path, "artifacts"), maxArtifacts); err != nil {
+			logger.Error(err, "Error rotating artifacts")
+		}
+
+		// Deleting the Job also deletes its pod and its input secret.
+		propagation := metav1.DeletePropagationBackground
+		err = r.clientset.BatchV1().Jobs(r.namespace).Delete(ctx, job.GetName(),
+			metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil {
+			logger.Error(err, "Error deleting the Job")
+		}
+	}()
+
+	return result, nil
+}
+
+// createJob - creates the Job that runs command for the run ident of u, and
+// the secret with the files of the input directory at inputDirPath that the
+// pod of the Job mounts.
+func (r *jobRunner) createJob(ctx context.Context, ident string, u *unstructured.Unstructured,
+	inputDirPath string, command []string) (*batchv1.Job, error) {
+	name := fmt.Sprintf("ansible-runner-%s", ident)
+	envFiles, err := readInputFiles(filepath.Join(inputDirPath, "env"))
+	if err != nil {
+		return nil, err
+	}
+	inventoryFiles, err := readInputFiles(filepath.Join(inputDirPath, "inventory"))
+	if err != nil {
+		return nil, err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: r.namespace},
+		Data:       map[string][]byte{},
+	}
+	var envItems, inventoryItems []corev1.KeyToPath
+	for file, data := range envFiles {
+		secret.Data["env."+file] = data
+		envItems = append(envItems, corev1.KeyToPath{Key: "env." + file, Path: file})
+	}
+	for file, data := range inventoryFiles {
+		secret.Data["inventory."+file] = data
+		inventoryItems = append(inventoryItems, corev1.KeyToPath{Key: "inventory." + file, Path: file})
+	}
+
+	backoffLimit := int32(0)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: r.namespace},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy:      corev1.RestartPolicyNever,
+					ServiceAccountName: r.config.ServiceAccountName,
+					Containers: []corev1.Container{{
+						Name:      jobContainerName,
+						Image:     r.config.Image,
+						Command:   command,
+						Resources: r.config.Resources,
+						VolumeMounts: []corev1.VolumeMount{
+							{Name: "runner", MountPath: jobInputDirPath},
+							{Name: "project", MountPath: filepath.Join(jobInputDirPath, "project")},
+							{Name: "env", MountPath: filepath.Join(jobInputDirPath, "env"), ReadOnly: true},
+							{Name: "inventory", MountPath: filepath.Join(jobInputDirPath, "inventory"), ReadOnly: true},
+						},
+					}},
+					Volumes: []corev1.Volume{
+						{Name: "runner", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+						{Name: "project", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+						{Name: "env", VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{SecretName: name, Items: envItems},
+						}},
+						{Name: "inventory", VolumeSource: corev1.VolumeSource{
+							Secret: &corev1.SecretVolumeSource{SecretName: name, Items: inventoryItems},
+						}},
+					},
+				},
+			},
+		},
+	}
+	if err := handler.SetOwnerAnnotations(u, job); err != nil {
+		return nil, err
+	}
+
+	job, err = r.clientset.BatchV1().Jobs(r.namespace).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Job: %w", err)
+	}
+	// The pod of the Job waits for the secret to be created, which is
+	// garbage collected with the Job.
+	secret.OwnerReferences = []metav1.OwnerReference{
+		*metav1.NewControllerRef(job, batchv1.SchemeGroupVersion.WithKind("Job")),
+	}
+	if _, err := r.clientset.CoreV1().Secrets(r.namespace).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		propagation := metav1.DeletePropagationBackground
+		if err := r.clientset.BatchV1().Jobs(r.namespace).Delete(ctx, name,
+			metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil {
+			log.Error(err, "Failed to delete Job", "Job", r.namespace+"/"+name)
+		}
+		return nil, fmt.Errorf("failed to create secret for Job: %w", err)
+	}
+	return job, nil
+}
+
+// waitForJob - waits for the Job name to finish, and returns whether it
+// succeeded. It returns wait.ErrWaitTimeout if ctx is done first. Errors
+// getting the Job are retried, unless the Job was not found.
+func (r *jobRunner) waitForJob(ctx context.Context, name string) (bool, error) {
+	var succeeded bool
+	err := wait.PollImmediateUntil(r.pollInterval, func() (bool, error) {
+		job, err := r.clientset.BatchV1().Jobs(r.namespace).Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return false, err
+		}
+		if err != nil {
+			// A cancelled ctx also stops polling, with wait.ErrWaitTimeout.
+			if ctx.Err() == nil {
+				log.Error(err, "Error getting the Job, retrying", "Job", r.namespace+"/"+name)
+			}
+			return false, nil
+		}
+		for _, c := range job.Status.Conditions {
+			if c.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch c.Type {
+			case batchv1.JobComplete:
+				succeeded = true
+				return true, nil
+			case batchv1.JobFailed:
+				return true, nil
+			}
+		}
+		return false, nil
+	}, ctx.Done())
+	return succeeded, err
+}
+
+// collectArtifacts - writes the artifacts of the run from the pod of the Job
+// name to artifactsDir. The logs of the pod are written as the stdout of the run.
+func (r *jobRunner) collectArtifacts(ctx context.Context, artifactsDir, name string, succeeded bool) error {
+	if err := os.MkdirAll(artifactsDir, os.ModePerm); err != nil {
+		return err
+	}
+	status := "failed"
+	if succeeded {
+		status = "successful"
+	}
+	if err := ioutil.WriteFile(filepath.Join(artifactsDir, "status"), []byte(status), 0644); err != nil {
+		return err
+	}
+
+	pods, err := r.clientset.CoreV1().Pods(r.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "job-name=" + name,
+	})
+	if err != nil {
+		return err
+	}
+	if len(pods.Items) == 0 {
+		return fmt.Errorf("no pod found for Job %s/%s", r.namespace, name)
+	}
+	logs, err := r.clientset.CoreV1().Pods(r.namespace).GetLogs(pods.Items[0].GetName(), &corev1.PodLogOptions{
+		Container: jobContainerName,
+	}).DoRaw(ctx)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(artifactsDir, "stdout"), logs, 0644)
+}
+
+// readInputFiles - returns the contents of the regular files in dir by name.
+func readInputFiles(dir string) (map[string][]byte, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{}
+	for _, info := range infos {
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(dir, info.Name()))
+		if err != nil {
+			return nil, err
+		}
+		files[info.Name()] = data
+	}
+	return files, nil
+}
+
+// writeJobEvents - forwards the events of in to out, and writes each of them to
+// the job_events directory of artifactsDir, like ansible-runner does when it
+// runs in the operator container. It returns when in is closed.
+func writeJobEvents(logger logr.Logger, artifactsDir string, in <-chan eventapi.JobEvent,
+	out chan<- eventapi.JobEvent) {
+	eventsDir := filepath.Join(artifactsDir, "job_events")
+	if err := os.MkdirAll(eventsDir, os.ModePerm); err != nil {
+		logger.Error(err, "Error creating the job events directory")
+		eventsDir = ""
+	}
+	for event := range in {
+		if eventsDir != "" {
+			data, err := json.Marshal(event)
+			if err == nil {
+				name := fmt.Sprintf("%d-%s.json", event.Counter, event.UUID)
+				err = ioutil.WriteFile(filepath.Join(eventsDir, name), data, 0644)
+			}
+			if err != nil {
+				logger.Error(err, "Error writing job event", "uuid", event.UUID)
+			}
+		}
+		out <- event
+	}
+}
+
+// eventHost - returns the host at which the pods of Jobs reach the event API.
+func eventHost() (string, error) {
+	if ip := os.Getenv(PodIPEnvVar); ip != "" {
+		return ip, nil
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP.String(), nil
+		}
+	}
+	return "", fmt.Errorf("no IP address found, set the %s environment variable", PodIPEnvVar)
+}
@@ -0,0 +1,270 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner/artifacts"
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner/eventapi"
+	"github.com/operator-framework/operator-sdk/internal/ansible/watches"
+)
+
+func newTestJobRunner(t *testing.T, config watches.Runner) (*jobRunner, *fake.Clientset, *unstructured.Unstructured) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Unable to get working director: %v", err)
+	}
+	gvk := schema.GroupVersionKind{Group: "operator.example.com", Version: "v1alpha1", Kind: "JobExample"}
+	watch := watches.New(gvk, "", filepath.Join(cwd, "testdata", "playbook.yml"), nil, nil)
+	watch.Runner = &config
+	r, err := newRunner(*watch, "", nil)
+	if err != nil {
+		t.Fatalf("Error creating runner: %v", err)
+	}
+	clientset := fake.NewSimpleClientset()
+	jr := &jobRunner{
+		runner:       r,
+		config:       config,
+		clientset:    clientset,
+		namespace:    "operators",
+		pollInterval: 10 * time.Millisecond,
+	}
+
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	u.SetNamespace("default")
+	u.SetName("example")
+	return jr, clientset, u
+}
+
+func TestJobRunnerRun(t *testing.T) {
+	config := watches.Runner{
+		Type:  watches.RunnerTypeJob,
+		Image: "quay.io/example/operator:v0.0.1",
+		Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+		},
+	}
+	jr, clientset, u := newTestJobRunner(t, config)
+	defer os.RemoveAll(jr.inputDirPath(u))
+	sinkDir, err := ioutil.TempDir("", "sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(sinkDir)
+	if jr.artifactSink, err = artifacts.NewSink("file://" + sinkDir); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv(PodIPEnvVar, "10.0.0.1")
+	defer os.Unsetenv(PodIPEnvVar)
+
+	result, err := jr.Run(context.TODO(), "1234", u, "/tmp/kubeconfig")
+	if err != nil {
+		t.Fatalf("Error running: %v", err)
+	}
+
+	ctx := context.TODO()
+	job, err := clientset.BatchV1().Jobs("operators").Get(ctx, "ansible-runner-1234", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Error getting Job: %v", err)
+	}
+	container := job.Spec.Template.Spec.Containers[0]
+	if container.Image != config.Image {
+		t.Fatalf("Unexpected image %v expected %v", container.Image, config.Image)
+	}
+	if !reflect.DeepEqual(container.Resources, config.Resources) {
+		t.Fatalf("Unexpected resources %v expected %v", container.Resources, config.Resources)
+	}
+	expectedCommand := playbookCmdFunc(jr.Path)("1234", jobInputDirPath, 20, 2).Args
+	if !reflect.DeepEqual(container.Command, expectedCommand) {
+		t.Fatalf("Unexpected command %v expected %v", container.Command, expectedCommand)
+	}
+	if job.GetAnnotations()["operator-sdk/primary-resource"] != "default/example" {
+		t.Fatalf("Unexpected annotations %v", job.GetAnnotations())
+	}
+
+	secret, err := clientset.CoreV1().Secrets("operators").Get(ctx, "ansible-runner-1234", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Error getting secret: %v", err)
+	}
+	if owner := metav1.GetControllerOf(secret); owner == nil || owner.Name != job.GetName() {
+		t.Fatalf("Secret is not owned by the Job: %v", secret.GetOwnerReferences())
+	}
+	for _, key := range []string{"env.extravars", "env.settings", "inventory.hosts"} {
+		if _, ok := secret.Data[key]; !ok {
+			t.Fatalf("Secret has no key %v", key)
+		}
+	}
+	if strings.Contains(string(secret.Data["env.envvars"]), "/tmp/kubeconfig") {
+		t.Fatalf("Unexpected kubeconfig in the environment of the Job: %s", secret.Data["env.envvars"])
+	}
+
+	// Send an event like ansible-runner does from the pod of the Job.
+	settings := map[string]string{}
+	if err := json.Unmarshal(secret.Data["env.settings"], &settings); err != nil {
+		t.Fatalf("Error parsing settings: %v", err)
+	}
+	if !strings.HasPrefix(settings["runner_http_url"], "http://10.0.0.1:") {
+		t.Fatalf("Unexpected event API URL %v", settings["runner_http_url"])
+	}
+	url := strings.Replace(settings["runner_http_url"], "10.0.0.1", "127.0.0.1", 1) + settings["runner_http_path"]
+	event, _ := json.Marshal(eventapi.JobEvent{UUID: "event", Counter: 1, Event: eventapi.EventPlaybookOnStats})
+	resp, err := http.Post(url, "application/json", bytes.NewReader(event))
+	if err != nil {
+		t.Fatalf("Error sending event: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Unexpected status %v sending event", resp.StatusCode)
+	}
+
+	// Finish the Job.
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "ansible-runner-1234-abcde",
+		Namespace: "operators",
+		Labels:    map[string]string{"job-name": job.GetName()},
+	}}
+	if _, err := clientset.CoreV1().Pods("operators").Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Error creating pod: %v", err)
+	}
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	if _, err := clientset.BatchV1().Jobs("operators").UpdateStatus(ctx, job, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Error updating Job: %v", err)
+	}
+
+	var events []eventapi.JobEvent
+	for e := range result.Events() {
+		events = append(events, e)
+	}
+	if len(events) != 1 || events[0].UUID != "event" {
+		t.Fatalf("Unexpected events %v", events)
+	}
+	stdout, err := result.Stdout()
+	if err != nil {
+		t.Fatalf("Error getting stdout: %v", err)
+	}
+	if stdout != "fake logs" {
+		t.Fatalf("Unexpected stdout %q", stdout)
+	}
+	status, err := ioutil.ReadFile(filepath.Join(jr.inputDirPath(u), "artifacts", "1234", "status"))
+	if err != nil || string(status) != "successful" {
+		t.Fatalf("Unexpected status %q: %v", status, err)
+	}
+
+	exportedDir := filepath.Join(sinkDir, "operator.example.com", "v1alpha1", "JobExample", "default", "example", "1234")
+	if expected := "file://" + exportedDir; result.ArtifactsURL() != expected {
+		t.Fatalf("Unexpected artifacts URL %v expected %v", result.ArtifactsURL(), expected)
+	}
+	for _, file := range []string{"stdout", "status", filepath.Join("job_events", "1-event.json")} {
+		if _, err := os.Stat(filepath.Join(exportedDir, file)); err != nil {
+			t.Fatalf("Artifact %v was not exported: %v", file, err)
+		}
+	}
+}
+
+func TestJobRunnerRunCancelled(t *testing.T) {
+	jr, clientset, u := newTestJobRunner(t, watches.Runner{Type: watches.RunnerTypeJob, Image: "operator"})
+	defer os.RemoveAll(jr.inputDirPath(u))
+
+	os.Setenv(PodIPEnvVar, "10.0.0.1")
+	defer os.Unsetenv(PodIPEnvVar)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	result, err := jr.Run(ctx, "5678", u, "")
+	if err != nil {
+		t.Fatalf("Error running: %v", err)
+	}
+	cancel()
+
+	// The events channel is closed although the Job has not finished.
+	for range result.Events() {
+	}
+	_, err = clientset.BatchV1().Jobs("operators").Get(context.TODO(), "ansible-runner-5678", metav1.GetOptions{})
+	for i := 0; i < 100 && !apierrors.IsNotFound(err); i++ {
+		time.Sleep(10 * time.Millisecond)
+		_, err = clientset.BatchV1().Jobs("operators").Get(context.TODO(), "ansible-runner-5678", metav1.GetOptions{})
+	}
+	if !apierrors.IsNotFound(err) {
+		t.Fatalf("The Job of the cancelled run was not deleted: %v", err)
+	}
+}
+
+func TestWaitForJobRetriesErrors(t *testing.T) {
+	jr, clientset, _ := newTestJobRunner(t, watches.Runner{Type: watches.RunnerTypeJob, Image: "operator"})
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "ansible-runner-1234", Namespace: "operators"},
+		Status: batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}},
+		},
+	}
+	if _, err := clientset.BatchV1().Jobs("operators").Create(context.TODO(), job, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Error creating Job: %v", err)
+	}
+	// Fail the first get of the Job.
+	failures := 0
+	clientset.PrependReactor("get", "jobs", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if failures > 0 {
+			return false, nil, nil
+		}
+		failures++
+		return true, nil, apierrors.NewServiceUnavailable("etcd is unavailable")
+	})
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	succeeded, err := jr.waitForJob(ctx, job.GetName())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !succeeded {
+		t.Fatal("Expected the Job to succeed")
+	}
+	if failures != 1 {
+		t.Fatalf("Unexpected failures %v expected 1", failures)
+	}
+}
+
+func TestWaitForJobNotFound(t *testing.T) {
+	jr, _, _ := newTestJobRunner(t, watches.Runner{Type: watches.RunnerTypeJob, Image: "operator"})
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	if _, err := jr.waitForJob(ctx, "ansible-runner-1234"); !apierrors.IsNotFound(err) {
+		t.Fatalf("Expected not found error, got %v", err)
+	}
+}
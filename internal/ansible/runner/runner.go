@@ -15,21 +15,28 @@
 package runner
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/operator-framework/operator-sdk/internal/ansible/metrics"
 	"github.com/operator-framework/operator-sdk/internal/ansible/paramconv"
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner/artifacts"
 	"github.com/operator-framework/operator-sdk/internal/ansible/runner/eventapi"
 	"github.com/operator-framework/operator-sdk/internal/ansible/runner/internal/inputdir"
 	"github.com/operator-framework/operator-sdk/internal/ansible/watches"
@@ -48,12 +55,23 @@ const (
 	// to the ansible-runner command. This will override the value for a particular CR.
 	// Example usage "ansible.sdk.operatorframework.io/verbosity: 5"
 	AnsibleVerbosityAnnotation = "ansible.sdk.operatorframework.io/verbosity"
+
+	// terminationGracePeriod - time given to ansible-runner to exit when a run
+	// is cancelled, before it is killed.
+	terminationGracePeriod = 10 * time.Second
+
+	// artifactExportTimeout - time given to the artifact sink to export the
+	// artifacts of a run. The artifacts are exported before the events
+	// channel of the run is closed, so exporting them delays the end of the
+	// reconciliation by up to this time.
+	artifactExportTimeout = 5 * time.Minute
 )
 
 // Runner - a runnable that should take the parameters and name and namespace
-// and run the correct code.
+// and run the correct code. When the context is cancelled, the run is
+// terminated and the events channel of its result is closed.
 type Runner interface {
-	Run(string, *unstructured.Unstructured, string) (RunResult, error)
+	Run(context.Context, string, *unstructured.Unstructured, string) (RunResult, error)
 	GetFinalizer() (string, bool)
 }
 
@@ -117,8 +135,15 @@ func roleCmdFunc(path string) cmdFuncType {
 	}
 }
 
-// New - creates a Runner from a Watch struct
-func New(watch watches.Watch, runnerArgs string) (Runner, error) {
+// New - creates a Runner from a Watch struct. If sink is not nil, the
+// artifacts of each run are exported to it.
+func New(watch watches.Watch, runnerArgs string, sink artifacts.Sink) (Runner, error) {
+	return newRunner(watch, runnerArgs, sink)
+}
+
+// newRunner - creates a runner that runs ansible-runner in the operator
+// container from a Watch struct.
+func newRunner(watch watches.Watch, runnerArgs string, sink artifacts.Sink) (*runner, error) {
 	var path string
 	var cmdFunc, finalizerCmdFunc cmdFuncType
 
@@ -161,6 +186,7 @@ func New(watch watches.Watch, runnerArgs string) (Runner, error) {
 		ansibleArgs:         runnerArgs,
 		snakeCaseParameters: watch.SnakeCaseParameters,
 		markUnsafe:          watch.MarkUnsafe,
+		artifactSink:        sink,
 	}, nil
 }
 
@@ -177,9 +203,10 @@ type runner struct {
 	snakeCaseParameters bool
 	markUnsafe          bool
 	ansibleArgs         string
+	artifactSink        artifacts.Sink
 }
 
-func (r *runner) Run(ident string, u *unstructured.Unstructured, kubeconfig string) (RunResult, error) {
+func (r *runner) Run(ctx context.Context, ident string, u *unstructured.Unstructured, kubeconfig string) (RunResult, error) {
 	timer := metrics.ReconcileTimer(r.GVK.String())
 	defer timer.ObserveDuration()
 
@@ -200,8 +227,7 @@ func (r *runner) Run(ident string, u *unstructured.Unstructured, kubeconfig stri
 		return nil, err
 	}
 	inputDir := inputdir.InputDir{
-		Path: filepath.Join("/tmp/ansible-operator/runner/", r.GVK.Group, r.GVK.Version, r.GVK.Kind,
-			u.GetNamespace(), u.GetName()),
+		Path:       r.inputDirPath(u),
 		Parameters: r.makeParameters(u),
 		EnvVars: map[string]string{
 			"K8S_AUTH_KUBECONFIG": kubeconfig,
@@ -226,6 +252,73 @@ func (r *runner) Run(ident string, u *unstructured.Unstructured, kubeconfig stri
 	if err != nil {
 		return nil, err
 	}
+	maxArtifacts := r.maxArtifacts(u)
+	verbosity := r.verbosity(u)
+	result := &runResult{
+		events:   receiver.Events,
+		inputDir: &inputDir,
+		ident:    ident,
+	}
+
+	go func() {
+		dc := r.command(ident, u, inputDir.Path, maxArtifacts, verbosity)
+		// Append current environment since setting dc.Env to anything other than nil overwrites current env
+		dc.Env = append(dc.Env, os.Environ()...)
+		dc.Env = append(dc.Env, fmt.Sprintf("K8S_AUTH_KUBECONFIG=%s", kubeconfig),
+			fmt.Sprintf("KUBECONFIG=%s", kubeconfig))
+
+		// Run ansible-runner in its own process group, so that ansible and
+		// its children can be terminated with it when the run is cancelled.
+		dc.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		var output bytes.Buffer
+		dc.Stdout = &output
+		dc.Stderr = &output
+
+		err := dc.Start()
+		if err == nil {
+			exited := make(chan struct{})
+			go func() {
+				select {
+				case <-ctx.Done():
+					logger.Info("Run cancelled, terminating ansible-runner")
+					terminateProcessGroup(logger, dc.Process.Pid, exited)
+				case <-exited:
+				}
+			}()
+			err = dc.Wait()
+			close(exited)
+		}
+		if err != nil {
+			logger.Error(err, output.String())
+		} else {
+			logger.Info("Ansible-runner exited successfully")
+		}
+
+		// The URL must be set before the receiver is closed, since it is
+		// read as soon as the events channel is closed.
+		result.artifactsURL = r.exportArtifacts(logger, u, inputDir.Path, ident, maxArtifacts)
+
+		receiver.Close()
+		err = <-errChan
+		// http.Server returns this in the case of being closed cleanly
+		if err != nil && err != http.ErrServerClosed {
+			logger.Error(err, "Error from event API")
+		}
+
+		linkLatestArtifacts(logger, inputDir.Path, ident)
+	}()
+
+	return result, nil
+}
+
+// inputDirPath - returns the path of the input directory of ansible-runner for u.
+func (r *runner) inputDirPath(u *unstructured.Unstructured) string {
+	return filepath.Join("/tmp/ansible-operator/runner/", r.GVK.Group, r.GVK.Version, r.GVK.Kind,
+		u.GetNamespace(), u.GetName())
+}
+
+// maxArtifacts - returns the max artifacts to keep for u, which may be set by an annotation.
+func (r *runner) maxArtifacts(u *unstructured.Unstructured) int {
 	maxArtifacts := r.maxRunnerArtifacts
 	if ma, ok := u.GetAnnotations()[MaxRunnerArtifactsAnnotation]; ok {
 		i, err := strconv.Atoi(ma)
@@ -235,7 +328,11 @@ func (r *runner) Run(ident string, u *unstructured.Unstructured, kubeconfig stri
 			maxArtifacts = i
 		}
 	}
+	return maxArtifacts
+}
 
+// verbosity - returns the ansible verbosity for u, which may be set by an annotation.
+func (r *runner) verbosity(u *unstructured.Unstructured) int {
 	verbosity := r.ansibleVerbosity
 	if av, ok := u.GetAnnotations()[AnsibleVerbosityAnnotation]; ok {
 		i, err := strconv.Atoi(av)
@@ -245,54 +342,74 @@ func (r *runner) Run(ident string, u *unstructured.Unstructured, kubeconfig stri
 			verbosity = i
 		}
 	}
+	return verbosity
+}
 
-	go func() {
-		var dc *exec.Cmd
-		if r.isFinalizerRun(u) {
-			logger.V(1).Info("Resource is marked for deletion, running finalizer",
-				"Finalizer", r.Finalizer.Name)
-			dc = r.finalizerCmdFunc(ident, inputDir.Path, maxArtifacts, verbosity)
-		} else {
-			dc = r.cmdFunc(ident, inputDir.Path, maxArtifacts, verbosity)
-		}
-		// Append current environment since setting dc.Env to anything other than nil overwrites current env
-		dc.Env = append(dc.Env, os.Environ()...)
-		dc.Env = append(dc.Env, fmt.Sprintf("K8S_AUTH_KUBECONFIG=%s", kubeconfig),
-			fmt.Sprintf("KUBECONFIG=%s", kubeconfig))
+// command - returns the ansible-runner command that reconciles u, or runs
+// its finalizer if u is marked for deletion.
+func (r *runner) command(ident string, u *unstructured.Unstructured, inputDirPath string,
+	maxArtifacts, verbosity int) *exec.Cmd {
+	if r.isFinalizerRun(u) {
+		log.V(1).Info("Resource is marked for deletion, running finalizer",
+			"job", ident, "name", u.GetName(), "namespace", u.GetNamespace(),
+			"Finalizer", r.Finalizer.Name)
+		return r.finalizerCmdFunc(ident, inputDirPath, maxArtifacts, verbosity)
+	}
+	return r.cmdFunc(ident, inputDirPath, maxArtifacts, verbosity)
+}
 
-		output, err := dc.CombinedOutput()
-		if err != nil {
-			logger.Error(err, string(output))
-		} else {
-			logger.Info("Ansible-runner exited successfully")
+// terminateProcessGroup - sends SIGTERM to the process group pgid, so that
+// ansible-runner can stop ansible gracefully, and SIGKILL if it has not
+// exited after terminationGracePeriod.
+func terminateProcessGroup(logger logr.Logger, pgid int, exited <-chan struct{}) {
+	if err := syscall.Kill(-pgid, syscall.SIGTERM); err != nil {
+		logger.Error(err, "Error terminating ansible-runner")
+	}
+	select {
+	case <-exited:
+	case <-time.After(terminationGracePeriod):
+		if err := syscall.Kill(-pgid, syscall.SIGKILL); err != nil {
+			logger.Error(err, "Error killing ansible-runner")
 		}
+	}
+}
 
-		receiver.Close()
-		err = <-errChan
-		// http.Server returns this in the case of being closed cleanly
-		if err != nil && err != http.ErrServerClosed {
-			logger.Error(err, "Error from event API")
-		}
+// exportArtifacts - exports the artifacts of the run ident of u, in the input
+// directory at inputDirPath, to the artifact sink of the runner if it has one.
+// It returns the URL of the exported artifacts, or an empty string if they
+// were not exported. It blocks until the sink has exported them, or
+// artifactExportTimeout has elapsed.
+func (r *runner) exportArtifacts(logger logr.Logger, u *unstructured.Unstructured, inputDirPath, ident string,
+	maxArtifacts int) string {
+	if r.artifactSink == nil {
+		return ""
+	}
+	// Artifacts are exported even if the run was cancelled, for post-mortems.
+	ctx, cancel := context.WithTimeout(context.Background(), artifactExportTimeout)
+	defer cancel()
+	key := path.Join(r.GVK.Group, r.GVK.Version, r.GVK.Kind, u.GetNamespace(), u.GetName())
+	url, err := r.artifactSink.Export(ctx, key, ident, filepath.Join(inputDirPath, "artifacts", ident), maxArtifacts)
+	if err != nil {
+		logger.Error(err, "Error exporting artifacts")
+		return ""
+	}
+	logger.V(1).Info("Exported artifacts", "url", url)
+	return url
+}
 
-		// link the current run to the `latest` directory under artifacts
-		currentRun := filepath.Join(inputDir.Path, "artifacts", ident)
-		latestArtifacts := filepath.Join(inputDir.Path, "artifacts", "latest")
-		if _, err = os.Lstat(latestArtifacts); err == nil {
-			if err = os.Remove(latestArtifacts); err != nil {
-				logger.Error(err, "Error removing the latest artifacts symlink")
-			}
-		}
-		if err = os.Symlink(currentRun, latestArtifacts); err != nil {
-			logger.Error(err, "Error symlinking latest artifacts")
+// linkLatestArtifacts - links the artifacts of the run ident to the `latest`
+// directory under artifacts.
+func linkLatestArtifacts(logger logr.Logger, inputDirPath, ident string) {
+	currentRun := filepath.Join(inputDirPath, "artifacts", ident)
+	latestArtifacts := filepath.Join(inputDirPath, "artifacts", "latest")
+	if _, err := os.Lstat(latestArtifacts); err == nil {
+		if err = os.Remove(latestArtifacts); err != nil {
+			logger.Error(err, "Error removing the latest artifacts symlink")
 		}
-
-	}()
-
-	return &runResult{
-		events:   receiver.Events,
-		inputDir: &inputDir,
-		ident:    ident,
-	}, nil
+	}
+	if err := os.Symlink(currentRun, latestArtifacts); err != nil {
+		logger.Error(err, "Error symlinking latest artifacts")
+	}
 }
 
 func (r *runner) isFinalizerRun(u *unstructured.Unstructured) bool {
@@ -313,6 +430,7 @@ func (r *runner) isFinalizerRun(u *unstructured.Unstructured) bool {
 // { "ansible_operator_meta": {
 //      "name": <object_name>,
 //      "namespace": <object_namespace>,
+//      "kind": <object_kind>,
 //   },
 //   <cr_spec_fields_as_snake_case>,
 //   <watch vars>,
@@ -349,7 +467,12 @@ func (r *runner) makeParameters(u *unstructured.Unstructured) map[string]interfa
 		}
 	}
 
-	parameters["ansible_operator_meta"] = map[string]string{"namespace": u.GetNamespace(), "name": u.GetName()}
+	// The kind lets roles that are shared by several kinds branch on it.
+	parameters["ansible_operator_meta"] = map[string]string{
+		"namespace": u.GetNamespace(),
+		"name":      u.GetName(),
+		"kind":      r.GVK.Kind,
+	}
 
 	objKey := escapeAnsibleKey(fmt.Sprintf("_%v_%v", r.GVK.Group, strings.ToLower(r.GVK.Kind)))
 	parameters[objKey] = u.Object
@@ -419,6 +542,9 @@ type RunResult interface {
 	Stdout() (string, error)
 	// Events returns the events from ansible-runner if it is available, else an error.
 	Events() <-chan eventapi.JobEvent
+	// ArtifactsURL returns the URL of the exported artifacts of the run once
+	// the events channel is closed, or an empty string if they were not exported.
+	ArtifactsURL() string
 }
 
 // RunResult facilitates access to information about a run of ansible.
@@ -427,8 +553,9 @@ type runResult struct {
 	// to a run of ansible.
 	events <-chan eventapi.JobEvent
 
-	ident    string
-	inputDir *inputdir.InputDir
+	ident        string
+	inputDir     *inputdir.InputDir
+	artifactsURL string
 }
 
 // Stdout returns the stdout from ansible-runner if it is available, else an error.
@@ -440,3 +567,8 @@ func (r *runResult) Stdout() (string, error) {
 func (r *runResult) Events() <-chan eventapi.JobEvent {
 	return r.events
 }
+
+// ArtifactsURL returns the URL of the exported artifacts of the run, if any.
+func (r *runResult) ArtifactsURL() string {
+	return r.artifactsURL
+}
@@ -159,7 +159,7 @@ func TestNew(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			testWatch := watches.New(tc.gvk, tc.role, tc.playbook, tc.vars, tc.finalizer)
 
-			testRunner, err := New(*testWatch, "")
+			testRunner, err := New(*testWatch, "", nil)
 			if err != nil {
 				t.Fatalf("Error occurred unexpectedly: %v", err)
 			}
@@ -371,3 +371,18 @@ func TestMakeParameters(t *testing.T) {
 		}
 	}
 }
+
+func TestMakeParametersMeta(t *testing.T) {
+	testRunner := runner{
+		GVK: schema.GroupVersionKind{Group: "app.example.com", Version: "v1alpha1", Kind: "Database"},
+	}
+	u := &unstructured.Unstructured{}
+	u.SetNamespace("default")
+	u.SetName("example")
+	parameters := testRunner.makeParameters(u)
+
+	expected := map[string]string{"namespace": "default", "name": "example", "kind": "Database"}
+	if meta := parameters["ansible_operator_meta"]; !reflect.DeepEqual(meta, expected) {
+		t.Fatalf("Unexpected ansible_operator_meta %v expected %v", meta, expected)
+	}
+}
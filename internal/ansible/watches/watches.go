@@ -27,8 +27,11 @@ import (
 	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	yaml "sigs.k8s.io/yaml"
 
@@ -47,6 +50,7 @@ type Watch struct {
 	Vars                        map[string]interface{}    `yaml:"vars"`
 	MaxRunnerArtifacts          int                       `yaml:"maxRunnerArtifacts"`
 	ReconcilePeriod             time.Duration             `yaml:"reconcilePeriod"`
+	RunTimeout                  time.Duration             `yaml:"runTimeout"`
 	Finalizer                   *Finalizer                `yaml:"finalizer"`
 	ManageStatus                bool                      `yaml:"manageStatus"`
 	WatchDependentResources     bool                      `yaml:"watchDependentResources"`
@@ -54,6 +58,7 @@ type Watch struct {
 	SnakeCaseParameters         bool                      `yaml:"snakeCaseParameters"`
 	MarkUnsafe                  bool                      `yaml:"markUnsafe"`
 	Selector                    metav1.LabelSelector      `yaml:"selector"`
+	Runner                      *Runner                   `yaml:"runner"`
 
 	// Not configurable via watches.yaml
 	MaxConcurrentReconciles int `yaml:"-"`
@@ -68,11 +73,34 @@ type Finalizer struct {
 	Vars     map[string]interface{} `yaml:"vars"`
 }
 
+// WildcardKind - kind of a watch that maps every kind of its group and version.
+// Wildcard watches are replaced by a watch per kind with ExpandWildcards.
+const WildcardKind = "*"
+
+// Types of Runner.
+const (
+	// RunnerTypeLocal - runs ansible-runner in the operator container.
+	RunnerTypeLocal = "local"
+	// RunnerTypeJob - runs ansible-runner in the pod of a Kubernetes Job.
+	RunnerTypeJob = "job"
+)
+
+// Runner - Configures the backend that runs ansible-runner for a watch.
+// Without a Runner, ansible-runner is run in the operator container.
+type Runner struct {
+	Type               string                      `yaml:"type"`
+	Image              string                      `yaml:"image"`
+	Namespace          string                      `yaml:"namespace"`
+	ServiceAccountName string                      `yaml:"serviceAccountName"`
+	Resources          corev1.ResourceRequirements `yaml:"resources"`
+}
+
 // Default values for optional fields on Watch
 var (
 	blacklistDefault                   = []schema.GroupVersionKind{}
 	maxRunnerArtifactsDefault          = 20
 	reconcilePeriodDefault             = metav1.Duration{Duration: time.Duration(0)}
+	runTimeoutDefault                  = metav1.Duration{Duration: time.Duration(0)}
 	manageStatusDefault                = true
 	watchDependentResourcesDefault     = true
 	watchClusterScopedResourcesDefault = false
@@ -120,11 +148,13 @@ type alias struct {
 	Group                       string                    `yaml:"group"`
 	Version                     string                    `yaml:"version"`
 	Kind                        string                    `yaml:"kind"`
+	Kinds                       []string                  `yaml:"kinds,omitempty"`
 	Playbook                    string                    `yaml:"playbook"`
 	Role                        string                    `yaml:"role"`
 	Vars                        map[string]interface{}    `yaml:"vars"`
 	MaxRunnerArtifacts          int                       `yaml:"maxRunnerArtifacts"`
 	ReconcilePeriod             *metav1.Duration          `yaml:"reconcilePeriod,omitempty"`
+	RunTimeout                  *metav1.Duration          `yaml:"runTimeout,omitempty"`
 	ManageStatus                *bool                     `yaml:"manageStatus,omitempty"`
 	WatchDependentResources     *bool                     `yaml:"watchDependentResources,omitempty"`
 	WatchClusterScopedResources *bool                     `yaml:"watchClusterScopedResources,omitempty"`
@@ -133,6 +163,7 @@ type alias struct {
 	Blacklist                   []schema.GroupVersionKind `yaml:"blacklist,omitempty"`
 	Finalizer                   *Finalizer                `yaml:"finalizer"`
 	Selector                    tempLabelSelector         `yaml:"selector"`
+	Runner                      *Runner                   `yaml:"runner,omitempty"`
 }
 
 // buildWatch will build Watch based on the values parsed from alias
@@ -153,6 +184,10 @@ func (w *Watch) setValuesFromAlias(tmp alias) error {
 		tmp.ReconcilePeriod = &reconcilePeriodDefault
 	}
 
+	if tmp.RunTimeout == nil {
+		tmp.RunTimeout = &runTimeoutDefault
+	}
+
 	if tmp.WatchClusterScopedResources == nil {
 		tmp.WatchClusterScopedResources = &watchClusterScopedResourcesDefault
 	}
@@ -187,6 +222,7 @@ func (w *Watch) setValuesFromAlias(tmp alias) error {
 	w.MaxRunnerArtifacts = tmp.MaxRunnerArtifacts
 	w.MaxConcurrentReconciles = getMaxConcurrentReconciles(gvk, maxConcurrentReconcilesDefault)
 	w.ReconcilePeriod = tmp.ReconcilePeriod.Duration
+	w.RunTimeout = tmp.RunTimeout.Duration
 	w.ManageStatus = *tmp.ManageStatus
 	w.WatchDependentResources = *tmp.WatchDependentResources
 	w.SnakeCaseParameters = *tmp.SnakeCaseParameters
@@ -195,6 +231,7 @@ func (w *Watch) setValuesFromAlias(tmp alias) error {
 	w.Finalizer = tmp.Finalizer
 	w.AnsibleVerbosity = getAnsibleVerbosity(gvk, ansibleVerbosityDefault)
 	w.Blacklist = tmp.Blacklist
+	w.Runner = tmp.Runner
 
 	wd, err := os.Getwd()
 	if err != nil {
@@ -284,6 +321,7 @@ func getPossibleRolePaths(rootDir, path string) []string {
 // A Watch is considered valid if it:
 // - Specifies a valid path to a Role||Playbook
 // - If a Finalizer is non-nil, it must have a name + valid path to a Role||Playbook or Vars
+// - If a Runner is non-nil, it must have a valid type, and an image if it runs Jobs
 func (w *Watch) Validate() error {
 	err := verifyAnsiblePath(w.Playbook, w.Role)
 	if err != nil {
@@ -306,9 +344,70 @@ func (w *Watch) Validate() error {
 		}
 	}
 
+	if w.Runner != nil {
+		err = w.Runner.validate()
+		if err != nil {
+			log.Error(err, fmt.Sprintf("Invalid runner for GVK: %v", w.GroupVersionKind.String()))
+			return err
+		}
+	}
+
 	return nil
 }
 
+func (r *Runner) validate() error {
+	switch r.Type {
+	case "", RunnerTypeLocal:
+		if r.Image != "" || r.Namespace != "" || r.ServiceAccountName != "" {
+			return fmt.Errorf("runner of type %q does not run pods", RunnerTypeLocal)
+		}
+	case RunnerTypeJob:
+		if r.Image == "" {
+			return fmt.Errorf("runner of type %q must have an image", RunnerTypeJob)
+		}
+	default:
+		return fmt.Errorf("runner type must be %q or %q, not %q", RunnerTypeLocal, RunnerTypeJob, r.Type)
+	}
+	return nil
+}
+
+// kinds - returns the kinds mapped by a watch entry, which sets either a kind or
+// a list of kinds.
+func (a alias) kinds() ([]string, error) {
+	if len(a.Kinds) == 0 {
+		return []string{a.Kind}, nil
+	}
+	gv := schema.GroupVersion{Group: a.Group, Version: a.Version}
+	if a.Kind != "" {
+		return nil, fmt.Errorf("watch for %v must not set both kind and kinds", gv)
+	}
+	for _, kind := range a.Kinds {
+		if kind == "" {
+			return nil, fmt.Errorf("invalid kinds of watch for %v: kind must not be empty", gv)
+		}
+		if kind == WildcardKind && len(a.Kinds) > 1 {
+			return nil, fmt.Errorf("invalid kinds of watch for %v: kind %q must be the only kind", gv, WildcardKind)
+		}
+	}
+	return a.Kinds, nil
+}
+
+// withKind - returns a copy of the watch for kind, in the same group and version.
+func (w Watch) withKind(kind string) Watch {
+	w.GroupVersionKind.Kind = kind
+	w.MaxConcurrentReconciles = getMaxConcurrentReconciles(w.GroupVersionKind, maxConcurrentReconcilesDefault)
+	w.AnsibleVerbosity = getAnsibleVerbosity(w.GroupVersionKind, ansibleVerbosityDefault)
+	if w.Finalizer != nil {
+		finalizer := *w.Finalizer
+		w.Finalizer = &finalizer
+	}
+	if w.Runner != nil {
+		runner := *w.Runner
+		w.Runner = &runner
+	}
+	return w
+}
+
 // New - returns a Watch with sensible defaults.
 func New(gvk schema.GroupVersionKind, role, playbook string, vars map[string]interface{}, finalizer *Finalizer) *Watch {
 	return &Watch{
@@ -320,6 +419,7 @@ func New(gvk schema.GroupVersionKind, role, playbook string, vars map[string]int
 		MaxRunnerArtifacts:          maxRunnerArtifactsDefault,
 		MaxConcurrentReconciles:     maxConcurrentReconcilesDefault,
 		ReconcilePeriod:             reconcilePeriodDefault.Duration,
+		RunTimeout:                  runTimeoutDefault.Duration,
 		ManageStatus:                manageStatusDefault,
 		WatchDependentResources:     watchDependentResourcesDefault,
 		WatchClusterScopedResources: watchClusterScopedResourcesDefault,
@@ -349,16 +449,23 @@ func Load(path string, maxReconciler, ansibleVerbosity int) ([]Watch, error) {
 		return nil, err
 	}
 
-	// Create one Watch per alias in aliases.
+	// Create one Watch per kind of each alias in aliases.
 
 	watches := []Watch{}
 	for _, tmp := range alias {
+		kinds, err := tmp.kinds()
+		if err != nil {
+			return nil, err
+		}
+		tmp.Kind = kinds[0]
 		w := Watch{}
 		err = w.setValuesFromAlias(tmp)
 		if err != nil {
 			return nil, err
 		}
-		watches = append(watches, w)
+		for _, kind := range kinds {
+			watches = append(watches, w.withKind(kind))
+		}
 	}
 
 	watchesMap := make(map[schema.GroupVersionKind]bool)
@@ -369,6 +476,15 @@ func Load(path string, maxReconciler, ansibleVerbosity int) ([]Watch, error) {
 		}
 
 		watchesMap[watch.GroupVersionKind] = true
+	}
+
+	for _, watch := range watches {
+		// a wildcard watch maps every kind of its group and version
+		wildcard := watch.GroupVersionKind.GroupVersion().WithKind(WildcardKind)
+		if watch.GroupVersionKind != wildcard && watchesMap[wildcard] {
+			return nil, fmt.Errorf("GVK %v overlaps the wildcard watch for %v", watch.GroupVersionKind.String(),
+				wildcard.GroupVersion())
+		}
 
 		err = watch.Validate()
 		if err != nil {
@@ -380,6 +496,39 @@ func Load(path string, maxReconciler, ansibleVerbosity int) ([]Watch, error) {
 	return watches, nil
 }
 
+// ExpandWildcards - replaces each wildcard watch of watches with a watch per
+// kind of its group and version that is served by the API server, as found
+// with the discovery client dc.
+func ExpandWildcards(watches []Watch, dc discovery.DiscoveryInterface) ([]Watch, error) {
+	expanded := []Watch{}
+	for _, w := range watches {
+		if w.GroupVersionKind.Kind != WildcardKind {
+			expanded = append(expanded, w)
+			continue
+		}
+		gv := w.GroupVersionKind.GroupVersion()
+		resources, err := dc.ServerResourcesForGroupVersion(gv.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover the kinds of the wildcard watch for %v: %w", gv, err)
+		}
+		kinds := sets.NewString()
+		for _, r := range resources.APIResources {
+			// subresources, such as status, have the kind of their resource
+			if !strings.Contains(r.Name, "/") {
+				kinds.Insert(r.Kind)
+			}
+		}
+		if kinds.Len() == 0 {
+			return nil, fmt.Errorf("no kinds found for the wildcard watch for %v", gv)
+		}
+		for _, kind := range kinds.List() {
+			log.Info("Expanding wildcard watch", "GroupVersion", gv.String(), "Kind", kind)
+			expanded = append(expanded, w.withKind(kind))
+		}
+	}
+	return expanded, nil
+}
+
 // verify that a given GroupVersionKind has a Version and Kind
 // A GVK without a group is valid. Certain scenarios may cause a GVK
 // without a group to fail in other ways later in the initialization
@@ -25,8 +25,12 @@ import (
 	"testing"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
 )
 
 func TestNew(t *testing.T) {
@@ -163,6 +167,16 @@ func TestLoad(t *testing.T) { //nolint:gocyclo
 			ReconcilePeriod: twoSeconds,
 			MarkUnsafe:      true,
 		},
+		Watch{
+			GroupVersionKind: schema.GroupVersionKind{
+				Version: "v1alpha1",
+				Group:   "app.example.com",
+				Kind:    "WithRunTimeout",
+			},
+			Playbook:     validTemplate.ValidPlaybook,
+			ManageStatus: true,
+			RunTimeout:   30 * time.Minute,
+		},
 		Watch{
 			GroupVersionKind: schema.GroupVersionKind{
 				Version: "v1alpha1",
@@ -382,6 +396,50 @@ func TestLoad(t *testing.T) { //nolint:gocyclo
 			},
 			ManageStatus: true,
 		},
+		Watch{
+			GroupVersionKind: schema.GroupVersionKind{
+				Version: "v1alpha1",
+				Group:   "app.example.com",
+				Kind:    "AnsibleJobRunnerTest",
+			},
+			Playbook:     validTemplate.ValidPlaybook,
+			ManageStatus: true,
+			Runner: &Runner{
+				Type:               RunnerTypeJob,
+				Image:              "quay.io/example/memcached-operator:v0.0.1",
+				ServiceAccountName: "memcached-operator-controller-manager",
+				Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+				},
+			},
+		},
+		Watch{
+			GroupVersionKind: schema.GroupVersionKind{
+				Version: "v1alpha1",
+				Group:   "app.example.com",
+				Kind:    "AnsibleKindsTestOne",
+			},
+			Playbook:     validTemplate.ValidPlaybook,
+			ManageStatus: true,
+		},
+		Watch{
+			GroupVersionKind: schema.GroupVersionKind{
+				Version: "v1alpha1",
+				Group:   "app.example.com",
+				Kind:    "AnsibleKindsTestTwo",
+			},
+			Playbook:     validTemplate.ValidPlaybook,
+			ManageStatus: true,
+		},
+		Watch{
+			GroupVersionKind: schema.GroupVersionKind{
+				Version: "v1alpha1",
+				Group:   "wildcard.example.com",
+				Kind:    WildcardKind,
+			},
+			Role:         validTemplate.ValidRole,
+			ManageStatus: true,
+		},
 	}
 
 	testCases := []struct {
@@ -399,6 +457,21 @@ func TestLoad(t *testing.T) { //nolint:gocyclo
 			path:        "testdata/duplicate_gvk.yaml",
 			shouldError: true,
 		},
+		{
+			name:        "error both kind and kinds",
+			path:        "testdata/invalid_kinds.yaml",
+			shouldError: true,
+		},
+		{
+			name:        "error wildcard with other kinds",
+			path:        "testdata/invalid_wildcard_kinds.yaml",
+			shouldError: true,
+		},
+		{
+			name:        "error GVK overlapping wildcard",
+			path:        "testdata/overlapping_wildcard.yaml",
+			shouldError: true,
+		},
 		{
 			name:        "error no file",
 			path:        "testdata/please_don't_create_me_gvk.yaml",
@@ -459,6 +532,11 @@ func TestLoad(t *testing.T) { //nolint:gocyclo
 			path:        "testdata/invalid_status.yaml",
 			shouldError: true,
 		},
+		{
+			name:        "error runner without image",
+			path:        "testdata/invalid_runner.yaml",
+			shouldError: true,
+		},
 		{
 			name:        "if collection env var is not set and collection is not installed to the default locations, fail",
 			path:        "testdata/invalid_collection.yaml",
@@ -551,6 +629,10 @@ func TestLoad(t *testing.T) { //nolint:gocyclo
 					t.Fatalf("The GVK: %v unexpected reconcile period: %v expected reconcile period: %v", gvk,
 						gotWatch.ReconcilePeriod, expectedWatch.ReconcilePeriod)
 				}
+				if gotWatch.RunTimeout != expectedWatch.RunTimeout {
+					t.Fatalf("The GVK: %v unexpected run timeout: %v expected run timeout: %v", gvk,
+						gotWatch.RunTimeout, expectedWatch.RunTimeout)
+				}
 				if gotWatch.MarkUnsafe != expectedWatch.MarkUnsafe {
 					t.Fatalf("The GVK: %v unexpected mark unsafe: %v expected mark unsafe: %v", gvk,
 						gotWatch.MarkUnsafe, expectedWatch.MarkUnsafe)
@@ -568,6 +650,11 @@ func TestLoad(t *testing.T) { //nolint:gocyclo
 						gotWatch.Selector, expectedWatch.Selector)
 				}
 
+				if !reflect.DeepEqual(gotWatch.Runner, expectedWatch.Runner) {
+					t.Fatalf("Incorrect runner GVK %s:\n\tgot %#v\n\texpected %#v", gvk,
+						gotWatch.Runner, expectedWatch.Runner)
+				}
+
 				if expectedWatch.MaxConcurrentReconciles == 0 {
 					if gotWatch.MaxConcurrentReconciles != tc.maxConcurrentReconciles {
 						t.Fatalf("Unexpected max workers: %v expected workers: %v", gotWatch.MaxConcurrentReconciles,
@@ -584,6 +671,54 @@ func TestLoad(t *testing.T) { //nolint:gocyclo
 	}
 }
 
+func TestExpandWildcards(t *testing.T) {
+	finalizer := &Finalizer{Name: "example.com/finalizer", Vars: map[string]interface{}{"sentinel": "finalizer_running"}}
+	explicit := New(schema.GroupVersionKind{Group: "app.example.com", Version: "v1alpha1", Kind: "Database"},
+		"", "playbook.yml", nil, nil)
+	wildcard := New(schema.GroupVersionKind{Group: "wildcard.example.com", Version: "v1alpha1", Kind: WildcardKind},
+		"role", "", nil, finalizer)
+	dc := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: []*metav1.APIResourceList{{
+		GroupVersion: "wildcard.example.com/v1alpha1",
+		APIResources: []metav1.APIResource{
+			{Name: "caches", Kind: "Cache"},
+			{Name: "caches/status", Kind: "Cache"},
+			{Name: "backups", Kind: "Backup"},
+		},
+	}}}}
+
+	expanded, err := ExpandWildcards([]Watch{*explicit, *wildcard}, dc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var gvks []schema.GroupVersionKind
+	for _, w := range expanded {
+		gvks = append(gvks, w.GroupVersionKind)
+	}
+	expected := []schema.GroupVersionKind{
+		explicit.GroupVersionKind,
+		{Group: "wildcard.example.com", Version: "v1alpha1", Kind: "Backup"},
+		{Group: "wildcard.example.com", Version: "v1alpha1", Kind: "Cache"},
+	}
+	if !reflect.DeepEqual(gvks, expected) {
+		t.Fatalf("Unexpected GVKs %v expected %v", gvks, expected)
+	}
+	if expanded[1].Role != "role" || expanded[2].Role != "role" {
+		t.Fatalf("Expanded watches should have the role of the wildcard watch: %v, %v", expanded[1].Role,
+			expanded[2].Role)
+	}
+	if expanded[1].Finalizer == expanded[2].Finalizer || !reflect.DeepEqual(expanded[1].Finalizer, finalizer) {
+		t.Fatalf("Expanded watches should have copies of the finalizer of the wildcard watch: %#v",
+			expanded[1].Finalizer)
+	}
+
+	// Wildcard watches fail to expand when their group and version are not served.
+	other := New(schema.GroupVersionKind{Group: "other.example.com", Version: "v1alpha1", Kind: WildcardKind},
+		"role", "", nil, nil)
+	if _, err := ExpandWildcards([]Watch{*other}, dc); err == nil {
+		t.Fatal("Expected error expanding wildcard watch of unknown group and version")
+	}
+}
+
 func TestMaxConcurrentReconciles(t *testing.T) {
 	testCases := []struct {
 		name          string
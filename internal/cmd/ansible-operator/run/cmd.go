@@ -25,6 +25,7 @@ import (
 
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
@@ -41,6 +42,7 @@ import (
 	"github.com/operator-framework/operator-sdk/internal/ansible/proxy"
 	"github.com/operator-framework/operator-sdk/internal/ansible/proxy/controllermap"
 	"github.com/operator-framework/operator-sdk/internal/ansible/runner"
+	"github.com/operator-framework/operator-sdk/internal/ansible/runner/artifacts"
 	"github.com/operator-framework/operator-sdk/internal/ansible/watches"
 	"github.com/operator-framework/operator-sdk/internal/clientbuilder"
 	"github.com/operator-framework/operator-sdk/internal/util/k8sutil"
@@ -180,14 +182,38 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 		os.Exit(1)
 	}
 
+	var sink artifacts.Sink
+	if f.ArtifactSink != "" {
+		sink, err = artifacts.NewSink(f.ArtifactSink)
+		if err != nil {
+			log.Error(err, "Failed to create artifact sink.")
+			os.Exit(1)
+		}
+	}
+
 	cMap := controllermap.NewControllerMap()
-	watches, err := watches.Load(f.WatchesFile, f.MaxConcurrentReconciles, f.AnsibleVerbosity)
+	ws, err := watches.Load(f.WatchesFile, f.MaxConcurrentReconciles, f.AnsibleVerbosity)
 	if err != nil {
 		log.Error(err, "Failed to load watches.")
 		os.Exit(1)
 	}
-	for _, w := range watches {
-		runner, err := runner.New(w, f.AnsibleArgs)
+	dc, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		log.Error(err, "Failed to create discovery client.")
+		os.Exit(1)
+	}
+	ws, err = watches.ExpandWildcards(ws, dc)
+	if err != nil {
+		log.Error(err, "Failed to expand wildcard watches.")
+		os.Exit(1)
+	}
+	for _, w := range ws {
+		var r runner.Runner
+		if w.Runner != nil && w.Runner.Type == watches.RunnerTypeJob {
+			r, err = runner.NewJob(w, f.AnsibleArgs, sink, mgr.GetConfig())
+		} else {
+			r, err = runner.New(w, f.AnsibleArgs, sink)
+		}
 		if err != nil {
 			log.Error(err, "Failed to create runner")
 			os.Exit(1)
@@ -195,12 +221,14 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 
 		ctr := controller.Add(mgr, controller.Options{
 			GVK:                     w.GroupVersionKind,
-			Runner:                  runner,
+			Runner:                  r,
 			ManageStatus:            w.ManageStatus,
 			AnsibleDebugLogs:        getAnsibleDebugLog(),
 			MaxConcurrentReconciles: w.MaxConcurrentReconciles,
 			ReconcilePeriod:         w.ReconcilePeriod,
 			Selector:                w.Selector,
+			ProgressUpdatePeriod:    f.ProgressUpdatePeriod,
+			RunTimeout:              w.RunTimeout,
 		})
 		if ctr == nil {
 			log.Error(fmt.Errorf("failed to add controller for GVK %v", w.GroupVersionKind.String()), "")
@@ -0,0 +1,136 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/release"
+	"github.com/operator-framework/operator-sdk/internal/helm/watches"
+)
+
+type renderCmd struct {
+	watchesFile string
+	valuesFiles []string
+	namespace   string
+}
+
+func NewCmd() *cobra.Command {
+	c := &renderCmd{}
+	cmd := &cobra.Command{
+		Use:   "render <custom-resource-file>",
+		Short: "Render the manifests of the release of a custom resource",
+		Long: `Render the manifests that the operator would apply for the release of the custom
+resource in a file, without a cluster. The release values are computed like the operator
+does, from the spec of the custom resource and the overrideValues of its watch, and owner
+references to the custom resource are injected into the manifests.
+
+Since values sources cannot be read without a cluster, the valuesFrom of the watch are
+ignored. Use --values to provide their values instead.
+
+Custom resources without a namespace are rendered in the namespace of --namespace, like
+kubectl creates them. Set --namespace to an empty string to render cluster-scoped custom
+resources.
+`,
+		Example: `  helm-operator render --watches-file watches.yaml config/samples/example_v1alpha1_nginx.yaml`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return c.run(cmd.OutOrStdout(), args[0])
+		},
+	}
+	cmd.Flags().StringVar(&c.watchesFile, "watches-file", "./watches.yaml", "Path to the watches file to use")
+	cmd.Flags().StringSliceVarP(&c.valuesFiles, "values", "f", nil,
+		"Values files merged in order in place of the valuesFrom sources of the watch")
+	cmd.Flags().StringVarP(&c.namespace, "namespace", "n", "default",
+		"Namespace of custom resources without one, or empty for cluster-scoped custom resources")
+	return cmd
+}
+
+func (c renderCmd) run(w io.Writer, crFile string) error {
+	data, err := ioutil.ReadFile(crFile)
+	if err != nil {
+		return fmt.Errorf("failed to read custom resource: %w", err)
+	}
+	// Decode with the unstructured JSON decoder, which keeps integers as int64
+	// like the operator's client does.
+	j, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse custom resource: %w", err)
+	}
+	cr := &unstructured.Unstructured{}
+	if err := cr.UnmarshalJSON(j); err != nil {
+		return fmt.Errorf("failed to parse custom resource: %w", err)
+	}
+	if cr.GetNamespace() == "" {
+		cr.SetNamespace(c.namespace)
+	}
+
+	ws, err := watches.Load(c.watchesFile)
+	if err != nil {
+		return fmt.Errorf("failed to load watches: %w", err)
+	}
+	var watch *watches.Watch
+	for i := range ws {
+		if ws[i].GroupVersionKind == cr.GroupVersionKind() {
+			// Only the chart of the watch of the custom resource is fetched.
+			if err := watches.FetchCharts(ws[i : i+1]); err != nil {
+				return err
+			}
+			watch = &ws[i]
+			break
+		}
+	}
+	if watch == nil {
+		return fmt.Errorf("no watch for %s in %s", cr.GroupVersionKind(), c.watchesFile)
+	}
+
+	sourceValues, err := release.LoadValuesFiles(c.valuesFiles)
+	if err != nil {
+		return err
+	}
+
+	opts := []release.ManagerFactoryOption{
+		release.ReleaseNamespace(watch.ReleaseNamespace()),
+	}
+	if watch.TargetRevisionFromSpec != "" {
+		opts = append(opts, release.IgnoredSpecFields(watch.TargetRevisionFromSpec))
+	}
+	if watch.PostRenderer != nil {
+		postRenderer, err := watch.PostRenderer.New()
+		if err != nil {
+			return fmt.Errorf("failed to create post-renderer: %w", err)
+		}
+		opts = append(opts, release.PostRenderer(postRenderer))
+	}
+
+	// Watches with several charts render the release of each chart in order.
+	if len(watch.Charts) == 0 {
+		return release.NewRenderer(watch.ChartDir, opts...).Render(w, cr, sourceValues, watch.OverrideValues)
+	}
+	for _, chart := range watch.Charts {
+		chartOpts := append([]release.ManagerFactoryOption{release.ReleaseNameSuffix(chart.Name)}, opts...)
+		renderer := release.NewRenderer(chart.ChartDir, chartOpts...)
+		if err := renderer.Render(w, cr, sourceValues, watch.OverrideValues); err != nil {
+			return fmt.Errorf("failed to render chart %q: %w", chart.Name, err)
+		}
+	}
+	return nil
+}
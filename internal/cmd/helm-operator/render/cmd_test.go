@@ -0,0 +1,134 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const testChartDir = "../../../plugins/helm/v1/chartutil/testdata/test-chart"
+
+var _ = Describe("Running a render command", func() {
+	var (
+		dir         string
+		watchesFile string
+		crFile      string
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "render")
+		Expect(err).NotTo(HaveOccurred())
+		chartDir, err := filepath.Abs(testChartDir)
+		Expect(err).NotTo(HaveOccurred())
+
+		watchesFile = filepath.Join(dir, "watches.yaml")
+		watches := fmt.Sprintf(`- group: example.com
+  version: v1
+  kind: Test
+  chart: %s
+`, chartDir)
+		Expect(ioutil.WriteFile(watchesFile, []byte(watches), 0644)).To(Succeed())
+
+		crFile = filepath.Join(dir, "cr.yaml")
+		cr := `apiVersion: example.com/v1
+kind: Test
+metadata:
+  name: test
+  namespace: ns
+spec:
+  replicaCount: 1000000
+  podSecurityContext:
+    runAsUser: 9007199254740993
+`
+		Expect(ioutil.WriteFile(crFile, []byte(cr), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	Describe("NewCmd", func() {
+		It("builds a cobra command", func() {
+			cmd := NewCmd()
+			Expect(cmd).NotTo(BeNil())
+			Expect(cmd.Use).NotTo(Equal(""))
+			Expect(cmd.Short).NotTo(Equal(""))
+		})
+	})
+
+	Describe("run", func() {
+		It("renders the manifests of the release of the custom resource", func() {
+			var out bytes.Buffer
+			cmd := NewCmd()
+			cmd.SetOut(&out)
+			cmd.SetArgs([]string{"--watches-file", watchesFile, crFile})
+			Expect(cmd.Execute()).To(Succeed())
+
+			Expect(out.String()).To(ContainSubstring("# Source: test-chart/templates/deployment.yaml\n"))
+			Expect(out.String()).To(ContainSubstring("name: test-test-chart\n"))
+			// Integers of the custom resource are not decoded as floats, which
+			// would round those beyond 2^53.
+			Expect(out.String()).To(ContainSubstring("replicas: 1000000\n"))
+			Expect(out.String()).To(ContainSubstring("runAsUser: 9007199254740993\n"))
+		})
+
+		It("renders custom resources without a namespace in the namespace flag", func() {
+			Expect(ioutil.WriteFile(crFile, []byte("apiVersion: example.com/v1\nkind: Test\nmetadata:\n  name: test\nspec: {}\n"),
+				0644)).To(Succeed())
+
+			var out bytes.Buffer
+			cmd := NewCmd()
+			cmd.SetOut(&out)
+			cmd.SetArgs([]string{"--watches-file", watchesFile, crFile})
+			Expect(cmd.Execute()).To(Succeed())
+			Expect(out.String()).To(ContainSubstring("name: test-test-chart\n"))
+			Expect(out.String()).To(ContainSubstring("namespace: default\n"))
+
+			out.Reset()
+			cmd = NewCmd()
+			cmd.SetOut(&out)
+			cmd.SetArgs([]string{"--watches-file", watchesFile, "--namespace", "other", crFile})
+			Expect(cmd.Execute()).To(Succeed())
+			Expect(out.String()).To(ContainSubstring("namespace: other\n"))
+		})
+
+		It("merges values files over the spec", func() {
+			valuesFile := filepath.Join(dir, "values.yaml")
+			Expect(ioutil.WriteFile(valuesFile, []byte("replicaCount: 3\n"), 0644)).To(Succeed())
+
+			var out bytes.Buffer
+			c := renderCmd{watchesFile: watchesFile, valuesFiles: []string{valuesFile}, namespace: "default"}
+			Expect(c.run(&out, crFile)).To(Succeed())
+			Expect(out.String()).To(ContainSubstring("replicas: 3\n"))
+		})
+
+		It("fails if no watch matches the custom resource", func() {
+			Expect(ioutil.WriteFile(watchesFile, []byte("- group: example.com\n  version: v1\n  kind: Other\n  chart: "+
+				testChartDir+"\n"), 0644)).To(Succeed())
+
+			var out bytes.Buffer
+			c := renderCmd{watchesFile: watchesFile}
+			Expect(c.run(&out, crFile)).To(MatchError(ContainSubstring("no watch for example.com/v1, Kind=Test")))
+		})
+	})
+})
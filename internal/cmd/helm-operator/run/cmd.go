@@ -21,6 +21,7 @@ import (
 	"os"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -40,6 +41,7 @@ import (
 	"github.com/operator-framework/operator-sdk/internal/helm/metrics"
 	"github.com/operator-framework/operator-sdk/internal/helm/release"
 	"github.com/operator-framework/operator-sdk/internal/helm/watches"
+	"github.com/operator-framework/operator-sdk/internal/helm/webhook"
 	"github.com/operator-framework/operator-sdk/internal/util/k8sutil"
 	sdkVersion "github.com/operator-framework/operator-sdk/internal/version"
 )
@@ -92,7 +94,7 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 			os.Exit(1)
 		}
 	}
-	exitIfUnsupported(options)
+	exitIfUnsupported(options, f.EnableValidatingWebhook)
 
 	cfg, err := config.GetConfig()
 	if err != nil {
@@ -174,21 +176,100 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 		log.Error(err, "Failed to create new manager factories.")
 		os.Exit(1)
 	}
+	if err := watches.FetchCharts(ws); err != nil {
+		log.Error(err, "Failed to fetch remote charts.")
+		os.Exit(1)
+	}
 	for _, w := range ws {
+		reconcilePeriod := f.ReconcilePeriod
+		if w.ReconcilePeriod != nil {
+			reconcilePeriod = w.ReconcilePeriod.Duration
+		}
+		maxConcurrentReconciles := f.MaxConcurrentReconciles
+		if w.MaxConcurrentReconciles > 0 {
+			maxConcurrentReconciles = w.MaxConcurrentReconciles
+		}
+
+		factoryOpts := []release.ManagerFactoryOption{
+			release.MaxHistory(w.MaxHistory),
+			release.ServerSideApply(w.ServerSideApply),
+			release.ReleaseNamespace(w.ReleaseNamespace()),
+		}
+		var ignoredSpecFields []string
+		if w.TargetRevisionFromSpec != "" {
+			ignoredSpecFields = []string{w.TargetRevisionFromSpec}
+			factoryOpts = append(factoryOpts, release.IgnoredSpecFields(ignoredSpecFields...))
+		}
+		if w.PostRenderer != nil {
+			postRenderer, err := w.PostRenderer.New()
+			if err != nil {
+				log.Error(err, "Failed to create post-renderer.")
+				os.Exit(1)
+			}
+			factoryOpts = append(factoryOpts, release.PostRenderer(postRenderer))
+		}
+		var timeout time.Duration
+		if w.Timeout != nil {
+			timeout = w.Timeout.Duration
+			factoryOpts = append(factoryOpts, release.ReleaseTimeout(timeout))
+		}
+
+		// Watches with several charts have a manager factory per chart, whose
+		// releases are named after the chart.
+		var (
+			managerFactory release.ManagerFactory
+			charts         []controller.ChartRelease
+			chartDirs      []string
+		)
+		if len(w.Charts) > 0 {
+			for _, c := range w.Charts {
+				opts := append([]release.ManagerFactoryOption{release.ReleaseNameSuffix(c.Name)}, factoryOpts...)
+				charts = append(charts, controller.ChartRelease{
+					Name:           c.Name,
+					ManagerFactory: release.NewManagerFactory(mgr, c.ChartDir, opts...),
+					DependsOn:      c.DependsOn,
+				})
+				chartDirs = append(chartDirs, c.ChartDir)
+			}
+		} else {
+			managerFactory = release.NewManagerFactory(mgr, w.ChartDir, factoryOpts...)
+			chartDirs = []string{w.ChartDir}
+		}
+
 		// Register the controller with the factory.
 		err := controller.Add(mgr, controller.WatchOptions{
 			Namespace:               namespace,
 			GVK:                     w.GroupVersionKind,
-			ManagerFactory:          release.NewManagerFactory(mgr, w.ChartDir),
-			ReconcilePeriod:         f.ReconcilePeriod,
+			ManagerFactory:          managerFactory,
+			ReconcilePeriod:         reconcilePeriod,
 			WatchDependentResources: *w.WatchDependentResources,
 			OverrideValues:          w.OverrideValues,
-			MaxConcurrentReconciles: f.MaxConcurrentReconciles,
+			MaxConcurrentReconciles: maxConcurrentReconciles,
+			RollbackOnFailure:       w.OnFailure == watches.OnFailureRollback,
+			ValuesFrom:              w.ValuesFrom,
+			Selector:                w.Selector,
+			Blacklist:               w.Blacklist,
+			Charts:                  charts,
+			TargetRevisionField:     w.TargetRevisionFromSpec,
+			Wait:                    w.Wait || w.Atomic,
+			Timeout:                 timeout,
+			Atomic:                  w.Atomic,
 		})
 		if err != nil {
 			log.Error(err, "Failed to add manager factory to controller.")
 			os.Exit(1)
 		}
+
+		if f.EnableValidatingWebhook {
+			webhook.Add(mgr, webhook.Options{
+				GVK:               w.GroupVersionKind,
+				ChartDirs:         chartDirs,
+				OverrideValues:    w.OverrideValues,
+				ValuesFrom:        w.ValuesFrom,
+				TargetNamespace:   w.ReleaseNamespace(),
+				IgnoredSpecFields: ignoredSpecFields,
+			})
+		}
 	}
 
 	// Start the Cmd
@@ -199,10 +280,14 @@ func run(cmd *cobra.Command, f *flags.Flags) {
 }
 
 // exitIfUnsupported prints an error containing unsupported field names and exits
-// if any of those fields are not their default values.
-func exitIfUnsupported(options manager.Options) {
+// if any of those fields are not their default values. Webhook options are only
+// supported when the validating webhook is enabled.
+func exitIfUnsupported(options manager.Options, webhookEnabled bool) {
+	if webhookEnabled {
+		return
+	}
 	var keys []string
-	// The below options are webhook-specific, which is not supported by ansible.
+	// The below options are webhook-specific, which are only used by the validating webhook.
 	if options.CertDir != "" {
 		keys = append(keys, "certDir")
 	}
@@ -100,6 +100,17 @@ func NewRESTClientGetter(mgr manager.Manager, ns string) (genericclioptions.REST
 	}, nil
 }
 
+// NewLocalRESTClientGetter returns a RESTClientGetter that maps resources in
+// namespace ns with restMapper, without contacting a cluster. The clients it
+// configures must not be used to send requests.
+func NewLocalRESTClientGetter(restMapper meta.RESTMapper, ns string) genericclioptions.RESTClientGetter {
+	return &restClientGetter{
+		restConfig:      &rest.Config{},
+		restMapper:      restMapper,
+		namespaceConfig: &namespaceClientConfig{ns},
+	}
+}
+
 var _ kube.Interface = &ownerRefInjectingClient{}
 
 func NewOwnerRefInjectingClient(base kube.Client, restMapper meta.RESTMapper,
@@ -0,0 +1,643 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	rpb "helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/internal/diff"
+	"github.com/operator-framework/operator-sdk/internal/helm/internal/types"
+	"github.com/operator-framework/operator-sdk/internal/helm/metrics"
+	"github.com/operator-framework/operator-sdk/internal/helm/release"
+)
+
+// ChartRelease is the release of one of the charts composing the custom
+// resources of a watch.
+type ChartRelease struct {
+	// Name identifies the chart. Its releases are named after their custom
+	// resource, suffixed with Name.
+	Name           string
+	ManagerFactory release.ManagerFactory
+	// DependsOn lists the charts, before this chart in the watch, whose
+	// releases must be ready before this chart's release is installed or
+	// upgraded.
+	DependsOn []string
+}
+
+// chartResult is the outcome of reconciling the release of a chart.
+type chartResult struct {
+	release   *rpb.Release
+	drifts    []release.Drift
+	conflicts []release.Conflict
+}
+
+// reconcileCharts reconciles a custom resource composed of several charts.
+// Releases are installed, upgraded and reconciled in the order of r.Charts,
+// and a release is not installed or upgraded until the releases it depends
+// on are ready. Releases are uninstalled in reverse order.
+func (r HelmOperatorReconciler) reconcileCharts(ctx context.Context, log logr.Logger, o *unstructured.Unstructured,
+	sourceValues map[string]interface{}) (reconcile.Result, error) {
+	status := types.StatusFor(o)
+	if o.GetDeletionTimestamp() != nil {
+		return r.uninstallCharts(ctx, log, o, status)
+	}
+	if hasAnnotation(helmDryRunAnnotation, o) {
+		return r.dryRunCharts(ctx, log, o, status, sourceValues)
+	}
+
+	// The releases of the charts have unrelated revisions, so they cannot be
+	// pinned to a single target revision.
+	if o.GetAnnotations()[helmTargetRevisionAnnotation] != "" {
+		err := errors.New("the target revision annotation is not supported by watches with several charts")
+		log.Error(err, "Failed to reconcile charts")
+		r.EventRecorder.Event(o, "Warning", "Unsupported", err.Error())
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionIrreconcilable,
+			Status:  types.StatusTrue,
+			Reason:  types.ReasonUnsupported,
+			Message: err.Error(),
+		})
+		err = r.updateResourceStatus(ctx, o, status)
+		return reconcile.Result{}, err
+	}
+	if hasAnnotation(helmTestAnnotation, o) {
+		if err := r.rejectChartsTest(ctx, log, o, status); err != nil {
+			log.Info("Failed to remove CR test annotation")
+			return reconcile.Result{}, err
+		}
+	}
+
+	status.SetCondition(types.HelmAppCondition{
+		Type:   types.ConditionInitialized,
+		Status: types.StatusTrue,
+	})
+	status.RemoveCondition(types.ConditionPaused)
+
+	// The finalizer is added before any release is installed, so that the
+	// releases installed before a failed release are uninstalled as well.
+	if !(controllerutil.ContainsFinalizer(o, uninstallFinalizer) ||
+		controllerutil.ContainsFinalizer(o, uninstallFinalizerLegacy)) {
+
+		log.V(1).Info("Adding finalizer", "finalizer", uninstallFinalizer)
+		controllerutil.AddFinalizer(o, uninstallFinalizer)
+		if err := r.updateResource(ctx, o); err != nil {
+			log.Info("Failed to add CR uninstall finalizer")
+			return reconcile.Result{}, err
+		}
+	}
+
+	var (
+		ready     = map[string]bool{}
+		names     = make([]string, 0, len(r.Charts))
+		drifts    []release.Drift
+		conflicts []release.Conflict
+		notReady  []string
+		waiting   []string
+		blockedBy string
+		pending   string
+		installed = true
+	)
+	for _, c := range r.Charts {
+		log := log.WithValues("chart", c.Name)
+		names = append(names, c.Name)
+
+		if blockedBy == "" {
+			for _, dep := range c.DependsOn {
+				if !ready[dep] {
+					blockedBy = c.Name
+					pending = fmt.Sprintf("Chart %s is waiting for chart %s to be ready", c.Name, dep)
+					break
+				}
+			}
+		}
+		if blockedBy != "" {
+			log.V(1).Info("Waiting for dependencies", "blockedBy", blockedBy)
+			chartRelease := chartReleaseFor(status, c.Name)
+			chartRelease.State = types.ChartPending
+			chartRelease.Message = pending
+			chartRelease.DryRunDiff = ""
+			if c.Name != blockedBy {
+				chartRelease.Message = fmt.Sprintf("Waiting for chart %s", blockedBy)
+			}
+			status.SetChartRelease(chartRelease)
+			continue
+		}
+
+		result, err := r.reconcileChart(ctx, log, o, status, c, sourceValues)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		drifts = append(drifts, result.drifts...)
+		conflicts = append(conflicts, result.conflicts...)
+		if result.release.Version != 1 {
+			installed = false
+		}
+
+		chartRelease := chartReleaseFor(status, c.Name)
+		if chartRelease.State == types.ChartReady {
+			ready[c.Name] = true
+		} else {
+			notReady = append(notReady, fmt.Sprintf("chart %s: %s", c.Name, chartRelease.Message))
+		}
+		if chartRelease.Progress != nil {
+			waiting = append(waiting, fmt.Sprintf("chart %s: %s", c.Name, chartRelease.Message))
+		}
+	}
+
+	status.RemoveCondition(types.ConditionReleaseFailed)
+	status.RemoveCondition(types.ConditionIrreconcilable)
+	if len(conflicts) > 0 {
+		r.recordConflicts(log, o, status, &release.ConflictError{Conflicts: conflicts})
+	} else {
+		status.RemoveCondition(types.ConditionConflicted)
+	}
+	r.recordDrifts(log, o, status, drifts)
+	status.DeployedRelease = nil
+
+	switch {
+	case blockedBy != "":
+		log.Info("Reconciled charts, waiting for dependencies", "blockedBy", blockedBy)
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionDeployed,
+			Status:  types.StatusFalse,
+			Reason:  types.ReasonDependenciesPending,
+			Message: pending,
+		})
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionReady,
+			Status:  types.StatusFalse,
+			Reason:  types.ReasonDependenciesPending,
+			Message: pending,
+		})
+	case len(waiting) > 0:
+		log.Info("Reconciled charts, waiting for release resources to be ready")
+		message := strings.Join(waiting, "; ")
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionProgressing,
+			Status:  types.StatusTrue,
+			Reason:  types.ReasonWaitingForResources,
+			Message: message,
+		})
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionDeployed,
+			Status:  types.StatusFalse,
+			Reason:  types.ReasonWaitingForResources,
+			Message: message,
+		})
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionReady,
+			Status:  types.StatusFalse,
+			Reason:  types.ReasonResourcesNotReady,
+			Message: strings.Join(notReady, "; "),
+		})
+	default:
+		log.Info("Reconciled charts")
+		if r.Wait {
+			status.SetCondition(types.HelmAppCondition{
+				Type:   types.ConditionProgressing,
+				Status: types.StatusFalse,
+				Reason: types.ReasonResourcesReady,
+			})
+		}
+		reason := types.ReasonUpgradeSuccessful
+		if installed {
+			reason = types.ReasonInstallSuccessful
+		}
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionDeployed,
+			Status:  types.StatusTrue,
+			Reason:  reason,
+			Message: fmt.Sprintf("Deployed charts: %s", strings.Join(names, ", ")),
+		})
+		if len(notReady) > 0 {
+			status.SetCondition(types.HelmAppCondition{
+				Type:    types.ConditionReady,
+				Status:  types.StatusFalse,
+				Reason:  types.ReasonResourcesNotReady,
+				Message: strings.Join(notReady, "; "),
+			})
+		} else {
+			status.SetCondition(types.HelmAppCondition{
+				Type:   types.ConditionReady,
+				Status: types.StatusTrue,
+				Reason: types.ReasonResourcesReady,
+			})
+		}
+	}
+
+	err := r.updateResourceStatus(ctx, o, status)
+	return reconcile.Result{RequeueAfter: r.requeuePeriod(status)}, err
+}
+
+// reconcileChart installs, upgrades or reconciles the release of chart c,
+// and records its state in status. Failures are recorded in the status of o,
+// which is then updated.
+func (r HelmOperatorReconciler) reconcileChart(ctx context.Context, log logr.Logger, o *unstructured.Unstructured,
+	status *types.HelmAppStatus, c ChartRelease, sourceValues map[string]interface{}) (chartResult, error) {
+	var result chartResult
+	manager, err := c.ManagerFactory.NewManager(o, sourceValues, r.OverrideValues)
+	if err != nil {
+		log.Error(err, "Failed to get release manager")
+		return result, r.failChart(ctx, log, o, status, c.Name,
+			types.ConditionIrreconcilable, types.ReasonReconcileError, err)
+	}
+	log = log.WithValues("release", manager.ReleaseName())
+
+	if err := manager.Sync(ctx); err != nil {
+		log.Error(err, "Failed to sync release")
+		r.recordRelease(o, manager, nil, err)
+		return result, r.failChart(ctx, log, o, status, c.Name,
+			types.ConditionIrreconcilable, types.ReasonReconcileError, err)
+	}
+
+	// Only the revisions installed or upgraded by the operator are waited
+	// for. Resources of a revision that was already ready only update the
+	// state of the chart.
+	var progress *types.HelmAppProgress
+	switch {
+	case !manager.IsInstalled():
+		for k, v := range r.OverrideValues {
+			r.EventRecorder.Eventf(o, "Warning", "OverrideValuesInUse",
+				"Chart value %q overridden to %q by operator's watches.yaml", k, v)
+		}
+		timer := metrics.ReleaseOperationTimer(r.GVK.String(), metrics.OperationInstall)
+		installedRelease, err := manager.InstallRelease(ctx)
+		timer.ObserveDuration()
+		r.recordRelease(o, manager, installedRelease, err)
+		if err != nil {
+			log.Error(err, "Release failed")
+			return result, r.failChart(ctx, log, o, status, c.Name,
+				types.ConditionReleaseFailed, types.ReasonInstallError, err)
+		}
+		log.Info("Installed release")
+		if log.V(0).Enabled() {
+			fmt.Println(diff.Generate("", installedRelease.Manifest))
+		}
+		result.release = installedRelease
+		if r.Wait {
+			progress = newProgress(installedRelease)
+		}
+	case manager.IsUpgradeRequired():
+		for k, v := range r.OverrideValues {
+			r.EventRecorder.Eventf(o, "Warning", "OverrideValuesInUse",
+				"Chart value %q overridden to %q by operator's watches.yaml", k, v)
+		}
+		force := hasAnnotation(helmUpgradeForceAnnotation, o)
+		timer := metrics.ReleaseOperationTimer(r.GVK.String(), metrics.OperationUpgrade)
+		previousRelease, upgradedRelease, err := manager.UpgradeRelease(ctx, release.ForceUpgrade(force))
+		timer.ObserveDuration()
+		r.recordRelease(o, manager, upgradedRelease, err)
+		if err != nil && (r.RollbackOnFailure || r.Atomic) {
+			return result, r.rollbackChart(ctx, log, o, status, c.Name, manager, err)
+		}
+		if err != nil {
+			log.Error(err, "Release failed")
+			return result, r.failChart(ctx, log, o, status, c.Name,
+				types.ConditionReleaseFailed, types.ReasonUpgradeError, err)
+		}
+		log.Info("Upgraded release", "force", force)
+		if log.V(0).Enabled() {
+			fmt.Println(diff.Generate(previousRelease.Manifest, upgradedRelease.Manifest))
+		}
+		result.release = upgradedRelease
+		if r.Wait {
+			progress = newProgress(upgradedRelease)
+		}
+	default:
+		timer := metrics.ReleaseOperationTimer(r.GVK.String(), metrics.OperationReconcile)
+		expectedRelease, drifts, err := manager.ReconcileRelease(ctx)
+		timer.ObserveDuration()
+		var conflictErr *release.ConflictError
+		if errors.As(err, &conflictErr) {
+			result.conflicts = conflictErr.Conflicts
+			err = nil
+		}
+		r.recordRelease(o, manager, expectedRelease, err)
+		if err != nil {
+			log.Error(err, "Failed to reconcile release")
+			return result, r.failChart(ctx, log, o, status, c.Name,
+				types.ConditionIrreconcilable, types.ReasonReconcileError, err)
+		}
+		log.V(1).Info("Reconciled release")
+		result.release, result.drifts = expectedRelease, drifts
+		if previous := chartReleaseFor(status, c.Name).Progress; r.Wait && previous != nil &&
+			previous.Revision == expectedRelease.Version {
+			progress = previous
+		}
+	}
+
+	if r.releaseHook != nil {
+		if err := r.releaseHook(result.release); err != nil {
+			log.Error(err, "Failed to run release hook")
+			return result, err
+		}
+	}
+
+	chartRelease := types.HelmAppChartRelease{
+		Name:  c.Name,
+		State: types.ChartReady,
+		DeployedRelease: &types.HelmAppRelease{
+			Name:      result.release.Name,
+			Namespace: result.release.Namespace,
+			Manifest:  result.release.Manifest,
+		},
+	}
+	resources, notReady, err := resourceStatuses(ctx, manager, result.release.Manifest)
+	switch {
+	case err != nil:
+		log.Error(err, "Failed to get release resource statuses")
+		chartRelease.State = types.ChartDeployed
+		chartRelease.Message = err.Error()
+	case len(notReady) > 0:
+		chartRelease.State = types.ChartDeployed
+		chartRelease.Message = strings.Join(notReady, "; ")
+	}
+	chartRelease.DeployedRelease.Resources = resources
+	if chartRelease.State != types.ChartReady {
+		chartRelease.Progress = progress
+	}
+	status.SetChartRelease(chartRelease)
+
+	if chartRelease.Progress != nil && r.waitRemaining(chartRelease.Progress) <= 0 {
+		waitErr := fmt.Errorf("timed out after %s waiting for release resources to be ready: %s",
+			r.waitTimeout(), chartRelease.Message)
+		r.recordRelease(o, manager, nil, waitErr)
+		switch {
+		case r.Atomic && result.release.Version > 1:
+			return result, r.rollbackChart(ctx, log, o, status, c.Name, manager, waitErr, release.RollbackToPrevious())
+		case r.Atomic:
+			log.Error(waitErr, "Release failed, uninstalling")
+			if _, err := manager.UninstallRelease(ctx); err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+				log.Error(err, "Failed to uninstall release")
+				return result, r.failChart(ctx, log, o, status, c.Name, types.ConditionReleaseFailed, types.ReasonUninstallError,
+					fmt.Errorf("failed install (%s) and failed uninstall: %w", waitErr, err))
+			}
+			metrics.ReleaseUninstalled(r.GVK.String(), o.GetNamespace(), manager.ReleaseName())
+			chartRelease.DeployedRelease = nil
+			chartRelease.Progress = nil
+			status.SetChartRelease(chartRelease)
+			r.EventRecorder.Eventf(o, "Warning", "Uninstalled",
+				"Install of chart %s timed out, uninstalled release: %s", c.Name, waitErr)
+		default:
+			log.Error(waitErr, "Release failed")
+		}
+		return result, r.failChart(ctx, log, o, status, c.Name, types.ConditionReleaseFailed, types.ReasonWaitTimeout, waitErr)
+	}
+	return result, nil
+}
+
+// dryRunCharts renders the releases of the charts of o that would be
+// installed or upgraded, without changing them or adding the finalizer. The
+// manifest diff of each release is reported in the status of its chart, with
+// the data of Secrets redacted and the diffs together truncated to
+// maxDryRunDiffSize, and its changed resources as an event.
+func (r HelmOperatorReconciler) dryRunCharts(ctx context.Context, log logr.Logger, o *unstructured.Unstructured,
+	status *types.HelmAppStatus, sourceValues map[string]interface{}) (reconcile.Result, error) {
+	maxDiffSize := maxDryRunDiffSize / len(r.Charts)
+	for _, c := range r.Charts {
+		log := log.WithValues("chart", c.Name)
+		manager, err := c.ManagerFactory.NewManager(o, sourceValues, r.OverrideValues)
+		if err != nil {
+			log.Error(err, "Failed to get release manager")
+			return reconcile.Result{}, r.failChart(ctx, log, o, status, c.Name,
+				types.ConditionIrreconcilable, types.ReasonReconcileError, err)
+		}
+		log = log.WithValues("release", manager.ReleaseName())
+
+		if err := manager.Sync(ctx); err != nil {
+			log.Error(err, "Failed to sync release")
+			return reconcile.Result{}, r.failChart(ctx, log, o, status, c.Name,
+				types.ConditionIrreconcilable, types.ReasonReconcileError, err)
+		}
+		deployedRelease, candidateRelease, err := manager.DryRunRelease(ctx)
+		if err != nil {
+			log.Error(err, "Failed to dry run release")
+			return reconcile.Result{}, r.failChart(ctx, log, o, status, c.Name,
+				types.ConditionReleaseFailed, types.ReasonDryRunError, err)
+		}
+
+		deployedManifest := ""
+		if deployedRelease != nil {
+			deployedManifest = deployedRelease.Manifest
+		}
+		chartRelease := chartReleaseFor(status, c.Name)
+		if chartRelease.State == "" {
+			chartRelease.State = types.ChartPending
+		}
+		if deployedManifest == candidateRelease.Manifest {
+			log.Info("Dry run release, no changes")
+			chartRelease.DryRunDiff = ""
+			r.EventRecorder.Eventf(o, "Normal", "DryRun",
+				"Release of chart %s is up to date, no changes would be made", c.Name)
+		} else {
+			log.Info("Dry run release, changes pending")
+			d := diff.GeneratePlain(release.RedactManifest(deployedManifest), release.RedactManifest(candidateRelease.Manifest))
+			chartRelease.DryRunDiff = truncateDiff(d, maxDiffSize)
+			r.EventRecorder.Eventf(o, "Normal", "DryRun", "Chart %s: %s",
+				c.Name, dryRunSummary(deployedManifest, candidateRelease.Manifest))
+		}
+		status.SetChartRelease(chartRelease)
+	}
+	status.RemoveCondition(types.ConditionReleaseFailed)
+	status.RemoveCondition(types.ConditionIrreconcilable)
+
+	err := r.updateResourceStatus(ctx, o, status)
+	return reconcile.Result{RequeueAfter: r.ReconcilePeriod}, err
+}
+
+// rejectChartsTest reports that the test hooks of the releases of o cannot
+// be run in the Tested condition and as an event, and then removes the test
+// annotation from o, like after running the tests of a single release.
+func (r HelmOperatorReconciler) rejectChartsTest(ctx context.Context, log logr.Logger, o *unstructured.Unstructured,
+	status *types.HelmAppStatus) error {
+	err := errors.New("the test annotation is not supported by watches with several charts")
+	log.Error(err, "Failed to test releases")
+	r.EventRecorder.Event(o, "Warning", "Unsupported", err.Error())
+	status.SetCondition(types.HelmAppCondition{
+		Type:    types.ConditionTested,
+		Status:  types.StatusFalse,
+		Reason:  types.ReasonUnsupported,
+		Message: err.Error(),
+	})
+
+	annotations := o.GetAnnotations()
+	delete(annotations, helmTestAnnotation)
+	o.SetAnnotations(annotations)
+	return r.updateResource(ctx, o)
+}
+
+// rollbackChart rolls the release of the named chart back to its last
+// deployed revision after the upgrade failed with upgradeErr.
+func (r HelmOperatorReconciler) rollbackChart(ctx context.Context, log logr.Logger, o *unstructured.Unstructured,
+	status *types.HelmAppStatus, name string, manager release.Manager, upgradeErr error,
+	opts ...release.RollbackOption) error {
+	log.Error(upgradeErr, "Release failed, rolling back")
+	rolledBackRelease, err := manager.RollbackRelease(ctx, opts...)
+	if err != nil {
+		log.Error(err, "Failed to roll back release")
+		return r.failChart(ctx, log, o, status, name, types.ConditionReleaseFailed, types.ReasonRollbackError,
+			fmt.Errorf("failed upgrade (%s) and failed rollback: %w", upgradeErr, err))
+	}
+
+	log.Info("Rolled back release", "revision", rolledBackRelease.Version)
+	r.EventRecorder.Eventf(o, "Warning", "RolledBack",
+		"Upgrade of chart %s failed, rolled back release to revision %d: %s", name, rolledBackRelease.Version, upgradeErr)
+	chartRelease := chartReleaseFor(status, name)
+	chartRelease.DeployedRelease = &types.HelmAppRelease{
+		Name:      rolledBackRelease.Name,
+		Namespace: rolledBackRelease.Namespace,
+		Manifest:  rolledBackRelease.Manifest,
+	}
+	chartRelease.Progress = nil
+	status.SetChartRelease(chartRelease)
+	_ = r.failChart(ctx, log, o, status, name, types.ConditionReleaseFailed, types.ReasonRolledBack, upgradeErr)
+	return upgradeErr
+}
+
+// failChart records that the release of the named chart failed with err in
+// the condition of conditionType, and updates the status of o. It returns
+// err.
+func (r HelmOperatorReconciler) failChart(ctx context.Context, log logr.Logger, o *unstructured.Unstructured,
+	status *types.HelmAppStatus, name string, conditionType types.HelmAppConditionType,
+	reason types.HelmAppConditionReason, err error) error {
+	status.SetCondition(types.HelmAppCondition{
+		Type:    conditionType,
+		Status:  types.StatusTrue,
+		Reason:  reason,
+		Message: fmt.Sprintf("chart %s: %s", name, err),
+	})
+	chartRelease := chartReleaseFor(status, name)
+	chartRelease.State = types.ChartFailed
+	chartRelease.Message = err.Error()
+	status.SetChartRelease(chartRelease)
+	if err := r.updateResourceStatus(ctx, o, status); err != nil {
+		log.Error(err, "Failed to update status after chart release failure")
+	}
+	return err
+}
+
+// uninstallCharts uninstalls the releases of a custom resource composed of
+// several charts in reverse order, and then removes its finalizer. With the
+// uninstall-wait annotation, the resources of each release are deleted
+// before the release of the previous chart is uninstalled.
+func (r HelmOperatorReconciler) uninstallCharts(ctx context.Context, log logr.Logger, o *unstructured.Unstructured,
+	status *types.HelmAppStatus) (reconcile.Result, error) {
+	if !(controllerutil.ContainsFinalizer(o, uninstallFinalizer) ||
+		controllerutil.ContainsFinalizer(o, uninstallFinalizerLegacy)) {
+
+		log.Info("Resource is terminated, skipping reconciliation")
+		return reconcile.Result{}, nil
+	}
+
+	wait := hasAnnotation(helmUninstallWaitAnnotation, o)
+	for i := len(r.Charts) - 1; i >= 0; i-- {
+		c := r.Charts[i]
+		log := log.WithValues("chart", c.Name)
+		manager, err := c.ManagerFactory.NewManager(o, nil, r.OverrideValues)
+		if err != nil {
+			log.Error(err, "Failed to get release manager")
+			return reconcile.Result{}, err
+		}
+		log = log.WithValues("release", manager.ReleaseName())
+
+		timer := metrics.ReleaseOperationTimer(r.GVK.String(), metrics.OperationUninstall)
+		uninstalledRelease, err := manager.UninstallRelease(ctx)
+		timer.ObserveDuration()
+		if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+			log.Error(err, "Failed to uninstall release")
+			return reconcile.Result{}, r.failChart(ctx, log, o, status, c.Name,
+				types.ConditionReleaseFailed, types.ReasonUninstallError, err)
+		}
+		metrics.ReleaseUninstalled(r.GVK.String(), o.GetNamespace(), manager.ReleaseName())
+		if errors.Is(err, driver.ErrReleaseNotFound) {
+			log.V(1).Info("Release not found")
+		} else {
+			log.Info("Uninstalled release")
+			if log.V(0).Enabled() && uninstalledRelease != nil {
+				fmt.Println(diff.Generate(uninstalledRelease.Manifest, ""))
+			}
+		}
+
+		chartRelease := chartReleaseFor(status, c.Name)
+		if wait && chartRelease.DeployedRelease != nil && chartRelease.DeployedRelease.Manifest != "" {
+			isAllResourcesDeleted, err := manager.CleanupRelease(ctx, chartRelease.DeployedRelease.Manifest)
+			if err != nil {
+				log.Error(err, "Failed to cleanup release")
+				return reconcile.Result{}, r.failChart(ctx, log, o, status, c.Name,
+					types.ConditionReleaseFailed, types.ReasonUninstallError, err)
+			}
+			if !isAllResourcesDeleted {
+				log.Info("Waiting until all resources are deleted")
+				status.SetCondition(types.HelmAppCondition{
+					Type:    types.ConditionDeployed,
+					Status:  types.StatusFalse,
+					Reason:  types.ReasonUninstallSuccessful,
+					Message: fmt.Sprintf("Waiting until all resources of chart %s are deleted.", c.Name),
+				})
+				if err := r.updateResourceStatus(ctx, o, status); err != nil {
+					log.Info("Failed to update CR status")
+					return reconcile.Result{}, err
+				}
+				return reconcile.Result{RequeueAfter: r.ReconcilePeriod}, nil
+			}
+		}
+		status.RemoveChartRelease(c.Name)
+	}
+
+	status.RemoveCondition(types.ConditionReleaseFailed)
+	status.SetCondition(types.HelmAppCondition{
+		Type:   types.ConditionDeployed,
+		Status: types.StatusFalse,
+		Reason: types.ReasonUninstallSuccessful,
+	})
+	if err := r.updateResourceStatus(ctx, o, status); err != nil {
+		log.Info("Failed to update CR status")
+		return reconcile.Result{}, err
+	}
+
+	log.Info("Removing finalizer")
+	controllerutil.RemoveFinalizer(o, uninstallFinalizer)
+	controllerutil.RemoveFinalizer(o, uninstallFinalizerLegacy)
+	if err := r.updateResource(ctx, o); err != nil {
+		log.Info("Failed to remove CR uninstall finalizer")
+		return reconcile.Result{}, err
+	}
+	if err := r.waitForDeletion(ctx, o); err != nil {
+		log.Info("Failed waiting for CR deletion")
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, nil
+}
+
+// chartReleaseFor returns the state of the release of the named chart in
+// status.
+func chartReleaseFor(status *types.HelmAppStatus, name string) types.HelmAppChartRelease {
+	for _, chartRelease := range status.ChartReleases {
+		if chartRelease.Name == name {
+			return chartRelease
+		}
+	}
+	return types.HelmAppChartRelease{Name: name}
+}
@@ -0,0 +1,385 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	rpb "helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/internal/types"
+	"github.com/operator-framework/operator-sdk/internal/helm/release"
+)
+
+// fakeChart is an in-memory chart whose releases are managed by
+// fakeManagers. It records the releases installed and uninstalled in calls.
+type fakeChart struct {
+	name      string
+	ready     bool
+	installed bool
+	calls     *[]string
+}
+
+func (f *fakeChart) NewManager(cr *unstructured.Unstructured, _ map[string]interface{}, _ map[string]string) (release.Manager, error) {
+	return &fakeManager{chart: f, releaseName: cr.GetName() + "-" + f.name}, nil
+}
+
+// fakeManager implements the parts of release.Manager used to reconcile
+// custom resources composed of several charts.
+type fakeManager struct {
+	release.Manager
+	chart       *fakeChart
+	releaseName string
+}
+
+func (m *fakeManager) ReleaseName() string        { return m.releaseName }
+func (m *fakeManager) Sync(context.Context) error { return nil }
+func (m *fakeManager) IsInstalled() bool          { return m.chart.installed }
+func (m *fakeManager) IsUpgradeRequired() bool    { return false }
+func (m *fakeManager) release(version int) *rpb.Release {
+	return &rpb.Release{Name: m.releaseName, Version: version}
+}
+
+func (m *fakeManager) InstallRelease(context.Context, ...release.InstallOption) (*rpb.Release, error) {
+	m.chart.installed = true
+	*m.chart.calls = append(*m.chart.calls, "install "+m.chart.name)
+	return m.release(1), nil
+}
+
+func (m *fakeManager) DryRunRelease(context.Context) (*rpb.Release, *rpb.Release, error) {
+	candidate := m.release(1)
+	candidate.Manifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: " + m.chart.name + "\n"
+	if !m.chart.installed {
+		return nil, candidate, nil
+	}
+	return m.release(1), candidate, nil
+}
+
+func (m *fakeManager) ReconcileRelease(context.Context) (*rpb.Release, []release.Drift, error) {
+	return m.release(1), nil, nil
+}
+
+func (m *fakeManager) UninstallRelease(context.Context, ...release.UninstallOption) (*rpb.Release, error) {
+	if !m.chart.installed {
+		return nil, driver.ErrReleaseNotFound
+	}
+	m.chart.installed = false
+	*m.chart.calls = append(*m.chart.calls, "uninstall "+m.chart.name)
+	return m.release(1), nil
+}
+
+func (m *fakeManager) ResourceStatuses(context.Context, string) ([]release.ResourceStatus, error) {
+	return []release.ResourceStatus{{
+		GroupVersionKind: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		Name:             m.chart.name,
+		Ready:            m.chart.ready,
+		Message:          "waiting",
+	}}, nil
+}
+
+// deletingClient deletes objects being deleted once their finalizers are
+// removed, like the API server.
+type deletingClient struct {
+	client.Client
+}
+
+func (c deletingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if err := c.Client.Update(ctx, obj, opts...); err != nil {
+		return err
+	}
+	if obj.GetDeletionTimestamp() != nil && len(obj.GetFinalizers()) == 0 {
+		return c.Client.Delete(ctx, obj)
+	}
+	return nil
+}
+
+func TestReconcileCharts(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Test"}
+	o := &unstructured.Unstructured{}
+	o.SetGroupVersionKind(gvk)
+	o.SetNamespace("ns")
+	o.SetName("test")
+	o.Object["spec"] = map[string]interface{}{}
+
+	var calls []string
+	crds := &fakeChart{name: "crds", ready: true, calls: &calls}
+	core := &fakeChart{name: "core", calls: &calls}
+	addons := &fakeChart{name: "addons", ready: true, calls: &calls}
+
+	c := deletingClient{fakeclient.NewClientBuilder().WithObjects(o).Build()}
+	r := HelmOperatorReconciler{
+		Client:        c,
+		EventRecorder: record.NewFakeRecorder(10),
+		GVK:           gvk,
+		Charts: []ChartRelease{
+			{Name: "crds", ManagerFactory: crds},
+			{Name: "core", ManagerFactory: core, DependsOn: []string{"crds"}},
+			{Name: "addons", ManagerFactory: addons, DependsOn: []string{"core"}},
+		},
+	}
+
+	key := apitypes.NamespacedName{Namespace: "ns", Name: "test"}
+	getStatus := func() *types.HelmAppStatus {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		assert.NoError(t, c.Get(context.TODO(), key, obj))
+		return types.StatusFor(obj)
+	}
+	getCondition := func(status *types.HelmAppStatus, conditionType types.HelmAppConditionType) types.HelmAppCondition {
+		for _, c := range status.Conditions {
+			if c.Type == conditionType {
+				return c
+			}
+		}
+		return types.HelmAppCondition{}
+	}
+	getStates := func(status *types.HelmAppStatus) []types.HelmAppChartState {
+		var states []types.HelmAppChartState
+		for _, cr := range status.ChartReleases {
+			states = append(states, cr.State)
+		}
+		return states
+	}
+
+	// The addons chart waits for the core chart to be ready.
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"install crds", "install core"}, calls)
+	status := getStatus()
+	assert.Equal(t, []types.HelmAppChartState{types.ChartReady, types.ChartDeployed, types.ChartPending}, getStates(status))
+	assert.Equal(t, types.ReasonDependenciesPending, getCondition(status, types.ConditionDeployed).Reason)
+	assert.Equal(t, types.StatusFalse, getCondition(status, types.ConditionReady).Status)
+
+	core.ready = true
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"install crds", "install core", "install addons"}, calls)
+	status = getStatus()
+	assert.Equal(t, []types.HelmAppChartState{types.ChartReady, types.ChartReady, types.ChartReady}, getStates(status))
+	assert.Equal(t, types.StatusTrue, getCondition(status, types.ConditionDeployed).Status)
+	assert.Equal(t, types.StatusTrue, getCondition(status, types.ConditionReady).Status)
+
+	// Releases are uninstalled in reverse order.
+	calls = nil
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	assert.NoError(t, c.Get(context.TODO(), key, obj))
+	now := metav1.Now()
+	obj.SetDeletionTimestamp(&now)
+	assert.NoError(t, c.Update(context.TODO(), obj))
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"uninstall addons", "uninstall core", "uninstall crds"}, calls)
+	assert.Error(t, c.Get(context.TODO(), key, obj))
+}
+
+func TestReconcileChartsWait(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Test"}
+	o := &unstructured.Unstructured{}
+	o.SetGroupVersionKind(gvk)
+	o.SetNamespace("ns")
+	o.SetName("test")
+	o.Object["spec"] = map[string]interface{}{}
+
+	var calls []string
+	core := &fakeChart{name: "core", calls: &calls}
+	c := deletingClient{fakeclient.NewClientBuilder().WithObjects(o).Build()}
+	r := HelmOperatorReconciler{
+		Client:        c,
+		EventRecorder: record.NewFakeRecorder(10),
+		GVK:           gvk,
+		Charts:        []ChartRelease{{Name: "core", ManagerFactory: core}},
+		Wait:          true,
+	}
+
+	key := apitypes.NamespacedName{Namespace: "ns", Name: "test"}
+	getConditions := func() map[types.HelmAppConditionType]types.HelmAppCondition {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		assert.NoError(t, c.Get(context.TODO(), key, obj))
+		conditions := map[types.HelmAppConditionType]types.HelmAppCondition{}
+		for _, c := range types.StatusFor(obj).Conditions {
+			conditions[c.Type] = c
+		}
+		return conditions
+	}
+
+	// The release is not deployed until its resources are ready.
+	result, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+	assert.NoError(t, err)
+	assert.Equal(t, notReadyRequeuePeriod, result.RequeueAfter)
+	conditions := getConditions()
+	assert.Equal(t, types.StatusTrue, conditions[types.ConditionProgressing].Status)
+	assert.Equal(t, types.StatusFalse, conditions[types.ConditionDeployed].Status)
+	assert.Equal(t, types.ReasonWaitingForResources, conditions[types.ConditionDeployed].Reason)
+
+	core.ready = true
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+	assert.NoError(t, err)
+	conditions = getConditions()
+	assert.Equal(t, types.StatusFalse, conditions[types.ConditionProgressing].Status)
+	assert.Equal(t, types.StatusTrue, conditions[types.ConditionDeployed].Status)
+
+	// Resources of a release that was ready only update the Ready condition
+	// once they are not ready anymore, even after the wait timeout.
+	core.ready = false
+	r.Atomic = true
+	r.Timeout = time.Nanosecond
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"install core"}, calls)
+	conditions = getConditions()
+	assert.Equal(t, types.StatusFalse, conditions[types.ConditionProgressing].Status)
+	assert.Equal(t, types.StatusTrue, conditions[types.ConditionDeployed].Status)
+	assert.Equal(t, types.StatusFalse, conditions[types.ConditionReady].Status)
+	assert.NotContains(t, conditions, types.ConditionReleaseFailed)
+}
+
+func TestReconcileChartsWaitTimeout(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Test"}
+	o := &unstructured.Unstructured{}
+	o.SetGroupVersionKind(gvk)
+	o.SetNamespace("ns")
+	o.SetName("test")
+	o.Object["spec"] = map[string]interface{}{}
+
+	var calls []string
+	core := &fakeChart{name: "core", calls: &calls}
+	c := deletingClient{fakeclient.NewClientBuilder().WithObjects(o).Build()}
+	r := HelmOperatorReconciler{
+		Client:        c,
+		EventRecorder: record.NewFakeRecorder(10),
+		GVK:           gvk,
+		Charts:        []ChartRelease{{Name: "core", ManagerFactory: core}},
+		Wait:          true,
+		Atomic:        true,
+		Timeout:       time.Nanosecond,
+	}
+
+	// The release just installed is uninstalled once the wait timed out.
+	key := apitypes.NamespacedName{Namespace: "ns", Name: "test"}
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"install core", "uninstall core"}, calls)
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	assert.NoError(t, c.Get(context.TODO(), key, obj))
+	status := types.StatusFor(obj)
+	if assert.Len(t, status.ChartReleases, 1) {
+		assert.Equal(t, types.ChartFailed, status.ChartReleases[0].State)
+		assert.Nil(t, status.ChartReleases[0].Progress)
+	}
+	failed := findCondition(status, types.ConditionReleaseFailed)
+	if assert.NotNil(t, failed) {
+		assert.Equal(t, types.ReasonWaitTimeout, failed.Reason)
+	}
+}
+
+// newChartsTestReconciler returns a reconciler of the custom resource ns/test
+// with annotations, composed of the core chart.
+func newChartsTestReconciler(core *fakeChart, annotations map[string]string) (HelmOperatorReconciler, *record.FakeRecorder) {
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Test"}
+	o := &unstructured.Unstructured{}
+	o.SetGroupVersionKind(gvk)
+	o.SetNamespace("ns")
+	o.SetName("test")
+	o.SetAnnotations(annotations)
+	o.Object["spec"] = map[string]interface{}{}
+
+	recorder := record.NewFakeRecorder(10)
+	return HelmOperatorReconciler{
+		Client:        deletingClient{fakeclient.NewClientBuilder().WithObjects(o).Build()},
+		EventRecorder: recorder,
+		GVK:           gvk,
+		Charts:        []ChartRelease{{Name: "core", ManagerFactory: core}},
+	}, recorder
+}
+
+func TestReconcileChartsDryRun(t *testing.T) {
+	var calls []string
+	core := &fakeChart{name: "core", ready: true, calls: &calls}
+	r, recorder := newChartsTestReconciler(core, map[string]string{helmDryRunAnnotation: "true"})
+
+	reconciled, _, err := reconcileTestResource(t, r)
+	assert.NoError(t, err)
+	assert.Empty(t, calls, "the releases are not installed")
+	assert.Empty(t, reconciled.GetFinalizers())
+
+	status := types.StatusFor(reconciled)
+	if assert.Len(t, status.ChartReleases, 1) {
+		assert.Equal(t, types.ChartPending, status.ChartReleases[0].State)
+		assert.Contains(t, status.ChartReleases[0].DryRunDiff, "+kind: ConfigMap\n")
+	}
+	if assert.Len(t, recorder.Events, 1) {
+		assert.Equal(t, "Normal DryRun Chart core: Release would change 1 resource(s): ConfigMap core added", <-recorder.Events)
+	}
+}
+
+func TestReconcileChartsTargetRevision(t *testing.T) {
+	var calls []string
+	core := &fakeChart{name: "core", ready: true, installed: true, calls: &calls}
+	r, recorder := newChartsTestReconciler(core, map[string]string{helmTargetRevisionAnnotation: "1"})
+
+	reconciled, _, err := reconcileTestResource(t, r)
+	assert.NoError(t, err)
+	assert.Empty(t, calls)
+	assert.Empty(t, reconciled.GetFinalizers(), "the releases are not reconciled")
+
+	irreconcilable := findCondition(types.StatusFor(reconciled), types.ConditionIrreconcilable)
+	if assert.NotNil(t, irreconcilable) {
+		assert.Equal(t, types.StatusTrue, irreconcilable.Status)
+		assert.Equal(t, types.ReasonUnsupported, irreconcilable.Reason)
+	}
+	if assert.Len(t, recorder.Events, 1) {
+		assert.Contains(t, <-recorder.Events, "Warning Unsupported the target revision annotation is not supported")
+	}
+}
+
+func TestReconcileChartsTest(t *testing.T) {
+	var calls []string
+	core := &fakeChart{name: "core", ready: true, calls: &calls}
+	r, recorder := newChartsTestReconciler(core, map[string]string{helmTestAnnotation: "true"})
+
+	reconciled, _, err := reconcileTestResource(t, r)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"install core"}, calls, "the releases are still reconciled")
+	assert.NotContains(t, reconciled.GetAnnotations(), helmTestAnnotation)
+
+	status := types.StatusFor(reconciled)
+	tested := findCondition(status, types.ConditionTested)
+	if assert.NotNil(t, tested) {
+		assert.Equal(t, types.StatusFalse, tested.Status)
+		assert.Equal(t, types.ReasonUnsupported, tested.Reason)
+	}
+	if deployed := findCondition(status, types.ConditionDeployed); assert.NotNil(t, deployed) {
+		assert.Equal(t, types.StatusTrue, deployed.Status)
+	}
+	if assert.NotEmpty(t, recorder.Events) {
+		assert.Contains(t, <-recorder.Events, "Warning Unsupported the test annotation is not supported")
+	}
+}
@@ -15,6 +15,7 @@
 package controller
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
@@ -22,14 +23,20 @@ import (
 
 	rpb "helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/releaseutil"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	crthandler "sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	ctrlpredicate "sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 	"sigs.k8s.io/yaml"
 
@@ -51,19 +58,48 @@ type WatchOptions struct {
 	WatchDependentResources bool
 	OverrideValues          map[string]string
 	MaxConcurrentReconciles int
+	RollbackOnFailure       bool
+	ValuesFrom              []release.ValuesSource
+	Selector                metav1.LabelSelector
+	Blacklist               []schema.GroupVersionKind
+	// Charts are the charts composing each custom resource. When set,
+	// ManagerFactory is not used.
+	Charts []ChartRelease
+	// TargetRevisionField is a top-level field of the spec of custom
+	// resources which, when set, pins their release to a revision.
+	TargetRevisionField string
+	// Wait, Timeout and Atomic configure how the reconciler waits for the
+	// resources of releases to be ready.
+	Wait    bool
+	Timeout time.Duration
+	Atomic  bool
 }
 
 // Add creates a new helm operator controller and adds it to the manager
 func Add(mgr manager.Manager, options WatchOptions) error {
 	controllerName := fmt.Sprintf("%v-controller", strings.ToLower(options.GVK.Kind))
 
+	selector, err := metav1.LabelSelectorAsSelector(&options.Selector)
+	if err != nil {
+		return fmt.Errorf("invalid selector: %w", err)
+	}
+
 	r := &HelmOperatorReconciler{
-		Client:          mgr.GetClient(),
-		EventRecorder:   mgr.GetEventRecorderFor(controllerName),
-		GVK:             options.GVK,
-		ManagerFactory:  options.ManagerFactory,
-		ReconcilePeriod: options.ReconcilePeriod,
-		OverrideValues:  options.OverrideValues,
+		Client:              mgr.GetClient(),
+		EventRecorder:       mgr.GetEventRecorderFor(controllerName),
+		GVK:                 options.GVK,
+		ManagerFactory:      options.ManagerFactory,
+		ReconcilePeriod:     options.ReconcilePeriod,
+		OverrideValues:      options.OverrideValues,
+		RollbackOnFailure:   options.RollbackOnFailure,
+		ValuesFrom:          options.ValuesFrom,
+		Charts:              options.Charts,
+		Selector:            selector,
+		TargetRevisionField: options.TargetRevisionField,
+		Wait:                options.Wait,
+		Timeout:             options.Timeout,
+		Atomic:              options.Atomic,
+		tests:               newTestRuns(),
 	}
 
 	// Register the GVK with the schema
@@ -78,29 +114,45 @@ func Add(mgr manager.Manager, options WatchOptions) error {
 		return err
 	}
 
+	selectorPredicate, err := ctrlpredicate.LabelSelectorPredicate(options.Selector)
+	if err != nil {
+		return fmt.Errorf("invalid selector: %w", err)
+	}
+
 	o := &unstructured.Unstructured{}
 	o.SetGroupVersionKind(options.GVK)
-	if err := c.Watch(&source.Kind{Type: o}, &libhandler.InstrumentedEnqueueRequestForObject{}); err != nil {
+	if err := c.Watch(&source.Kind{Type: o}, &libhandler.InstrumentedEnqueueRequestForObject{}, selectorPredicate); err != nil {
 		return err
 	}
 
 	if options.WatchDependentResources {
-		watchDependentResources(mgr, r, c)
+		watchDependentResources(mgr, r, c, options.Blacklist)
+	}
+
+	if err := watchValuesSources(mgr, c, options.GVK, selector, options.ValuesFrom); err != nil {
+		return err
 	}
 
 	log.Info("Watching resource", "apiVersion", options.GVK.GroupVersion(), "kind",
-		options.GVK.Kind, "namespace", options.Namespace, "reconcilePeriod", options.ReconcilePeriod.String())
+		options.GVK.Kind, "namespace", options.Namespace, "reconcilePeriod", options.ReconcilePeriod.String(),
+		"maxConcurrentReconciles", options.MaxConcurrentReconciles)
 	return nil
 }
 
 // watchDependentResources adds a release hook function to the HelmOperatorReconciler
-// that adds watches for resources in released Helm charts.
-func watchDependentResources(mgr manager.Manager, r *HelmOperatorReconciler, c controller.Controller) {
+// that adds watches for resources in released Helm charts, except for resources
+// whose GVK is in blacklist.
+func watchDependentResources(mgr manager.Manager, r *HelmOperatorReconciler, c controller.Controller,
+	blacklist []schema.GroupVersionKind) {
 	owner := &unstructured.Unstructured{}
 	owner.SetGroupVersionKind(r.GVK)
 
 	var m sync.RWMutex
 	watches := map[schema.GroupVersionKind]struct{}{}
+	// Blacklisted GVKs are never watched, so treat them as already watched.
+	for _, gvk := range blacklist {
+		watches[gvk] = struct{}{}
+	}
 	releaseHook := func(release *rpb.Release) error {
 		resources := releaseutil.SplitManifests(release.Manifest)
 		for _, resource := range resources {
@@ -177,3 +229,64 @@ func watchDependentResources(mgr manager.Manager, r *HelmOperatorReconciler, c c
 	}
 	r.releaseHook = releaseHook
 }
+
+// watchValuesSources watches the ConfigMaps and Secrets referenced by the
+// values sources, so that the custom resources matching selector are
+// reconciled when the values in their namespace change.
+func watchValuesSources(mgr manager.Manager, c controller.Controller, gvk schema.GroupVersionKind,
+	selector labels.Selector, sources []release.ValuesSource) error {
+	names := map[string]map[string]struct{}{}
+	for _, s := range sources {
+		if names[s.Kind] == nil {
+			names[s.Kind] = map[string]struct{}{}
+		}
+		names[s.Kind][s.Name] = struct{}{}
+	}
+
+	for kind, kindNames := range names {
+		var obj client.Object
+		switch kind {
+		case release.ValuesSourceConfigMap:
+			obj = &corev1.ConfigMap{}
+		case release.ValuesSourceSecret:
+			obj = &corev1.Secret{}
+		default:
+			return fmt.Errorf("unsupported values source kind %q", kind)
+		}
+		err := c.Watch(&source.Kind{Type: obj}, crthandler.EnqueueRequestsFromMapFunc(enqueueOwnersOf(mgr.GetClient(), gvk, selector, kindNames)))
+		if err != nil {
+			return err
+		}
+		log.Info("Watching values sources", "ownerApiVersion", gvk.GroupVersion(),
+			"ownerKind", gvk.Kind, "kind", kind)
+	}
+	return nil
+}
+
+// enqueueOwnersOf returns a function that maps a values source with one of
+// names to all custom resources of gvk in its namespace that match selector.
+func enqueueOwnersOf(c client.Client, gvk schema.GroupVersionKind, selector labels.Selector,
+	names map[string]struct{}) crthandler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		if _, ok := names[obj.GetName()]; !ok {
+			return nil
+		}
+		owners := &unstructured.UnstructuredList{}
+		owners.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		err := c.List(context.TODO(), owners, client.InNamespace(obj.GetNamespace()),
+			client.MatchingLabelsSelector{Selector: selector})
+		if err != nil {
+			log.Error(err, "Failed to list resources for values source", "apiVersion", gvk.GroupVersion(),
+				"kind", gvk.Kind, "namespace", obj.GetNamespace(), "name", obj.GetName())
+			return nil
+		}
+		requests := make([]reconcile.Request, 0, len(owners.Items))
+		for _, owner := range owners.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: apitypes.NamespacedName{
+				Namespace: owner.GetNamespace(),
+				Name:      owner.GetName(),
+			}})
+		}
+		return requests
+	}
+}
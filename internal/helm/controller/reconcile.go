@@ -18,23 +18,32 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/go-logr/logr"
 	rpb "helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
 	"helm.sh/helm/v3/pkg/storage/driver"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	apitypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/yaml"
 
 	"github.com/operator-framework/operator-sdk/internal/helm/internal/diff"
 	"github.com/operator-framework/operator-sdk/internal/helm/internal/types"
+	"github.com/operator-framework/operator-sdk/internal/helm/metrics"
 	"github.com/operator-framework/operator-sdk/internal/helm/release"
 )
 
@@ -46,13 +55,31 @@ type ReleaseHookFunc func(*rpb.Release) error
 
 // HelmOperatorReconciler reconciles custom resources as Helm releases.
 type HelmOperatorReconciler struct {
-	Client          client.Client
-	EventRecorder   record.EventRecorder
-	GVK             schema.GroupVersionKind
-	ManagerFactory  release.ManagerFactory
-	ReconcilePeriod time.Duration
-	OverrideValues  map[string]string
-	releaseHook     ReleaseHookFunc
+	Client            client.Client
+	EventRecorder     record.EventRecorder
+	GVK               schema.GroupVersionKind
+	ManagerFactory    release.ManagerFactory
+	ReconcilePeriod   time.Duration
+	OverrideValues    map[string]string
+	RollbackOnFailure bool
+	ValuesFrom        []release.ValuesSource
+	Charts            []ChartRelease
+	// Selector selects the custom resources to reconcile. Other custom
+	// resources are ignored, unless they are being deleted. A nil Selector
+	// selects all custom resources.
+	Selector labels.Selector
+	// TargetRevisionField is a top-level field of the spec of custom
+	// resources which, when set, pins their release to a revision.
+	TargetRevisionField string
+	// Wait reports releases as deployed only once their resources are ready.
+	// Releases whose resources are not ready within Timeout fail, and with
+	// Atomic they are rolled back, or uninstalled if they were just installed.
+	Wait        bool
+	Timeout     time.Duration
+	Atomic      bool
+	releaseHook ReleaseHookFunc
+	// tests tracks the test runs started by the test annotation.
+	tests *testRuns
 }
 
 const (
@@ -61,8 +88,27 @@ const (
 	// Deprecated: use uninstallFinalizer. This will be removed in operator-sdk v2.0.0.
 	uninstallFinalizerLegacy = "uninstall-helm-release"
 
-	helmUpgradeForceAnnotation  = "helm.sdk.operatorframework.io/upgrade-force"
-	helmUninstallWaitAnnotation = "helm.sdk.operatorframework.io/uninstall-wait"
+	helmUpgradeForceAnnotation   = "helm.sdk.operatorframework.io/upgrade-force"
+	helmUninstallWaitAnnotation  = "helm.sdk.operatorframework.io/uninstall-wait"
+	helmDryRunAnnotation         = "helm.sdk.operatorframework.io/dry-run"
+	helmTestAnnotation           = "helm.sdk.operatorframework.io/test"
+	helmPauseAnnotation          = "helm.sdk.operatorframework.io/pause"
+	helmTargetRevisionAnnotation = "helm.sdk.operatorframework.io/target-revision"
+
+	// notReadyRequeuePeriod is the maximum period between reconciliations of
+	// custom resources whose release resources are not ready.
+	notReadyRequeuePeriod = 10 * time.Second
+
+	// defaultWaitTimeout is the default time to wait for the resources of a
+	// release to be ready, like Helm's.
+	defaultWaitTimeout = 5 * time.Minute
+
+	// maxDryRunDiffSize is the maximum size in bytes of the dry run diff
+	// stored in the CR status, which is truncated beyond it.
+	maxDryRunDiffSize = 16 * 1024
+	// maxDryRunEventResources is the maximum number of changed resources
+	// listed in dry run events.
+	maxDryRunEventResources = 10
 )
 
 // Reconcile reconciles the requested resource by installing, updating, or
@@ -92,9 +138,56 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 		return reconcile.Result{}, err
 	}
 
-	manager, err := r.ManagerFactory.NewManager(o, r.OverrideValues)
+	// Resources excluded by the selector of the watch are not reconciled, but
+	// can still be uninstalled.
+	if o.GetDeletionTimestamp() == nil && r.Selector != nil && !r.Selector.Matches(labels.Set(o.GetLabels())) {
+		log.V(1).Info("Resource does not match the selector, skipping reconciliation")
+		return reconcile.Result{}, nil
+	}
+
+	// Paused resources are not reconciled, but can still be uninstalled.
+	if o.GetDeletionTimestamp() == nil && hasAnnotation(helmPauseAnnotation, o) {
+		return r.pauseReconciliation(ctx, log, o)
+	}
+
+	// Values sources are not needed to uninstall a release, and may already
+	// have been deleted along with the resource's namespace.
+	var sourceValues map[string]interface{}
+	if o.GetDeletionTimestamp() == nil {
+		sourceValues, err = release.LoadValuesFrom(ctx, r.Client, o.GetNamespace(), r.ValuesFrom)
+		if err != nil {
+			log.Error(err, "Failed to load values from sources")
+			status := types.StatusFor(o)
+			status.SetCondition(types.HelmAppCondition{
+				Type:    types.ConditionReleaseFailed,
+				Status:  types.StatusTrue,
+				Reason:  types.ReasonValuesFromError,
+				Message: err.Error(),
+			})
+			if err := r.updateResourceStatus(ctx, o, status); err != nil {
+				log.Error(err, "Failed to update status after values sources failure")
+			}
+			return reconcile.Result{}, err
+		}
+	}
+
+	if len(r.Charts) > 0 {
+		return r.reconcileCharts(ctx, log, o, sourceValues)
+	}
+
+	manager, err := r.ManagerFactory.NewManager(o, sourceValues, r.OverrideValues)
 	if err != nil {
 		log.Error(err, "Failed to get release manager")
+		status := types.StatusFor(o)
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionIrreconcilable,
+			Status:  types.StatusTrue,
+			Reason:  types.ReasonReconcileError,
+			Message: err.Error(),
+		})
+		if err := r.updateResourceStatus(ctx, o, status); err != nil {
+			log.Error(err, "Failed to update status after release manager failure")
+		}
 		return reconcile.Result{}, err
 	}
 
@@ -109,7 +202,10 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 			return reconcile.Result{}, nil
 		}
 
+		timer := metrics.ReleaseOperationTimer(r.GVK.String(), metrics.OperationUninstall)
+		r.tests.forget(request.NamespacedName)
 		uninstalledRelease, err := manager.UninstallRelease(ctx)
+		timer.ObserveDuration()
 		if err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
 			log.Error(err, "Failed to uninstall release")
 			status.SetCondition(types.HelmAppCondition{
@@ -124,6 +220,7 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 			return reconcile.Result{}, err
 		}
 		status.RemoveCondition(types.ConditionReleaseFailed)
+		metrics.ReleaseUninstalled(r.GVK.String(), o.GetNamespace(), manager.ReleaseName())
 
 		wait := hasAnnotation(helmUninstallWaitAnnotation, o)
 		if errors.Is(err, driver.ErrReleaseNotFound) {
@@ -200,9 +297,11 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 		Type:   types.ConditionInitialized,
 		Status: types.StatusTrue,
 	})
+	status.RemoveCondition(types.ConditionPaused)
 
 	if err := manager.Sync(ctx); err != nil {
 		log.Error(err, "Failed to sync release")
+		r.recordRelease(o, manager, nil, err)
 		status.SetCondition(types.HelmAppCondition{
 			Type:    types.ConditionIrreconcilable,
 			Status:  types.StatusTrue,
@@ -216,12 +315,47 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 	}
 	status.RemoveCondition(types.ConditionIrreconcilable)
 
+	if hasAnnotation(helmDryRunAnnotation, o) {
+		return r.dryRunRelease(ctx, log, o, manager, status)
+	}
+	status.DryRunDiff = ""
+
+	targetRevision, err := r.targetRevision(o)
+	if err != nil {
+		log.Error(err, "Failed to get target revision")
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionIrreconcilable,
+			Status:  types.StatusTrue,
+			Reason:  types.ReasonReconcileError,
+			Message: err.Error(),
+		})
+		if err := r.updateResourceStatus(ctx, o, status); err != nil {
+			log.Error(err, "Failed to update status after target revision failure")
+		}
+		return reconcile.Result{}, err
+	}
+	// Pinned releases are rolled back to their target revision instead of
+	// being upgraded, until the target revision is unset.
+	pinned := targetRevision > 0 && manager.IsInstalled()
+	if pinned {
+		rolledBack, err := r.pinRelease(ctx, log, o, manager, status, targetRevision)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if rolledBack {
+			return reconcile.Result{RequeueAfter: r.requeuePeriod(status)}, nil
+		}
+	}
+
 	if !manager.IsInstalled() {
 		for k, v := range r.OverrideValues {
 			r.EventRecorder.Eventf(o, "Warning", "OverrideValuesInUse",
 				"Chart value %q overridden to %q by operator's watches.yaml", k, v)
 		}
+		timer := metrics.ReleaseOperationTimer(r.GVK.String(), metrics.OperationInstall)
 		installedRelease, err := manager.InstallRelease(ctx)
+		timer.ObserveDuration()
+		r.recordRelease(o, manager, installedRelease, err)
 		if err != nil {
 			log.Error(err, "Release failed")
 			status.SetCondition(types.HelmAppCondition{
@@ -255,7 +389,7 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 		if log.V(0).Enabled() {
 			fmt.Println(diff.Generate("", installedRelease.Manifest))
 		}
-		log.V(1).Info("Config values", "values", installedRelease.Config)
+		r.logConfigValues(log, installedRelease)
 		message := ""
 		if installedRelease.Info != nil {
 			message = installedRelease.Info.Notes
@@ -267,11 +401,18 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 			Message: message,
 		})
 		status.DeployedRelease = &types.HelmAppRelease{
-			Name:     installedRelease.Name,
-			Manifest: installedRelease.Manifest,
+			Name:      installedRelease.Name,
+			Namespace: installedRelease.Namespace,
+			Manifest:  installedRelease.Manifest,
+		}
+		r.setResourceStatuses(ctx, log, manager, status)
+		r.setHistory(ctx, log, manager, status)
+		if r.Wait {
+			status.Progress = newProgress(installedRelease)
+			return r.waitForRelease(ctx, log, o, manager, status)
 		}
 		err = r.updateResourceStatus(ctx, o, status)
-		return reconcile.Result{RequeueAfter: r.ReconcilePeriod}, err
+		return reconcile.Result{RequeueAfter: r.requeuePeriod(status)}, err
 	}
 
 	if !(controllerutil.ContainsFinalizer(o, uninstallFinalizer) ||
@@ -285,13 +426,19 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 		}
 	}
 
-	if manager.IsUpgradeRequired() {
+	if manager.IsUpgradeRequired() && !pinned {
 		for k, v := range r.OverrideValues {
 			r.EventRecorder.Eventf(o, "Warning", "OverrideValuesInUse",
 				"Chart value %q overridden to %q by operator's watches.yaml", k, v)
 		}
 		force := hasAnnotation(helmUpgradeForceAnnotation, o)
+		timer := metrics.ReleaseOperationTimer(r.GVK.String(), metrics.OperationUpgrade)
 		previousRelease, upgradedRelease, err := manager.UpgradeRelease(ctx, release.ForceUpgrade(force))
+		timer.ObserveDuration()
+		r.recordRelease(o, manager, upgradedRelease, err)
+		if err != nil && (r.RollbackOnFailure || r.Atomic) {
+			return r.rollbackRelease(ctx, log, o, manager, status, err)
+		}
 		if err != nil {
 			log.Error(err, "Release failed")
 			status.SetCondition(types.HelmAppCondition{
@@ -318,7 +465,7 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 		if log.V(0).Enabled() {
 			fmt.Println(diff.Generate(previousRelease.Manifest, upgradedRelease.Manifest))
 		}
-		log.V(1).Info("Config values", "values", upgradedRelease.Config)
+		r.logConfigValues(log, upgradedRelease)
 		message := ""
 		if upgradedRelease.Info != nil {
 			message = upgradedRelease.Info.Notes
@@ -330,11 +477,18 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 			Message: message,
 		})
 		status.DeployedRelease = &types.HelmAppRelease{
-			Name:     upgradedRelease.Name,
-			Manifest: upgradedRelease.Manifest,
+			Name:      upgradedRelease.Name,
+			Namespace: upgradedRelease.Namespace,
+			Manifest:  upgradedRelease.Manifest,
+		}
+		r.setResourceStatuses(ctx, log, manager, status)
+		r.setHistory(ctx, log, manager, status)
+		if r.Wait {
+			status.Progress = newProgress(upgradedRelease)
+			return r.waitForRelease(ctx, log, o, manager, status)
 		}
 		err = r.updateResourceStatus(ctx, o, status)
-		return reconcile.Result{RequeueAfter: r.ReconcilePeriod}, err
+		return reconcile.Result{RequeueAfter: r.requeuePeriod(status)}, err
 	}
 
 	// If a change is made to the CR spec that causes a release failure, a
@@ -345,7 +499,17 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 	// no longer being attempted.
 	status.RemoveCondition(types.ConditionReleaseFailed)
 
-	expectedRelease, err := manager.ReconcileRelease(ctx)
+	timer := metrics.ReleaseOperationTimer(r.GVK.String(), metrics.OperationReconcile)
+	expectedRelease, drifts, err := manager.ReconcileRelease(ctx)
+	timer.ObserveDuration()
+	var conflictErr *release.ConflictError
+	if errors.As(err, &conflictErr) {
+		r.recordConflicts(log, o, status, conflictErr)
+		err = nil
+	} else {
+		status.RemoveCondition(types.ConditionConflicted)
+	}
+	r.recordRelease(o, manager, expectedRelease, err)
 	if err != nil {
 		log.Error(err, "Failed to reconcile release")
 		status.SetCondition(types.HelmAppCondition{
@@ -360,6 +524,7 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 		return reconcile.Result{}, err
 	}
 	status.RemoveCondition(types.ConditionIrreconcilable)
+	r.recordDrifts(log, o, status, drifts)
 
 	if r.releaseHook != nil {
 		if err := r.releaseHook(expectedRelease); err != nil {
@@ -368,6 +533,15 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 		}
 	}
 
+	if hasAnnotation(helmTestAnnotation, o) {
+		if err := r.testRelease(ctx, log, o, manager, status); err != nil {
+			log.Info("Failed to remove CR test annotation")
+			return reconcile.Result{}, err
+		}
+	} else {
+		r.tests.forget(request.NamespacedName)
+	}
+
 	log.Info("Reconciled release")
 	reason := types.ReasonUpgradeSuccessful
 	if expectedRelease.Version == 1 {
@@ -377,6 +551,10 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 	if expectedRelease.Info != nil {
 		message = expectedRelease.Info.Notes
 	}
+	if pinned {
+		reason = types.ReasonRevisionPinned
+		message = fmt.Sprintf("Release is pinned to revision %d", targetRevision)
+	}
 	status.SetCondition(types.HelmAppCondition{
 		Type:    types.ConditionDeployed,
 		Status:  types.StatusTrue,
@@ -384,13 +562,709 @@ func (r HelmOperatorReconciler) Reconcile(ctx context.Context, request reconcile
 		Message: message,
 	})
 	status.DeployedRelease = &types.HelmAppRelease{
-		Name:     expectedRelease.Name,
-		Manifest: expectedRelease.Manifest,
+		Name:      expectedRelease.Name,
+		Namespace: expectedRelease.Namespace,
+		Manifest:  expectedRelease.Manifest,
+	}
+	r.setResourceStatuses(ctx, log, manager, status)
+	r.setHistory(ctx, log, manager, status)
+	if r.Wait && status.Progress != nil && status.Progress.Revision == expectedRelease.Version {
+		return r.waitForRelease(ctx, log, o, manager, status)
+	}
+	// Resources of a revision that was already ready, or that the operator
+	// did not install or upgrade, only update the Ready condition.
+	if status.Progress != nil {
+		status.Progress = nil
+		status.RemoveCondition(types.ConditionProgressing)
 	}
+	err = r.updateResourceStatus(ctx, o, status)
+	return reconcile.Result{RequeueAfter: r.requeuePeriod(status)}, err
+}
+
+// targetRevision returns the revision to which the release of o is pinned by
+// the target revision spec field or, if it is unset, annotation. It is 0 if
+// the release is not pinned.
+func (r HelmOperatorReconciler) targetRevision(o *unstructured.Unstructured) (int, error) {
+	if r.TargetRevisionField != "" {
+		revision, found, err := unstructured.NestedInt64(o.Object, "spec", r.TargetRevisionField)
+		if err != nil {
+			return 0, fmt.Errorf("invalid target revision: %w", err)
+		}
+		if found && revision < 1 {
+			return 0, fmt.Errorf("invalid target revision %d: must be positive", revision)
+		}
+		if found {
+			return int(revision), nil
+		}
+	}
+	if value := o.GetAnnotations()[helmTargetRevisionAnnotation]; value != "" {
+		revision, err := strconv.Atoi(value)
+		if err != nil || revision < 1 {
+			return 0, fmt.Errorf("invalid target revision %q: must be a positive integer", value)
+		}
+		return revision, nil
+	}
+	return 0, nil
+}
+
+// pinRelease rolls the release of o back to targetRevision, unless it is
+// already deployed at that revision. It reports whether the release was
+// rolled back.
+func (r HelmOperatorReconciler) pinRelease(ctx context.Context, log logr.Logger, o *unstructured.Unstructured,
+	manager release.Manager, status *types.HelmAppStatus, targetRevision int) (bool, error) {
+	history, err := manager.ReleaseHistory(ctx)
+	if err == nil && isPinned(history, targetRevision) {
+		return false, nil
+	}
+
+	var rolledBackRelease *rpb.Release
+	if err == nil {
+		log.Info("Rolling back release to target revision", "revision", targetRevision)
+		rolledBackRelease, err = manager.RollbackRelease(ctx, release.RollbackVersion(targetRevision))
+	}
+	if err != nil {
+		log.Error(err, "Failed to roll back release to target revision")
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionReleaseFailed,
+			Status:  types.StatusTrue,
+			Reason:  types.ReasonRollbackError,
+			Message: err.Error(),
+		})
+		if err := r.updateResourceStatus(ctx, o, status); err != nil {
+			log.Error(err, "Failed to update status after rollback release failure")
+		}
+		return false, err
+	}
+	r.recordRelease(o, manager, rolledBackRelease, nil)
+
+	log.Info("Rolled back release", "revision", rolledBackRelease.Version)
+	r.EventRecorder.Eventf(o, "Normal", "RolledBack", "Rolled back release to target revision %d", targetRevision)
+	status.RemoveCondition(types.ConditionReleaseFailed)
+	status.SetCondition(types.HelmAppCondition{
+		Type:    types.ConditionDeployed,
+		Status:  types.StatusTrue,
+		Reason:  types.ReasonRevisionPinned,
+		Message: fmt.Sprintf("Release is pinned to revision %d", targetRevision),
+	})
+	status.DeployedRelease = &types.HelmAppRelease{
+		Name:      rolledBackRelease.Name,
+		Namespace: rolledBackRelease.Namespace,
+		Manifest:  rolledBackRelease.Manifest,
+	}
+	r.setResourceStatuses(ctx, log, manager, status)
+	r.setHistory(ctx, log, manager, status)
+	return true, r.updateResourceStatus(ctx, o, status)
+}
+
+// isPinned reports whether the deployed revision in history is targetRevision
+// or a rollback to it, which Helm describes as "Rollback to <revision>".
+func isPinned(history []*rpb.Release, targetRevision int) bool {
+	for i := len(history) - 1; i >= 0; i-- {
+		rel := history[i]
+		if rel.Info == nil || rel.Info.Status != rpb.StatusDeployed {
+			continue
+		}
+		return rel.Version == targetRevision ||
+			rel.Info.Description == fmt.Sprintf("Rollback to %d", targetRevision)
+	}
+	return false
+}
+
+// setHistory records the revisions of the release kept in the release
+// storage in status.
+func (r HelmOperatorReconciler) setHistory(ctx context.Context, log logr.Logger, manager release.Manager,
+	status *types.HelmAppStatus) {
+	history, err := manager.ReleaseHistory(ctx)
+	if err != nil {
+		log.Error(err, "Failed to get release history")
+		return
+	}
+	status.History = make([]types.HelmAppRevision, 0, len(history))
+	for _, rel := range history {
+		revision := types.HelmAppRevision{Revision: rel.Version}
+		if rel.Chart != nil && rel.Chart.Metadata != nil {
+			revision.ChartVersion = rel.Chart.Metadata.Version
+		}
+		if rel.Info != nil {
+			revision.Status = rel.Info.Status.String()
+			revision.Description = rel.Info.Description
+			revision.Updated = metav1.NewTime(rel.Info.LastDeployed.Time)
+		}
+		status.History = append(status.History, revision)
+	}
+}
+
+// recordRelease records the state of the release of o managed by manager in
+// the release metrics, after a release operation returned rel and err.
+func (r HelmOperatorReconciler) recordRelease(o *unstructured.Unstructured, manager release.Manager, rel *rpb.Release, err error) {
+	if err != nil || rel == nil {
+		metrics.ReleaseState(r.GVK.String(), o.GetNamespace(), manager.ReleaseName(), metrics.ReleaseFailed, 0)
+		return
+	}
+	metrics.ReleaseState(r.GVK.String(), o.GetNamespace(), manager.ReleaseName(), metrics.ReleaseDeployed, rel.Version)
+}
+
+// recordConflicts reports the fields of release resources that server-side
+// apply did not overwrite because they are managed by other field managers.
+func (r HelmOperatorReconciler) recordConflicts(log logr.Logger, o *unstructured.Unstructured,
+	status *types.HelmAppStatus, conflictErr *release.ConflictError) {
+	for _, c := range conflictErr.Conflicts {
+		log.Info("Release resource has conflicting fields", "apiVersion", c.GroupVersionKind.GroupVersion(),
+			"kind", c.GroupVersionKind.Kind, "resourceNamespace", c.Namespace, "resourceName", c.Name,
+			"conflict", c.Message)
+		r.EventRecorder.Eventf(o, "Warning", "FieldConflict", "%s %s/%s has conflicting fields: %s",
+			c.GroupVersionKind.Kind, c.Namespace, c.Name, c.Message)
+	}
+	status.SetCondition(types.HelmAppCondition{
+		Type:    types.ConditionConflicted,
+		Status:  types.StatusTrue,
+		Reason:  types.ReasonFieldConflict,
+		Message: conflictErr.Error(),
+	})
+}
+
+// setResourceStatuses records the readiness of the resources in the deployed
+// release, and sets the Ready condition of the custom resource accordingly.
+func (r HelmOperatorReconciler) setResourceStatuses(ctx context.Context, log logr.Logger, manager release.Manager,
+	status *types.HelmAppStatus) {
+	resources, notReady, err := resourceStatuses(ctx, manager, status.DeployedRelease.Manifest)
+	if err != nil {
+		log.Error(err, "Failed to get release resource statuses")
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionReady,
+			Status:  types.StatusUnknown,
+			Reason:  types.ReasonReadinessError,
+			Message: err.Error(),
+		})
+		return
+	}
+	status.DeployedRelease.Resources = resources
+
+	if len(notReady) > 0 {
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionReady,
+			Status:  types.StatusFalse,
+			Reason:  types.ReasonResourcesNotReady,
+			Message: strings.Join(notReady, "; "),
+		})
+		return
+	}
+	status.SetCondition(types.HelmAppCondition{
+		Type:   types.ConditionReady,
+		Status: types.StatusTrue,
+		Reason: types.ReasonResourcesReady,
+	})
+}
+
+// resourceStatuses returns the readiness of the resources in manifest, and
+// a description of each resource that is not ready.
+func resourceStatuses(ctx context.Context, manager release.Manager, manifest string) ([]types.HelmAppResource, []string, error) {
+	statuses, err := manager.ResourceStatuses(ctx, manifest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var notReady []string
+	resources := make([]types.HelmAppResource, 0, len(statuses))
+	for _, rs := range statuses {
+		apiVersion, kind := rs.GroupVersionKind.ToAPIVersionAndKind()
+		resources = append(resources, types.HelmAppResource{
+			APIVersion: apiVersion,
+			Kind:       kind,
+			Namespace:  rs.Namespace,
+			Name:       rs.Name,
+			Ready:      rs.Ready,
+			Message:    rs.Message,
+		})
+		if !rs.Ready {
+			notReady = append(notReady, fmt.Sprintf("%s %s: %s", kind, rs.Name, rs.Message))
+		}
+	}
+	return resources, notReady, nil
+}
+
+// logConfigValues logs the values of rel at debug verbosity. Values merged
+// from Secret values sources must not be logged, so the values are not logged
+// when any values source is a Secret.
+func (r HelmOperatorReconciler) logConfigValues(log logr.Logger, rel *rpb.Release) {
+	for _, s := range r.ValuesFrom {
+		if s.Kind == release.ValuesSourceSecret {
+			log.V(1).Info("Config values include values from Secrets, not logging them")
+			return
+		}
+	}
+	log.V(1).Info("Config values", "values", rel.Config)
+}
+
+// requeuePeriod returns the period after which the custom resource is
+// reconciled again. Custom resources whose resources are not ready yet are
+// reconciled sooner, so that their Ready condition is updated promptly, as
+// are custom resources whose release tests are running, so that their results
+// are collected promptly.
+func (r HelmOperatorReconciler) requeuePeriod(status *types.HelmAppStatus) time.Duration {
+	for _, c := range status.Conditions {
+		pending := c.Type == types.ConditionReady && c.Status != types.StatusTrue ||
+			c.Type == types.ConditionTested && c.Reason == types.ReasonTestsRunning
+		if pending && (r.ReconcilePeriod == 0 || r.ReconcilePeriod > notReadyRequeuePeriod) {
+			return notReadyRequeuePeriod
+		}
+	}
+	return r.ReconcilePeriod
+}
+
+// waitForRelease reports the revision of status.Progress as deployed once its
+// resources are ready. Until then, the Progressing condition is set and the
+// custom resource is requeued, rather than blocking a worker until the
+// resources are ready. The release fails if its resources are not ready
+// within the wait timeout.
+func (r HelmOperatorReconciler) waitForRelease(ctx context.Context, log logr.Logger, o *unstructured.Unstructured,
+	manager release.Manager, status *types.HelmAppStatus) (reconcile.Result, error) {
+	revision := status.Progress.Revision
+	ready, message := resourcesReady(status)
+	if ready {
+		status.Progress = nil
+		status.SetCondition(types.HelmAppCondition{
+			Type:   types.ConditionProgressing,
+			Status: types.StatusFalse,
+			Reason: types.ReasonResourcesReady,
+		})
+		err := r.updateResourceStatus(ctx, o, status)
+		return reconcile.Result{RequeueAfter: r.requeuePeriod(status)}, err
+	}
+
+	if r.waitRemaining(status.Progress) > 0 {
+		log.Info("Waiting for release resources to be ready", "revision", revision)
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionProgressing,
+			Status:  types.StatusTrue,
+			Reason:  types.ReasonWaitingForResources,
+			Message: message,
+		})
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionDeployed,
+			Status:  types.StatusFalse,
+			Reason:  types.ReasonWaitingForResources,
+			Message: message,
+		})
+		err := r.updateResourceStatus(ctx, o, status)
+		return reconcile.Result{RequeueAfter: r.requeuePeriod(status)}, err
+	}
+
+	waitErr := fmt.Errorf("timed out after %s waiting for release resources to be ready: %s", r.waitTimeout(), message)
+	r.recordRelease(o, manager, nil, waitErr)
+	status.SetCondition(types.HelmAppCondition{
+		Type:    types.ConditionProgressing,
+		Status:  types.StatusFalse,
+		Reason:  types.ReasonWaitTimeout,
+		Message: waitErr.Error(),
+	})
+	if r.Atomic && revision > 1 {
+		return r.rollbackRelease(ctx, log, o, manager, status, waitErr, release.RollbackToPrevious())
+	}
+	if r.Atomic {
+		return r.uninstallTimedOutRelease(ctx, log, o, manager, status, waitErr)
+	}
+
+	log.Error(waitErr, "Release failed")
+	status.SetCondition(types.HelmAppCondition{
+		Type:    types.ConditionReleaseFailed,
+		Status:  types.StatusTrue,
+		Reason:  types.ReasonWaitTimeout,
+		Message: waitErr.Error(),
+	})
+	status.SetCondition(types.HelmAppCondition{
+		Type:    types.ConditionDeployed,
+		Status:  types.StatusFalse,
+		Reason:  types.ReasonWaitTimeout,
+		Message: waitErr.Error(),
+	})
+	if err := r.updateResourceStatus(ctx, o, status); err != nil {
+		log.Error(err, "Failed to update status after release wait timeout")
+	}
+	return reconcile.Result{}, waitErr
+}
+
+// uninstallTimedOutRelease uninstalls a release whose resources were not
+// ready within the wait timeout after it was installed. The install is
+// retried on the next reconciliation.
+func (r HelmOperatorReconciler) uninstallTimedOutRelease(ctx context.Context, log logr.Logger, o *unstructured.Unstructured,
+	manager release.Manager, status *types.HelmAppStatus, waitErr error) (reconcile.Result, error) {
+	log.Error(waitErr, "Release failed, uninstalling")
+	if _, err := manager.UninstallRelease(ctx); err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+		log.Error(err, "Failed to uninstall release")
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionReleaseFailed,
+			Status:  types.StatusTrue,
+			Reason:  types.ReasonUninstallError,
+			Message: fmt.Sprintf("failed install (%s) and failed uninstall: %s", waitErr, err),
+		})
+		if err := r.updateResourceStatus(ctx, o, status); err != nil {
+			log.Error(err, "Failed to update status after uninstall release failure")
+		}
+		return reconcile.Result{}, err
+	}
+	metrics.ReleaseUninstalled(r.GVK.String(), o.GetNamespace(), manager.ReleaseName())
+
+	log.Info("Uninstalled release")
+	r.EventRecorder.Eventf(o, "Warning", "Uninstalled", "Install timed out, uninstalled release: %s", waitErr)
+	status.SetCondition(types.HelmAppCondition{
+		Type:    types.ConditionReleaseFailed,
+		Status:  types.StatusTrue,
+		Reason:  types.ReasonWaitTimeout,
+		Message: waitErr.Error(),
+	})
+	status.SetCondition(types.HelmAppCondition{
+		Type:    types.ConditionDeployed,
+		Status:  types.StatusFalse,
+		Reason:  types.ReasonWaitTimeout,
+		Message: waitErr.Error(),
+	})
+	status.DeployedRelease = nil
+	status.Progress = nil
+	if err := r.updateResourceStatus(ctx, o, status); err != nil {
+		log.Error(err, "Failed to update status after uninstalling release")
+	}
+	return reconcile.Result{}, waitErr
+}
+
+// waitTimeout returns the time to wait for the resources of a release to be
+// ready.
+func (r HelmOperatorReconciler) waitTimeout() time.Duration {
+	if r.Timeout > 0 {
+		return r.Timeout
+	}
+	return defaultWaitTimeout
+}
+
+// newProgress records rel, which the operator just installed or upgraded, as
+// the revision whose resources are waited for.
+func newProgress(rel *rpb.Release) *types.HelmAppProgress {
+	return &types.HelmAppProgress{Revision: rel.Version, StartTime: metav1.Now()}
+}
+
+// waitRemaining returns how much longer to wait for the resources of the
+// revision of progress to be ready, from the time it was installed or
+// upgraded. It is not positive once the wait timed out.
+func (r HelmOperatorReconciler) waitRemaining(progress *types.HelmAppProgress) time.Duration {
+	return r.waitTimeout() - time.Since(progress.StartTime.Time)
+}
+
+// resourcesReady reports whether the Ready condition of status is true, and
+// returns its message otherwise.
+func resourcesReady(status *types.HelmAppStatus) (bool, string) {
+	for _, c := range status.Conditions {
+		if c.Type == types.ConditionReady {
+			return c.Status == types.StatusTrue, c.Message
+		}
+	}
+	return false, ""
+}
+
+// rollbackRelease rolls a release back to its last deployed revision after
+// the upgrade failed with upgradeErr. The upgrade is retried on the next
+// reconciliation.
+func (r HelmOperatorReconciler) rollbackRelease(ctx context.Context, log logr.Logger, o *unstructured.Unstructured,
+	manager release.Manager, status *types.HelmAppStatus, upgradeErr error,
+	opts ...release.RollbackOption) (reconcile.Result, error) {
+	log.Error(upgradeErr, "Release failed, rolling back")
+	rolledBackRelease, err := manager.RollbackRelease(ctx, opts...)
+	if err != nil {
+		log.Error(err, "Failed to roll back release")
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionReleaseFailed,
+			Status:  types.StatusTrue,
+			Reason:  types.ReasonRollbackError,
+			Message: fmt.Sprintf("failed upgrade (%s) and failed rollback: %s", upgradeErr, err),
+		})
+		if err := r.updateResourceStatus(ctx, o, status); err != nil {
+			log.Error(err, "Failed to update status after rollback release failure")
+		}
+		return reconcile.Result{}, err
+	}
+
+	log.Info("Rolled back release", "revision", rolledBackRelease.Version)
+	r.EventRecorder.Eventf(o, "Warning", "RolledBack",
+		"Upgrade failed, rolled back release to revision %d: %s", rolledBackRelease.Version, upgradeErr)
+	status.SetCondition(types.HelmAppCondition{
+		Type:    types.ConditionReleaseFailed,
+		Status:  types.StatusTrue,
+		Reason:  types.ReasonRolledBack,
+		Message: upgradeErr.Error(),
+	})
+	status.DeployedRelease = &types.HelmAppRelease{
+		Name:      rolledBackRelease.Name,
+		Namespace: rolledBackRelease.Namespace,
+		Manifest:  rolledBackRelease.Manifest,
+	}
+	status.Progress = nil
+	r.setResourceStatuses(ctx, log, manager, status)
+	r.setHistory(ctx, log, manager, status)
+	if err := r.updateResourceStatus(ctx, o, status); err != nil {
+		log.Error(err, "Failed to update status after rollback release")
+	}
+	return reconcile.Result{}, upgradeErr
+}
+
+// testRelease runs the test hooks of the deployed release in the background,
+// and sets the Tested condition to Unknown until they complete. A later
+// reconciliation then reports the result of each test as an event and in the
+// Tested condition, and removes the test annotation from the resource, so
+// that the tests only run once each time the annotation is set.
+func (r HelmOperatorReconciler) testRelease(ctx context.Context, log logr.Logger, o *unstructured.Unstructured,
+	manager release.Manager, status *types.HelmAppStatus) error {
+	key := apitypes.NamespacedName{Namespace: o.GetNamespace(), Name: o.GetName()}
+	run, started := r.tests.start(key, manager)
+	select {
+	case <-run.done:
+	default:
+		if started {
+			log.Info("Testing release")
+		}
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionTested,
+			Status:  types.StatusUnknown,
+			Reason:  types.ReasonTestsRunning,
+			Message: "release tests are running",
+		})
+		return nil
+	}
+	r.tests.forget(key)
+	results, err := run.results, run.err
+
+	var passed, failed, notRun []string
+	for _, result := range results {
+		switch {
+		case result.Passed():
+			passed = append(passed, result.Name)
+			r.EventRecorder.Eventf(o, "Normal", "TestPassed", "Test %q passed", result.Name)
+		case result.NotRun():
+			notRun = append(notRun, result.Name)
+			r.EventRecorder.Eventf(o, "Warning", "TestNotRun", "Test %q did not run", result.Name)
+		default:
+			failed = append(failed, result.Name)
+			r.EventRecorder.Eventf(o, "Warning", "TestFailed", "Test %q did not pass: %s", result.Name, result.Phase)
+		}
+	}
+
+	switch {
+	case len(failed) > 0:
+		log.Info("Release tests failed", "passed", passed, "failed", failed, "notRun", notRun)
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionTested,
+			Status:  types.StatusFalse,
+			Reason:  types.ReasonTestsFailed,
+			Message: fmt.Sprintf("failed tests: %s", strings.Join(failed, ", ")),
+		})
+	case err != nil:
+		log.Error(err, "Failed to test release")
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionTested,
+			Status:  types.StatusFalse,
+			Reason:  types.ReasonTestError,
+			Message: err.Error(),
+		})
+	case len(notRun) > 0:
+		log.Info("Release tests did not run", "passed", passed, "notRun", notRun)
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionTested,
+			Status:  types.StatusUnknown,
+			Reason:  types.ReasonTestsNotRun,
+			Message: fmt.Sprintf("tests not run: %s", strings.Join(notRun, ", ")),
+		})
+	default:
+		log.Info("Release tests passed", "passed", passed)
+		message := "release has no tests"
+		if len(passed) > 0 {
+			message = fmt.Sprintf("passed tests: %s", strings.Join(passed, ", "))
+		}
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionTested,
+			Status:  types.StatusTrue,
+			Reason:  types.ReasonTestsPassed,
+			Message: message,
+		})
+	}
+
+	annotations := o.GetAnnotations()
+	delete(annotations, helmTestAnnotation)
+	o.SetAnnotations(annotations)
+	return r.updateResource(ctx, o)
+}
+
+// dryRunRelease renders the release that would be installed or upgraded
+// without changing the release. It reports the resulting manifest diff in the
+// CR status, with the data of Secrets redacted and truncated to
+// maxDryRunDiffSize, and the changed resources as an event.
+func (r HelmOperatorReconciler) dryRunRelease(ctx context.Context, log logr.Logger, o *unstructured.Unstructured,
+	manager release.Manager, status *types.HelmAppStatus) (reconcile.Result, error) {
+	deployedRelease, candidateRelease, err := manager.DryRunRelease(ctx)
+	if err != nil {
+		log.Error(err, "Failed to dry run release")
+		status.SetCondition(types.HelmAppCondition{
+			Type:    types.ConditionReleaseFailed,
+			Status:  types.StatusTrue,
+			Reason:  types.ReasonDryRunError,
+			Message: err.Error(),
+		})
+		if err := r.updateResourceStatus(ctx, o, status); err != nil {
+			log.Error(err, "Failed to update status after dry run release failure")
+		}
+		return reconcile.Result{}, err
+	}
+	status.RemoveCondition(types.ConditionReleaseFailed)
+
+	deployedManifest := ""
+	if deployedRelease != nil {
+		deployedManifest = deployedRelease.Manifest
+	}
+	if deployedManifest == candidateRelease.Manifest {
+		log.Info("Dry run release, no changes")
+		status.DryRunDiff = ""
+		r.EventRecorder.Event(o, "Normal", "DryRun", "Release is up to date, no changes would be made")
+	} else {
+		log.Info("Dry run release, changes pending")
+		d := diff.GeneratePlain(release.RedactManifest(deployedManifest), release.RedactManifest(candidateRelease.Manifest))
+		status.DryRunDiff = truncateDiff(d, maxDryRunDiffSize)
+		r.EventRecorder.Event(o, "Normal", "DryRun", dryRunSummary(deployedManifest, candidateRelease.Manifest))
+	}
+
 	err = r.updateResourceStatus(ctx, o, status)
 	return reconcile.Result{RequeueAfter: r.ReconcilePeriod}, err
 }
 
+// truncateDiff returns d cut at the last line that fits in maxSize bytes,
+// followed by a note of how much was cut.
+func truncateDiff(d string, maxSize int) string {
+	if len(d) <= maxSize {
+		return d
+	}
+	cut := strings.LastIndex(d[:maxSize], "\n") + 1
+	return fmt.Sprintf("%s... diff truncated, %d more bytes\n", d[:cut], len(d)-cut)
+}
+
+// dryRunSummary returns a message listing the resources that would be
+// added, changed or removed by replacing the deployed manifest with the
+// candidate manifest, without their content.
+func dryRunSummary(deployedManifest, candidateManifest string) string {
+	deployed, candidate := manifestResources(deployedManifest), manifestResources(candidateManifest)
+	var changes []string
+	for ref, manifest := range candidate {
+		deployedResource, ok := deployed[ref]
+		switch {
+		case !ok:
+			changes = append(changes, ref+" added")
+		case deployedResource != manifest:
+			changes = append(changes, ref+" changed")
+		}
+	}
+	for ref := range deployed {
+		if _, ok := candidate[ref]; !ok {
+			changes = append(changes, ref+" removed")
+		}
+	}
+	if len(changes) == 0 {
+		return "Release would be changed"
+	}
+	sort.Strings(changes)
+
+	total := len(changes)
+	if total > maxDryRunEventResources {
+		changes = append(changes[:maxDryRunEventResources], fmt.Sprintf("and %d more", total-maxDryRunEventResources))
+	}
+	return fmt.Sprintf("Release would change %d resource(s): %s", total, strings.Join(changes, ", "))
+}
+
+// manifestResources returns the manifests of the resources in manifest by
+// resource reference.
+func manifestResources(manifest string) map[string]string {
+	resources := map[string]string{}
+	for _, m := range releaseutil.SplitManifests(manifest) {
+		var head struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Namespace string `json:"namespace"`
+				Name      string `json:"name"`
+			} `json:"metadata"`
+		}
+		if err := yaml.Unmarshal([]byte(m), &head); err != nil || head.Kind == "" {
+			continue
+		}
+		resources[resourceRef(head.Kind, head.Metadata.Namespace, head.Metadata.Name)] = m
+	}
+	return resources
+}
+
+// resourceRef returns a reference to a resource for messages.
+func resourceRef(kind, namespace, name string) string {
+	if namespace == "" {
+		return fmt.Sprintf("%s %s", kind, name)
+	}
+	return fmt.Sprintf("%s %s/%s", kind, namespace, name)
+}
+
+// recordDrifts reports resources that were corrected during release
+// reconciliation in the CR status, as events, and as metrics.
+func (r HelmOperatorReconciler) recordDrifts(log logr.Logger, o *unstructured.Unstructured, status *types.HelmAppStatus, drifts []release.Drift) {
+	if len(drifts) == 0 {
+		status.RemoveCondition(types.ConditionDrifted)
+		return
+	}
+
+	now := metav1.Now()
+	resources := make([]string, 0, len(drifts))
+	for _, d := range drifts {
+		apiVersion, kind := d.GroupVersionKind.ToAPIVersionAndKind()
+		ref := resourceRef(kind, d.Namespace, d.Name)
+		resources = append(resources, ref)
+
+		// The patch of a Secret holds its data, which must not be logged or
+		// recorded in the status.
+		patch := string(d.RedactedPatch())
+		log.Info("Corrected drifted resource", "resourceApiVersion", apiVersion, "resourceKind", kind,
+			"resourceNamespace", d.Namespace, "resourceName", d.Name, "action", d.Action, "patch", patch)
+		r.EventRecorder.Eventf(o, "Warning", "DriftCorrected", "%s %s to match the release manifest", d.Action, ref)
+		metrics.DriftCorrected(r.GVK.String(), d.GroupVersionKind.String())
+
+		status.AddDrift(types.HelmAppDrift{
+			APIVersion:   apiVersion,
+			Kind:         kind,
+			Namespace:    d.Namespace,
+			Name:         d.Name,
+			Action:       d.Action,
+			Patch:        patch,
+			PatchType:    string(d.PatchType),
+			DetectedTime: now,
+		})
+	}
+	status.SetCondition(types.HelmAppCondition{
+		Type:    types.ConditionDrifted,
+		Status:  types.StatusTrue,
+		Reason:  types.ReasonDriftCorrected,
+		Message: fmt.Sprintf("Corrected drift in %d resource(s): %s", len(drifts), strings.Join(resources, ", ")),
+	})
+}
+
+// pauseReconciliation sets the Paused condition of o without installing,
+// upgrading or reconciling its release, so that release resources can be
+// modified by hand. Reconciliation resumes when the pause annotation is
+// removed.
+func (r HelmOperatorReconciler) pauseReconciliation(ctx context.Context, log logr.Logger,
+	o *unstructured.Unstructured) (reconcile.Result, error) {
+	log.Info("Reconciliation is paused, skipping release reconciliation")
+	status := types.StatusFor(o)
+	status.SetCondition(types.HelmAppCondition{
+		Type:    types.ConditionPaused,
+		Status:  types.StatusTrue,
+		Reason:  types.ReasonReconcilePaused,
+		Message: fmt.Sprintf("Reconciliation is paused by the %s annotation", helmPauseAnnotation),
+	})
+	if err := r.updateResourceStatus(ctx, o, status); err != nil {
+		log.Error(err, "Failed to update status after pausing reconciliation")
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, nil
+}
+
 // returns the boolean representation of the annotation string
 // will return false if annotation is not set
 func hasAnnotation(anno string, o *unstructured.Unstructured) bool {
@@ -415,8 +1289,14 @@ func (r HelmOperatorReconciler) updateResource(ctx context.Context, o client.Obj
 }
 
 func (r HelmOperatorReconciler) updateResourceStatus(ctx context.Context, o *unstructured.Unstructured, status *types.HelmAppStatus) error {
+	// Store the status as JSON values, like the rest of the object, so that
+	// the object can be deep copied.
+	statusMap, err := status.ToMap()
+	if err != nil {
+		return err
+	}
 	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
-		o.Object["status"] = status
+		o.Object["status"] = statusMap
 		return r.Client.Status().Update(ctx, o)
 	})
 }
@@ -15,10 +15,25 @@
 package controller
 
 import (
+	"context"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/go-logr/logr"
 	"github.com/stretchr/testify/assert"
+	rpb "helm.sh/helm/v3/pkg/release"
+	helmtime "helm.sh/helm/v3/pkg/time"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/internal/types"
+	"github.com/operator-framework/operator-sdk/internal/helm/release"
 )
 
 func TestHasAnnotation(t *testing.T) {
@@ -129,6 +144,102 @@ func TestHasAnnotation(t *testing.T) {
 	for _, test := range uninstallWaitTests {
 		assert.Equal(t, test.expectedVal, hasAnnotation(helmUninstallWaitAnnotation, annotations(test.input)), test.name)
 	}
+
+	dryRunTests := []struct {
+		input       map[string]interface{}
+		expectedVal bool
+		name        string
+	}{
+		{
+			input: map[string]interface{}{
+				"helm.sdk.operatorframework.io/dry-run": "true",
+			},
+			expectedVal: true,
+			name:        "dry run base case true",
+		},
+		{
+			input: map[string]interface{}{
+				"helm.sdk.operatorframework.io/dry-run": "false",
+			},
+			expectedVal: false,
+			name:        "dry run base case false",
+		},
+		{
+			input: map[string]interface{}{
+				"helm.sdk.operatorframework.io/upgrade-force": "true",
+			},
+			expectedVal: false,
+			name:        "dry run annotation not set",
+		},
+	}
+
+	for _, test := range dryRunTests {
+		assert.Equal(t, test.expectedVal, hasAnnotation(helmDryRunAnnotation, annotations(test.input)), test.name)
+	}
+
+	testTests := []struct {
+		input       map[string]interface{}
+		expectedVal bool
+		name        string
+	}{
+		{
+			input: map[string]interface{}{
+				"helm.sdk.operatorframework.io/test": "true",
+			},
+			expectedVal: true,
+			name:        "test base case true",
+		},
+		{
+			input: map[string]interface{}{
+				"helm.sdk.operatorframework.io/test": "false",
+			},
+			expectedVal: false,
+			name:        "test base case false",
+		},
+		{
+			input: map[string]interface{}{
+				"helm.sdk.operatorframework.io/dry-run": "true",
+			},
+			expectedVal: false,
+			name:        "test annotation not set",
+		},
+	}
+
+	for _, test := range testTests {
+		assert.Equal(t, test.expectedVal, hasAnnotation(helmTestAnnotation, annotations(test.input)), test.name)
+	}
+
+	pauseTests := []struct {
+		input       map[string]interface{}
+		expectedVal bool
+		name        string
+	}{
+		{
+			input: map[string]interface{}{
+				"helm.sdk.operatorframework.io/pause": "true",
+			},
+			expectedVal: true,
+			name:        "pause base case true",
+		},
+		{
+			input: map[string]interface{}{
+				"helm.sdk.operatorframework.io/pause": "false",
+			},
+			expectedVal: false,
+			name:        "pause base case false",
+		},
+		{
+			input: map[string]interface{}{
+				"helm.sdk.operatorframework.io/test": "true",
+			},
+			expectedVal: false,
+			name:        "pause annotation not set",
+		},
+	}
+
+	for _, test := range pauseTests {
+		assert.Equal(t, test.expectedVal, hasAnnotation(helmPauseAnnotation, annotations(test.input)), test.name)
+	}
 }
 
 func annotations(m map[string]interface{}) *unstructured.Unstructured {
@@ -140,3 +251,598 @@ func annotations(m map[string]interface{}) *unstructured.Unstructured {
 		},
 	}
 }
+
+func TestRequeuePeriod(t *testing.T) {
+	ready := &types.HelmAppStatus{Conditions: []types.HelmAppCondition{
+		{Type: types.ConditionReady, Status: types.StatusTrue},
+	}}
+	notReady := &types.HelmAppStatus{Conditions: []types.HelmAppCondition{
+		{Type: types.ConditionReady, Status: types.StatusFalse},
+	}}
+	testsRunning := &types.HelmAppStatus{Conditions: []types.HelmAppCondition{
+		{Type: types.ConditionReady, Status: types.StatusTrue},
+		{Type: types.ConditionTested, Status: types.StatusUnknown, Reason: types.ReasonTestsRunning},
+	}}
+
+	tests := []struct {
+		name            string
+		reconcilePeriod time.Duration
+		status          *types.HelmAppStatus
+		expected        time.Duration
+	}{
+		{"ready", time.Minute, ready, time.Minute},
+		{"no ready condition", time.Minute, &types.HelmAppStatus{}, time.Minute},
+		{"not ready", time.Minute, notReady, notReadyRequeuePeriod},
+		{"not ready without reconcile period", 0, notReady, notReadyRequeuePeriod},
+		{"not ready with short reconcile period", time.Second, notReady, time.Second},
+		{"tests running", time.Minute, testsRunning, notReadyRequeuePeriod},
+	}
+
+	for _, test := range tests {
+		r := HelmOperatorReconciler{ReconcilePeriod: test.reconcilePeriod}
+		assert.Equal(t, test.expected, r.requeuePeriod(test.status), test.name)
+	}
+}
+
+func TestWaitRemaining(t *testing.T) {
+	startedAt := func(d time.Duration) *types.HelmAppProgress {
+		return &types.HelmAppProgress{Revision: 2, StartTime: metav1.NewTime(time.Now().Add(-d))}
+	}
+
+	r := HelmOperatorReconciler{}
+	assert.True(t, r.waitRemaining(startedAt(time.Minute)) > 0)
+	assert.True(t, r.waitRemaining(startedAt(10*time.Minute)) <= 0)
+
+	r.Timeout = 30 * time.Second
+	assert.True(t, r.waitRemaining(startedAt(time.Minute)) <= 0)
+}
+
+func TestTargetRevision(t *testing.T) {
+	newCR := func(spec map[string]interface{}, revisionAnnotation string) *unstructured.Unstructured {
+		o := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+		if revisionAnnotation != "" {
+			o.SetAnnotations(map[string]string{helmTargetRevisionAnnotation: revisionAnnotation})
+		}
+		return o
+	}
+
+	tests := []struct {
+		name      string
+		field     string
+		cr        *unstructured.Unstructured
+		expected  int
+		expectErr bool
+	}{
+		{"not pinned", "", newCR(map[string]interface{}{}, ""), 0, false},
+		{"annotation", "", newCR(map[string]interface{}{}, "3"), 3, false},
+		{"spec field", "targetRevision", newCR(map[string]interface{}{"targetRevision": int64(2)}, "3"), 2, false},
+		{"unset spec field", "targetRevision", newCR(map[string]interface{}{}, "3"), 3, false},
+		{"spec field not configured", "", newCR(map[string]interface{}{"targetRevision": int64(2)}, ""), 0, false},
+		{"invalid annotation", "", newCR(map[string]interface{}{}, "latest"), 0, true},
+		{"zero annotation", "", newCR(map[string]interface{}{}, "0"), 0, true},
+		{"negative spec field", "targetRevision", newCR(map[string]interface{}{"targetRevision": int64(-1)}, ""), 0, true},
+		{"non-integer spec field", "targetRevision", newCR(map[string]interface{}{"targetRevision": "2"}, ""), 0, true},
+	}
+
+	for _, test := range tests {
+		r := HelmOperatorReconciler{TargetRevisionField: test.field}
+		revision, err := r.targetRevision(test.cr)
+		if test.expectErr {
+			assert.Error(t, err, test.name)
+			continue
+		}
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, revision, test.name)
+	}
+}
+
+func TestIsPinned(t *testing.T) {
+	history := []*rpb.Release{
+		{Version: 1, Info: &rpb.Info{Status: rpb.StatusSuperseded}},
+		{Version: 2, Info: &rpb.Info{Status: rpb.StatusSuperseded}},
+		{Version: 3, Info: &rpb.Info{Status: rpb.StatusDeployed, Description: "Rollback to 1"}},
+		{Version: 4, Info: &rpb.Info{Status: rpb.StatusFailed}},
+	}
+	assert.True(t, isPinned(history, 1))
+	assert.True(t, isPinned(history, 3))
+	assert.False(t, isPinned(history, 2))
+	assert.False(t, isPinned(nil, 1))
+}
+
+// findCondition returns the condition of status with type conditionType, or
+// nil if it is not set.
+func findCondition(status *types.HelmAppStatus, conditionType types.HelmAppConditionType) *types.HelmAppCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == conditionType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func TestReconcilePaused(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Test"}
+	o := &unstructured.Unstructured{}
+	o.SetGroupVersionKind(gvk)
+	o.SetNamespace("ns")
+	o.SetName("test")
+	o.SetAnnotations(map[string]string{helmPauseAnnotation: "true"})
+	o.Object["spec"] = map[string]interface{}{}
+
+	// The reconciler has no manager factory, so it fails if it attempts to
+	// manage the release of the paused resource.
+	c := fakeclient.NewClientBuilder().WithObjects(o).Build()
+	r := HelmOperatorReconciler{Client: c, GVK: gvk}
+
+	key := apitypes.NamespacedName{Namespace: "ns", Name: "test"}
+	result, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+	assert.NoError(t, err)
+	assert.Equal(t, reconcile.Result{}, result)
+
+	paused := &unstructured.Unstructured{}
+	paused.SetGroupVersionKind(gvk)
+	assert.NoError(t, c.Get(context.TODO(), key, paused))
+	condition := findCondition(types.StatusFor(paused), types.ConditionPaused)
+	if assert.NotNil(t, condition) {
+		assert.Equal(t, types.StatusTrue, condition.Status)
+		assert.Equal(t, types.ReasonReconcilePaused, condition.Reason)
+	}
+}
+
+func TestReconcileSelector(t *testing.T) {
+	// The reconciler has no manager factory, so it fails if it attempts to
+	// manage the release of the excluded resource.
+	r, _ := newTestReconciler(nil, nil)
+	selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{MatchLabels: map[string]string{"tier": "backend"}})
+	assert.NoError(t, err)
+	r.Selector = selector
+
+	reconciled, result, err := reconcileTestResource(t, r)
+	assert.NoError(t, err)
+	assert.Equal(t, reconcile.Result{}, result)
+	assert.Empty(t, reconciled.GetFinalizers())
+	assert.Nil(t, reconciled.Object["status"])
+}
+
+func TestRecordDriftsRedactsSecrets(t *testing.T) {
+	drifts := []release.Drift{
+		{
+			GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "Secret"},
+			Namespace:        "ns",
+			Name:             "test-credentials",
+			Action:           release.DriftActionPatched,
+			Patch:            []byte(`{"data":{"password":"c2VjcmV0"}}`),
+			PatchType:        apitypes.StrategicMergePatchType,
+		},
+		{
+			GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+			Namespace:        "ns",
+			Name:             "test-config",
+			Action:           release.DriftActionPatched,
+			Patch:            []byte(`{"data":{"key":"value"}}`),
+			PatchType:        apitypes.StrategicMergePatchType,
+		},
+	}
+
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Test"}
+	r := HelmOperatorReconciler{GVK: gvk, EventRecorder: record.NewFakeRecorder(10)}
+	status := &types.HelmAppStatus{}
+	r.recordDrifts(log, &unstructured.Unstructured{}, status, drifts)
+
+	if assert.Len(t, status.DriftHistory, 2) {
+		assert.Equal(t, `{"data":{"password":"REDACTED"}}`, status.DriftHistory[0].Patch)
+		assert.Equal(t, `{"data":{"key":"value"}}`, status.DriftHistory[1].Patch)
+	}
+}
+
+// fakeReleaseManager is a release.Manager whose release is installed and up
+// to date. It panics if the release is installed or upgraded, and records the
+// releases rolled back and uninstalled in calls.
+type fakeReleaseManager struct {
+	release.Manager
+	deployed, candidate *rpb.Release
+	reconcileErr        error
+	statuses            []release.ResourceStatus
+	calls               []string
+	// testsDone, when set, blocks the tests until it is closed.
+	testsDone   chan struct{}
+	testResults []release.TestResult
+}
+
+func (m *fakeReleaseManager) ReleaseName() string        { return "test" }
+func (m *fakeReleaseManager) Sync(context.Context) error { return nil }
+func (m *fakeReleaseManager) IsInstalled() bool          { return true }
+func (m *fakeReleaseManager) IsUpgradeRequired() bool    { return false }
+func (m *fakeReleaseManager) DryRunRelease(context.Context) (*rpb.Release, *rpb.Release, error) {
+	return m.deployed, m.candidate, nil
+}
+
+func (m *fakeReleaseManager) ReconcileRelease(context.Context) (*rpb.Release, []release.Drift, error) {
+	return m.deployed, nil, m.reconcileErr
+}
+
+func (m *fakeReleaseManager) ReleaseHistory(context.Context) ([]*rpb.Release, error) {
+	return []*rpb.Release{m.deployed}, nil
+}
+
+func (m *fakeReleaseManager) ResourceStatuses(context.Context, string) ([]release.ResourceStatus, error) {
+	return m.statuses, nil
+}
+
+func (m *fakeReleaseManager) RollbackRelease(context.Context, ...release.RollbackOption) (*rpb.Release, error) {
+	m.calls = append(m.calls, "rollback")
+	return &rpb.Release{Name: "test", Version: m.deployed.Version + 1}, nil
+}
+
+func (m *fakeReleaseManager) UninstallRelease(context.Context, ...release.UninstallOption) (*rpb.Release, error) {
+	m.calls = append(m.calls, "uninstall")
+	return m.deployed, nil
+}
+
+func (m *fakeReleaseManager) TestRelease(context.Context, ...release.TestOption) ([]release.TestResult, error) {
+	if m.testsDone != nil {
+		<-m.testsDone
+	}
+	return m.testResults, nil
+}
+
+type fakeManagerFactory struct {
+	manager release.Manager
+}
+
+func (f fakeManagerFactory) NewManager(*unstructured.Unstructured, map[string]interface{}, map[string]string) (release.Manager, error) {
+	return f.manager, nil
+}
+
+// newTestReconciler returns a reconciler of the custom resource ns/test,
+// whose release is managed by manager.
+func newTestReconciler(manager release.Manager, annotations map[string]string) (HelmOperatorReconciler, *record.FakeRecorder) {
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Test"}
+	o := &unstructured.Unstructured{}
+	o.SetGroupVersionKind(gvk)
+	o.SetNamespace("ns")
+	o.SetName("test")
+	o.SetAnnotations(annotations)
+	o.Object["spec"] = map[string]interface{}{}
+
+	recorder := record.NewFakeRecorder(10)
+	c := fakeclient.NewClientBuilder().WithObjects(o).Build()
+	return HelmOperatorReconciler{
+		Client:         c,
+		EventRecorder:  recorder,
+		GVK:            gvk,
+		ManagerFactory: fakeManagerFactory{manager},
+		tests:          newTestRuns(),
+	}, recorder
+}
+
+// reconcileTestResource reconciles the custom resource ns/test, and returns
+// it once reconciled.
+func reconcileTestResource(t *testing.T, r HelmOperatorReconciler) (*unstructured.Unstructured, reconcile.Result, error) {
+	key := apitypes.NamespacedName{Namespace: "ns", Name: "test"}
+	result, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+	o := &unstructured.Unstructured{}
+	o.SetGroupVersionKind(r.GVK)
+	assert.NoError(t, r.Client.Get(context.TODO(), key, o))
+	return o, result, err
+}
+
+func TestReconcileDryRun(t *testing.T) {
+	deployed := `---
+# Source: test-chart/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: test-credentials
+data:
+  password: ZGVwbG95ZWQtc2VjcmV0
+---
+# Source: test-chart/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+spec:
+  replicas: 1
+`
+	candidate := `---
+# Source: test-chart/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: test-credentials
+data:
+  password: Y2FuZGlkYXRlLXNlY3JldA==
+---
+# Source: test-chart/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+---
+# Source: test-chart/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+spec:
+  replicas: 2
+`
+
+	manager := &fakeReleaseManager{
+		deployed:  &rpb.Release{Name: "test", Version: 1, Manifest: deployed},
+		candidate: &rpb.Release{Name: "test", Version: 2, Manifest: candidate},
+	}
+	r, recorder := newTestReconciler(manager, map[string]string{helmDryRunAnnotation: "true"})
+	reconciled, _, err := reconcileTestResource(t, r)
+	assert.NoError(t, err)
+	assert.Empty(t, reconciled.GetFinalizers(), "the release is not reconciled")
+
+	status := types.StatusFor(reconciled)
+	assert.Nil(t, status.DeployedRelease)
+	assert.Contains(t, status.DryRunDiff, "-  replicas: 1\n+  replicas: 2\n")
+	assert.Contains(t, status.DryRunDiff, "+kind: ConfigMap\n")
+	assert.Contains(t, status.DryRunDiff, "  password: REDACTED\n")
+	assert.NotContains(t, status.DryRunDiff, "ZGVwbG95ZWQtc2VjcmV0")
+	assert.NotContains(t, status.DryRunDiff, "Y2FuZGlkYXRlLXNlY3JldA==")
+
+	if assert.Len(t, recorder.Events, 1) {
+		assert.Equal(t, "Normal DryRun Release would change 3 resource(s): ConfigMap test added, "+
+			"Deployment test changed, Secret test-credentials changed", <-recorder.Events)
+	}
+}
+
+func TestTruncateDiff(t *testing.T) {
+	d := " a\n-b\n+c\n"
+	assert.Equal(t, d, truncateDiff(d, len(d)))
+	assert.Equal(t, " a\n-b\n... diff truncated, 3 more bytes\n", truncateDiff(d, len(d)-1))
+	assert.Equal(t, "... diff truncated, 9 more bytes\n", truncateDiff(d, 2))
+}
+
+func TestReconcileConflicts(t *testing.T) {
+	conflictErr := &release.ConflictError{Conflicts: []release.Conflict{{
+		GroupVersionKind: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		Namespace:        "ns",
+		Name:             "test",
+		Message:          `Apply failed with 1 conflict: conflict with "kube-controller-manager": .spec.replicas`,
+	}}}
+	manager := &fakeReleaseManager{
+		deployed:     &rpb.Release{Name: "test", Version: 1, Info: &rpb.Info{Status: rpb.StatusDeployed}},
+		reconcileErr: fmt.Errorf("failed to apply release: %w", conflictErr),
+	}
+	r, recorder := newTestReconciler(manager, nil)
+	reconciled, _, err := reconcileTestResource(t, r)
+	assert.NoError(t, err, "conflicts are not reconcile errors")
+
+	status := types.StatusFor(reconciled)
+	conflicted := findCondition(status, types.ConditionConflicted)
+	if assert.NotNil(t, conflicted) {
+		assert.Equal(t, types.StatusTrue, conflicted.Status)
+		assert.Equal(t, types.ReasonFieldConflict, conflicted.Reason)
+		assert.Equal(t, conflictErr.Error(), conflicted.Message)
+	}
+	assert.Nil(t, findCondition(status, types.ConditionIrreconcilable))
+	if assert.NotEmpty(t, recorder.Events) {
+		assert.Contains(t, <-recorder.Events, "Warning FieldConflict Deployment ns/test has conflicting fields")
+	}
+}
+
+func TestReconcileTestRelease(t *testing.T) {
+	tests := []struct {
+		name           string
+		results        []release.TestResult
+		expectedStatus types.ConditionStatus
+		expectedReason types.HelmAppConditionReason
+		expectedEvents []string
+	}{
+		{
+			name:           "passed",
+			results:        []release.TestResult{{Name: "test-a", Phase: rpb.HookPhaseSucceeded}},
+			expectedStatus: types.StatusTrue,
+			expectedReason: types.ReasonTestsPassed,
+			expectedEvents: []string{`Normal TestPassed Test "test-a" passed`},
+		},
+		{
+			name: "failed",
+			results: []release.TestResult{
+				{Name: "test-a", Phase: rpb.HookPhaseFailed},
+				{Name: "test-b"},
+			},
+			expectedStatus: types.StatusFalse,
+			expectedReason: types.ReasonTestsFailed,
+			expectedEvents: []string{
+				`Warning TestFailed Test "test-a" did not pass: Failed`,
+				`Warning TestNotRun Test "test-b" did not run`,
+			},
+		},
+		{
+			name: "not run",
+			results: []release.TestResult{
+				{Name: "test-a", Phase: rpb.HookPhaseSucceeded},
+				{Name: "test-b", Phase: rpb.HookPhaseUnknown},
+			},
+			expectedStatus: types.StatusUnknown,
+			expectedReason: types.ReasonTestsNotRun,
+			expectedEvents: []string{
+				`Normal TestPassed Test "test-a" passed`,
+				`Warning TestNotRun Test "test-b" did not run`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manager := &fakeReleaseManager{
+				deployed:    &rpb.Release{Name: "test", Version: 1, Info: &rpb.Info{Status: rpb.StatusDeployed}},
+				testsDone:   make(chan struct{}),
+				testResults: test.results,
+			}
+			r, recorder := newTestReconciler(manager, map[string]string{helmTestAnnotation: "true"})
+
+			// The tests run in the background, and the custom resource is
+			// requeued until they complete.
+			reconciled, result, err := reconcileTestResource(t, r)
+			assert.NoError(t, err)
+			assert.Equal(t, notReadyRequeuePeriod, result.RequeueAfter)
+			assert.Contains(t, reconciled.GetAnnotations(), helmTestAnnotation)
+			tested := findCondition(types.StatusFor(reconciled), types.ConditionTested)
+			if assert.NotNil(t, tested) {
+				assert.Equal(t, types.StatusUnknown, tested.Status)
+				assert.Equal(t, types.ReasonTestsRunning, tested.Reason)
+			}
+
+			close(manager.testsDone)
+			key := apitypes.NamespacedName{Namespace: "ns", Name: "test"}
+			run, started := r.tests.start(key, manager)
+			assert.False(t, started, "tests are not run twice")
+			<-run.done
+
+			reconciled, _, err = reconcileTestResource(t, r)
+			assert.NoError(t, err)
+			assert.NotContains(t, reconciled.GetAnnotations(), helmTestAnnotation)
+			tested = findCondition(types.StatusFor(reconciled), types.ConditionTested)
+			if assert.NotNil(t, tested) {
+				assert.Equal(t, test.expectedStatus, tested.Status)
+				assert.Equal(t, test.expectedReason, tested.Reason)
+			}
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+			assert.Equal(t, test.expectedEvents, events)
+			assert.Empty(t, r.tests.runs)
+		})
+	}
+}
+
+// setTestStatus sets the status of the custom resource ns/test.
+func setTestStatus(t *testing.T, r HelmOperatorReconciler, status *types.HelmAppStatus) {
+	key := apitypes.NamespacedName{Namespace: "ns", Name: "test"}
+	o := &unstructured.Unstructured{}
+	o.SetGroupVersionKind(r.GVK)
+	assert.NoError(t, r.Client.Get(context.TODO(), key, o))
+	statusMap, err := status.ToMap()
+	assert.NoError(t, err)
+	o.Object["status"] = statusMap
+	assert.NoError(t, r.Client.Update(context.TODO(), o))
+}
+
+func TestReconcileWaitReadyRelease(t *testing.T) {
+	tests := []struct {
+		name     string
+		progress *types.HelmAppProgress
+	}{
+		{"no progress", nil},
+		{"progress of another revision", &types.HelmAppProgress{Revision: 1, StartTime: metav1.NewTime(time.Now().Add(-time.Hour))}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lastDeployed := helmtime.Time{Time: time.Now().Add(-time.Hour)}
+			manager := &fakeReleaseManager{
+				deployed: &rpb.Release{Name: "test", Version: 2, Info: &rpb.Info{LastDeployed: lastDeployed}},
+				statuses: []release.ResourceStatus{{
+					GroupVersionKind: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+					Name:             "test",
+					Message:          "0 of 1 replicas are ready",
+				}},
+			}
+			r, _ := newTestReconciler(manager, nil)
+			r.Wait, r.Atomic, r.Timeout = true, true, time.Minute
+			status := &types.HelmAppStatus{Progress: test.progress}
+			if test.progress != nil {
+				status.SetCondition(types.HelmAppCondition{
+					Type:   types.ConditionProgressing,
+					Status: types.StatusTrue,
+					Reason: types.ReasonWaitingForResources,
+				})
+			}
+			setTestStatus(t, r, status)
+
+			// The release was not installed or upgraded by the operator, so its
+			// resources only update the Ready condition.
+			reconciled, _, err := reconcileTestResource(t, r)
+			assert.NoError(t, err)
+			assert.Empty(t, manager.calls)
+
+			status = types.StatusFor(reconciled)
+			assert.Nil(t, status.Progress)
+			assert.Nil(t, findCondition(status, types.ConditionProgressing))
+			assert.Nil(t, findCondition(status, types.ConditionReleaseFailed))
+			if deployed := findCondition(status, types.ConditionDeployed); assert.NotNil(t, deployed) {
+				assert.Equal(t, types.StatusTrue, deployed.Status)
+			}
+			if ready := findCondition(status, types.ConditionReady); assert.NotNil(t, ready) {
+				assert.Equal(t, types.StatusFalse, ready.Status)
+				assert.Equal(t, types.ReasonResourcesNotReady, ready.Reason)
+			}
+		})
+	}
+}
+
+func TestReconcileWaitTimeout(t *testing.T) {
+	manager := &fakeReleaseManager{
+		deployed: &rpb.Release{Name: "test", Version: 2},
+		statuses: []release.ResourceStatus{{
+			GroupVersionKind: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			Name:             "test",
+			Message:          "0 of 1 replicas are ready",
+		}},
+	}
+	r, _ := newTestReconciler(manager, nil)
+	r.Wait, r.Atomic, r.Timeout = true, true, time.Minute
+	setTestStatus(t, r, &types.HelmAppStatus{
+		Progress: &types.HelmAppProgress{Revision: 2, StartTime: metav1.NewTime(time.Now().Add(-time.Second))},
+	})
+
+	// The upgraded revision is waited for until the timeout.
+	reconciled, _, err := reconcileTestResource(t, r)
+	assert.NoError(t, err)
+	assert.Empty(t, manager.calls)
+	status := types.StatusFor(reconciled)
+	assert.NotNil(t, status.Progress)
+	if progressing := findCondition(status, types.ConditionProgressing); assert.NotNil(t, progressing) {
+		assert.Equal(t, types.StatusTrue, progressing.Status)
+	}
+
+	// It is then rolled back.
+	r.Timeout = time.Nanosecond
+	reconciled, _, err = reconcileTestResource(t, r)
+	assert.Error(t, err)
+	assert.Equal(t, []string{"rollback"}, manager.calls)
+	status = types.StatusFor(reconciled)
+	assert.Nil(t, status.Progress)
+	if failed := findCondition(status, types.ConditionReleaseFailed); assert.NotNil(t, failed) {
+		assert.Equal(t, types.ReasonRolledBack, failed.Reason)
+	}
+}
+
+// recordingLogger is a logr.Logger that records the messages and values it
+// logs at any verbosity.
+type recordingLogger struct {
+	logr.Logger
+	messages *[]string
+}
+
+func (l recordingLogger) V(int) logr.Logger { return l }
+func (l recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	*l.messages = append(*l.messages, fmt.Sprint(msg, keysAndValues))
+}
+
+func TestLogConfigValues(t *testing.T) {
+	rel := &rpb.Release{Config: map[string]interface{}{"password": "secret"}}
+
+	var messages []string
+	r := HelmOperatorReconciler{ValuesFrom: []release.ValuesSource{{Kind: release.ValuesSourceConfigMap, Name: "values"}}}
+	r.logConfigValues(recordingLogger{messages: &messages}, rel)
+	if assert.Len(t, messages, 1) {
+		assert.Contains(t, messages[0], "secret")
+	}
+
+	messages = nil
+	r.ValuesFrom = append(r.ValuesFrom, release.ValuesSource{Kind: release.ValuesSourceSecret, Name: "credentials"})
+	r.logConfigValues(recordingLogger{messages: &messages}, rel)
+	if assert.Len(t, messages, 1) {
+		assert.NotContains(t, messages[0], "secret")
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"sync"
+
+	apitypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/release"
+)
+
+// testRun is a run of the test hooks of a release. Its results and err are
+// set once done is closed.
+type testRun struct {
+	done    chan struct{}
+	results []release.TestResult
+	err     error
+}
+
+// testRuns tracks the test runs of the releases of custom resources. Tests
+// run in the background, so that a worker is not blocked until they complete,
+// and their results are collected by a later reconciliation.
+type testRuns struct {
+	mu   sync.Mutex
+	runs map[apitypes.NamespacedName]*testRun
+}
+
+func newTestRuns() *testRuns {
+	return &testRuns{runs: map[apitypes.NamespacedName]*testRun{}}
+}
+
+// start runs the tests of the release of manager in the background, unless a
+// run was already started for key. It returns the run of key, and whether it
+// was just started.
+func (t *testRuns) start(key apitypes.NamespacedName, manager release.Manager) (*testRun, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if run, ok := t.runs[key]; ok {
+		return run, false
+	}
+	run := &testRun{done: make(chan struct{})}
+	t.runs[key] = run
+	go func() {
+		defer close(run.done)
+		// The tests outlive the reconciliation that started them.
+		run.results, run.err = manager.TestRelease(context.Background())
+	}()
+	return run, true
+}
+
+// forget removes the run of key, if any. The results of a run that is not
+// done yet are discarded.
+func (t *testRuns) forget(key apitypes.NamespacedName) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.runs, key)
+}
@@ -33,6 +33,7 @@ type Flags struct {
 	LeaderElectionNamespace string
 	MaxConcurrentReconciles int
 	ProbeAddr               string
+	EnableValidatingWebhook bool
 
 	// Path to a controller-runtime componentconfig file.
 	// If this is empty, use default values.
@@ -65,6 +66,13 @@ func (f *Flags) AddTo(flagSet *pflag.FlagSet) {
 		runtime.NumCPU(),
 		"Maximum number of concurrent reconciles for controllers.",
 	)
+	flagSet.BoolVar(&f.EnableValidatingWebhook,
+		"enable-validating-webhook",
+		false,
+		"Serve a validating admission webhook that rejects custom resources whose"+
+			" values do not meet the specifications of their chart's values schema,"+
+			" or with which their chart cannot be rendered.",
+	)
 
 	// Controller manager flags.
 	flagSet.StringVar(&f.ManagerConfigPath,
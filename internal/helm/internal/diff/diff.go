@@ -24,6 +24,15 @@ import (
 
 // Generate generates a diff between a and b, in color.
 func Generate(a, b string) string {
+	return generate(a, b, true)
+}
+
+// GeneratePlain generates a diff between a and b, without color.
+func GeneratePlain(a, b string) string {
+	return generate(a, b, false)
+}
+
+func generate(a, b string, color bool) string {
 	dmp := diffmatchpatch.New()
 
 	wSrc, wDst, warray := dmp.DiffLinesToRunes(a, b)
@@ -35,13 +44,13 @@ func Generate(a, b string) string {
 
 		switch diff.Type {
 		case diffmatchpatch.DiffInsert:
-			_, _ = buff.WriteString("\x1b[32m")
+			writeColor(&buff, "\x1b[32m", color)
 			_, _ = buff.WriteString(prefixLines(text, "+"))
-			_, _ = buff.WriteString("\x1b[0m")
+			writeColor(&buff, "\x1b[0m", color)
 		case diffmatchpatch.DiffDelete:
-			_, _ = buff.WriteString("\x1b[31m")
+			writeColor(&buff, "\x1b[31m", color)
 			_, _ = buff.WriteString(prefixLines(text, "-"))
-			_, _ = buff.WriteString("\x1b[0m")
+			writeColor(&buff, "\x1b[0m", color)
 		case diffmatchpatch.DiffEqual:
 			_, _ = buff.WriteString(prefixLines(text, " "))
 		}
@@ -49,6 +58,12 @@ func Generate(a, b string) string {
 	return buff.String()
 }
 
+func writeColor(buff *bytes.Buffer, code string, color bool) {
+	if color {
+		_, _ = buff.WriteString(code)
+	}
+}
+
 func prefixLines(s, prefix string) string {
 	var buf bytes.Buffer
 	lines := strings.Split(s, "\n")
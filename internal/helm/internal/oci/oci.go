@@ -0,0 +1,161 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package oci pulls Helm charts stored as OCI artifacts from container
+// registries.
+//
+// Helm v3.4 only supports OCI registries in its experimental registry
+// package, which is internal and cannot be imported, and its chart
+// downloader does not handle OCI references. Charts are therefore pulled with
+// ORAS and containerd's registry resolver, which Helm's registry client wraps,
+// using the same credentials file and media types as Helm. This package can be
+// replaced by Helm's downloader once it supports OCI references.
+package oci
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+
+	orasdocker "github.com/deislabs/oras/pkg/auth/docker"
+	orascontent "github.com/deislabs/oras/pkg/content"
+	orascontext "github.com/deislabs/oras/pkg/context"
+	"github.com/deislabs/oras/pkg/oras"
+	"helm.sh/helm/v3/pkg/helmpath"
+)
+
+const (
+	// Scheme is the prefix of OCI chart references.
+	Scheme = "oci://"
+
+	// configMediaType is the media type of the config of chart manifests.
+	configMediaType = "application/vnd.cncf.helm.config.v1+json"
+)
+
+// chartLayerMediaTypes are the media types used for chart content layers by
+// the different versions of Helm's OCI support.
+var chartLayerMediaTypes = map[string]struct{}{
+	"application/vnd.cncf.helm.chart.content.v1.tar+gzip": {},
+	"application/tar+gzip":                                {},
+}
+
+// IsOCI returns true if ref is an OCI chart reference.
+func IsOCI(ref string) bool {
+	return strings.HasPrefix(ref, Scheme)
+}
+
+// Client pulls charts from OCI registries.
+type Client struct {
+	// HTTPClient is the client used to talk to registries. If nil,
+	// http.DefaultClient is used.
+	HTTPClient *http.Client
+	// CredentialsFile is a Docker-style config file containing registry
+	// credentials. If empty, Helm's registry config file is used.
+	CredentialsFile string
+}
+
+// Pull downloads the chart referenced by ref at version into destDir, and
+// returns the path of the downloaded chart archive. The archive's digest is
+// verified against the digest recorded in the chart's manifest.
+//
+// Registries on loopback addresses are accessed over plain HTTP, all other
+// registries over HTTPS.
+func (c Client) Pull(ref, version, destDir string) (string, error) {
+	if !IsOCI(ref) {
+		return "", fmt.Errorf("invalid OCI reference %q: must start with %q", ref, Scheme)
+	}
+	if version == "" {
+		return "", fmt.Errorf("invalid OCI reference %q: version must be set", ref)
+	}
+	host, repository, err := splitRef(ref)
+	if err != nil {
+		return "", err
+	}
+
+	credentialsFile := c.CredentialsFile
+	if credentialsFile == "" {
+		credentialsFile = helmpath.ConfigPath("registry.json")
+	}
+	authClient, err := orasdocker.NewClient(credentialsFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read registry credentials: %w", err)
+	}
+	// The context discards the logs of ORAS and containerd.
+	ctx := orascontext.Background()
+	resolver, err := authClient.Resolver(ctx, c.httpClient(), isLoopback(host))
+	if err != nil {
+		return "", err
+	}
+
+	allowedMediaTypes := []string{configMediaType}
+	for mediaType := range chartLayerMediaTypes {
+		allowedMediaTypes = append(allowedMediaTypes, mediaType)
+	}
+	store := orascontent.NewMemoryStore()
+	name := fmt.Sprintf("%s/%s:%s", host, repository, version)
+	_, layers, err := oras.Pull(ctx, resolver, name, store,
+		oras.WithPullEmptyNameAllowed(),
+		oras.WithAllowedMediaTypes(allowedMediaTypes))
+	if err != nil {
+		return "", fmt.Errorf("failed to pull chart %s:%s: %w", ref, version, err)
+	}
+	for _, layer := range layers {
+		if _, ok := chartLayerMediaTypes[layer.MediaType]; !ok {
+			continue
+		}
+		_, content, ok := store.Get(layer)
+		if !ok {
+			return "", fmt.Errorf("failed to get chart for %s:%s: layer %s not found", ref, version, layer.Digest)
+		}
+		archive := filepath.Join(destDir, fmt.Sprintf("%s-%s.tgz", path.Base(repository), version))
+		if err := ioutil.WriteFile(archive, content, 0644); err != nil {
+			return "", err
+		}
+		return archive, nil
+	}
+	return "", fmt.Errorf("manifest for %s:%s does not contain a chart layer", ref, version)
+}
+
+func (c Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func splitRef(ref string) (string, string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(ref, Scheme), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid OCI reference %q: expected %s<host>/<repository>", ref, Scheme)
+	}
+	if strings.ContainsAny(parts[1], ":@") {
+		return "", "", fmt.Errorf("invalid OCI reference %q: tags and digests must be set using the chart version", ref)
+	}
+	return parts[0], parts[1], nil
+}
+
+func isLoopback(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,193 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oci
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const manifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+type manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	Config        descriptor   `json:"config"`
+	Layers        []descriptor `json:"layers"`
+}
+
+func digestOf(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// newRegistry returns a registry serving content as the chart layer of
+// repository at version, and layerContent as the content of its blob. If
+// token is set, the registry requires bearer token authentication.
+func newRegistry(t *testing.T, repository, version string, content, layerContent []byte, token string) *httptest.Server {
+	config := []byte("{}")
+	m, err := json.Marshal(manifest{
+		SchemaVersion: 2,
+		Config:        descriptor{MediaType: configMediaType, Digest: digestOf(config), Size: int64(len(config))},
+		Layers: []descriptor{
+			{MediaType: "application/tar+gzip", Digest: digestOf(content), Size: int64(len(content))},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	var srv *httptest.Server
+	authorized := func(w http.ResponseWriter, r *http.Request) bool {
+		if token == "" || r.Header.Get("Authorization") == "Bearer "+token {
+			return true
+		}
+		w.Header().Set("WWW-Authenticate",
+			fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:%s:pull"`, srv.URL, repository))
+		w.WriteHeader(http.StatusUnauthorized)
+		return false
+	}
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("scope") != fmt.Sprintf("repository:%s:pull", repository) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": token})
+	})
+	serve := func(p, mediaType string, b []byte) {
+		mux.HandleFunc(p, func(w http.ResponseWriter, r *http.Request) {
+			if !authorized(w, r) {
+				return
+			}
+			w.Header().Set("Content-Type", mediaType)
+			w.Header().Set("Content-Length", fmt.Sprint(len(b)))
+			if r.Method != http.MethodHead {
+				_, _ = w.Write(b)
+			}
+		})
+	}
+	serve(fmt.Sprintf("/v2/%s/manifests/%s", repository, version), manifestMediaType, m)
+	serve(fmt.Sprintf("/v2/%s/manifests/%s", repository, digestOf(m)), manifestMediaType, m)
+	serve(fmt.Sprintf("/v2/%s/blobs/%s", repository, digestOf(config)), "application/octet-stream", config)
+	serve(fmt.Sprintf("/v2/%s/blobs/%s", repository, digestOf(content)), "application/octet-stream", layerContent)
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestPull(t *testing.T) {
+	content := []byte("chart content")
+	testCases := []struct {
+		name      string
+		token     string
+		ref       func(host string) string
+		version   string
+		expectErr bool
+	}{
+		{
+			name:    "anonymous",
+			ref:     func(host string) string { return Scheme + host + "/charts/nginx" },
+			version: "1.2.3",
+		},
+		{
+			name:    "bearer token",
+			token:   "secret",
+			ref:     func(host string) string { return Scheme + host + "/charts/nginx" },
+			version: "1.2.3",
+		},
+		{
+			name:      "unknown version",
+			ref:       func(host string) string { return Scheme + host + "/charts/nginx" },
+			version:   "4.5.6",
+			expectErr: true,
+		},
+		{
+			name:      "missing version",
+			ref:       func(host string) string { return Scheme + host + "/charts/nginx" },
+			expectErr: true,
+		},
+		{
+			name:      "tag in reference",
+			ref:       func(host string) string { return Scheme + host + "/charts/nginx:1.2.3" },
+			version:   "1.2.3",
+			expectErr: true,
+		},
+		{
+			name:      "not an OCI reference",
+			ref:       func(host string) string { return "https://" + host + "/charts/nginx" },
+			version:   "1.2.3",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newRegistry(t, "charts/nginx", "1.2.3", content, content, tc.token)
+			dir, err := ioutil.TempDir("", "oci-pull-test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			c := Client{CredentialsFile: filepath.Join(dir, "registry.json")}
+			archive, err := c.Pull(tc.ref(strings.TrimPrefix(srv.URL, "http://")), tc.version, dir)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, filepath.Join(dir, "nginx-1.2.3.tgz"), archive)
+			b, err := ioutil.ReadFile(archive)
+			assert.NoError(t, err)
+			assert.Equal(t, content, b)
+		})
+	}
+}
+
+func TestPullDigestMismatch(t *testing.T) {
+	// Serve different content for the blob than is recorded in the manifest.
+	srv := newRegistry(t, "charts/nginx", "1.2.3", []byte("chart content"), []byte("tampered chart"), "")
+
+	dir, err := ioutil.TempDir("", "oci-pull-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := Client{CredentialsFile: filepath.Join(dir, "registry.json")}
+	_, err = c.Pull(Scheme+strings.TrimPrefix(srv.URL, "http://")+"/charts/nginx", "1.2.3", dir)
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(dir, "nginx-1.2.3.tgz"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -51,15 +51,96 @@ type HelmAppCondition struct {
 }
 
 type HelmAppRelease struct {
-	Name     string `json:"name,omitempty"`
-	Manifest string `json:"manifest,omitempty"`
+	Name      string            `json:"name,omitempty"`
+	Namespace string            `json:"namespace,omitempty"`
+	Manifest  string            `json:"manifest,omitempty"`
+	Resources []HelmAppResource `json:"resources,omitempty"`
 }
 
+// HelmAppRevision is a revision of a release kept in the release storage.
+type HelmAppRevision struct {
+	Revision     int         `json:"revision"`
+	ChartVersion string      `json:"chartVersion,omitempty"`
+	Status       string      `json:"status,omitempty"`
+	Description  string      `json:"description,omitempty"`
+	Updated      metav1.Time `json:"updated,omitempty"`
+}
+
+// HelmAppProgress is a revision of a release that the operator installed or
+// upgraded while waiting for resources, and whose resources have not been
+// ready yet. The wait timeout is measured from its start time.
+type HelmAppProgress struct {
+	Revision  int         `json:"revision"`
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// HelmAppResource is the readiness of a resource in a release.
+type HelmAppResource struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	Ready      bool   `json:"ready"`
+	Message    string `json:"message,omitempty"`
+}
+
+// HelmAppChartRelease is the state of the release of one of the charts
+// composing a custom resource.
+type HelmAppChartRelease struct {
+	Name    string            `json:"name"`
+	State   HelmAppChartState `json:"state"`
+	Message string            `json:"message,omitempty"`
+
+	DeployedRelease *HelmAppRelease  `json:"deployedRelease,omitempty"`
+	Progress        *HelmAppProgress `json:"progress,omitempty"`
+	// DryRunDiff is the manifest diff of the release that would be installed
+	// or upgraded, set while the custom resource has the dry-run annotation.
+	DryRunDiff string `json:"dryRunDiff,omitempty"`
+}
+
+type HelmAppChartState string
+
+const (
+	// ChartPending charts are waiting for the charts they depend on.
+	ChartPending HelmAppChartState = "Pending"
+	// ChartDeployed charts have a deployed release whose resources are not
+	// all ready.
+	ChartDeployed HelmAppChartState = "Deployed"
+	// ChartReady charts have a deployed release whose resources are ready.
+	ChartReady HelmAppChartState = "Ready"
+	// ChartFailed charts could not be installed, upgraded or reconciled.
+	ChartFailed HelmAppChartState = "Failed"
+)
+
+// HelmAppDrift records a resource from the deployed release that had drifted
+// from the release manifest and the correction that was applied to it.
+type HelmAppDrift struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	Action     string `json:"action"`
+	Patch      string `json:"patch,omitempty"`
+	PatchType  string `json:"patchType,omitempty"`
+
+	DetectedTime metav1.Time `json:"detectedTime,omitempty"`
+}
+
+// MaxDriftHistory is the maximum number of drift records kept in a
+// HelmAppStatus. Older records are discarded first.
+const MaxDriftHistory = 10
+
 const (
 	ConditionInitialized    HelmAppConditionType = "Initialized"
 	ConditionDeployed       HelmAppConditionType = "Deployed"
 	ConditionReleaseFailed  HelmAppConditionType = "ReleaseFailed"
 	ConditionIrreconcilable HelmAppConditionType = "Irreconcilable"
+	ConditionDrifted        HelmAppConditionType = "Drifted"
+	ConditionReady          HelmAppConditionType = "Ready"
+	ConditionTested         HelmAppConditionType = "Tested"
+	ConditionConflicted     HelmAppConditionType = "Conflicted"
+	ConditionPaused         HelmAppConditionType = "Paused"
+	ConditionProgressing    HelmAppConditionType = "Progressing"
 
 	StatusTrue    ConditionStatus = "True"
 	StatusFalse   ConditionStatus = "False"
@@ -72,11 +153,46 @@ const (
 	ReasonUpgradeError        HelmAppConditionReason = "UpgradeError"
 	ReasonReconcileError      HelmAppConditionReason = "ReconcileError"
 	ReasonUninstallError      HelmAppConditionReason = "UninstallError"
+	ReasonDriftCorrected      HelmAppConditionReason = "DriftCorrected"
+	ReasonDryRunError         HelmAppConditionReason = "DryRunError"
+	ReasonRolledBack          HelmAppConditionReason = "RolledBack"
+	ReasonRollbackError       HelmAppConditionReason = "RollbackError"
+	ReasonValuesFromError     HelmAppConditionReason = "ValuesFromError"
+	ReasonResourcesReady      HelmAppConditionReason = "ResourcesReady"
+	ReasonResourcesNotReady   HelmAppConditionReason = "ResourcesNotReady"
+	ReasonReadinessError      HelmAppConditionReason = "ReadinessError"
+	ReasonTestsPassed         HelmAppConditionReason = "TestsPassed"
+	ReasonTestsFailed         HelmAppConditionReason = "TestsFailed"
+	ReasonTestError           HelmAppConditionReason = "TestError"
+	ReasonTestsRunning        HelmAppConditionReason = "TestsRunning"
+	ReasonTestsNotRun         HelmAppConditionReason = "TestsNotRun"
+	ReasonFieldConflict       HelmAppConditionReason = "FieldConflict"
+	ReasonReconcilePaused     HelmAppConditionReason = "ReconcilePaused"
+	ReasonDependenciesPending HelmAppConditionReason = "DependenciesPending"
+	ReasonWaitingForResources HelmAppConditionReason = "WaitingForResources"
+	ReasonWaitTimeout         HelmAppConditionReason = "WaitTimeout"
+	ReasonRevisionPinned      HelmAppConditionReason = "RevisionPinned"
+	ReasonUnsupported         HelmAppConditionReason = "Unsupported"
 )
 
 type HelmAppStatus struct {
 	Conditions      []HelmAppCondition `json:"conditions"`
 	DeployedRelease *HelmAppRelease    `json:"deployedRelease,omitempty"`
+	DriftHistory    []HelmAppDrift     `json:"driftHistory,omitempty"`
+	DryRunDiff      string             `json:"dryRunDiff,omitempty"`
+
+	// Progress is the revision of the release whose resources the operator
+	// is waiting for, if any.
+	Progress *HelmAppProgress `json:"progress,omitempty"`
+
+	// History lists the revisions of the release kept in the release
+	// storage, oldest first.
+	History []HelmAppRevision `json:"history,omitempty"`
+
+	// ChartReleases are the releases of the charts composing the custom
+	// resource, in installation order. They are only set for watches with
+	// several charts, which have no DeployedRelease.
+	ChartReleases []HelmAppChartRelease `json:"chartReleases,omitempty"`
 }
 
 func (s *HelmAppStatus) ToMap() (map[string]interface{}, error) {
@@ -129,6 +245,44 @@ func (s *HelmAppStatus) RemoveCondition(conditionType HelmAppConditionType) *Hel
 	return s
 }
 
+// AddDrift appends drift records to the status object's drift history,
+// discarding the oldest records so that at most MaxDriftHistory are kept.
+// AddDrift does not update the resource in the cluster.
+func (s *HelmAppStatus) AddDrift(drifts ...HelmAppDrift) *HelmAppStatus {
+	s.DriftHistory = append(s.DriftHistory, drifts...)
+	if n := len(s.DriftHistory); n > MaxDriftHistory {
+		s.DriftHistory = append([]HelmAppDrift(nil), s.DriftHistory[n-MaxDriftHistory:]...)
+	}
+	return s
+}
+
+// SetChartRelease sets the state of a chart release on the status object,
+// replacing the state of the release of the same chart if it exists.
+// SetChartRelease does not update the resource in the cluster.
+func (s *HelmAppStatus) SetChartRelease(chartRelease HelmAppChartRelease) *HelmAppStatus {
+	for i := range s.ChartReleases {
+		if s.ChartReleases[i].Name == chartRelease.Name {
+			s.ChartReleases[i] = chartRelease
+			return s
+		}
+	}
+	s.ChartReleases = append(s.ChartReleases, chartRelease)
+	return s
+}
+
+// RemoveChartRelease removes the state of the release of the named chart
+// from the status object. RemoveChartRelease does not update the resource in
+// the cluster.
+func (s *HelmAppStatus) RemoveChartRelease(name string) *HelmAppStatus {
+	for i := range s.ChartReleases {
+		if s.ChartReleases[i].Name == name {
+			s.ChartReleases = append(s.ChartReleases[:i], s.ChartReleases[i+1:]...)
+			return s
+		}
+	}
+	return s
+}
+
 // StatusFor safely returns a typed status block from a custom resource.
 func StatusFor(cr *unstructured.Unstructured) *HelmAppStatus {
 	switch s := cr.Object["status"].(type) {
@@ -15,6 +15,7 @@
 package types
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -59,6 +60,40 @@ func TestRemoveCondition(t *testing.T) {
 	assert.Empty(t, actual.Conditions)
 }
 
+func TestAddDrift(t *testing.T) {
+	status := newTestStatus()
+	for i := 0; i < MaxDriftHistory+2; i++ {
+		status.AddDrift(HelmAppDrift{Kind: "Deployment", Name: fmt.Sprintf("drift-%d", i)})
+	}
+	assert.Len(t, status.DriftHistory, MaxDriftHistory)
+	assert.Equal(t, "drift-2", status.DriftHistory[0].Name)
+	assert.Equal(t, fmt.Sprintf("drift-%d", MaxDriftHistory+1), status.DriftHistory[MaxDriftHistory-1].Name)
+
+	newStatus, err := status.ToMap()
+	assert.NoError(t, err)
+
+	resource := newTestResource()
+	resource.Object["status"] = newStatus
+	actual := StatusFor(resource)
+
+	assert.Equal(t, status.DriftHistory, actual.DriftHistory)
+}
+
+func TestSetChartRelease(t *testing.T) {
+	status := newTestStatus()
+	status.SetChartRelease(HelmAppChartRelease{Name: "crds", State: ChartReady})
+	status.SetChartRelease(HelmAppChartRelease{Name: "core", State: ChartPending})
+	status.SetChartRelease(HelmAppChartRelease{Name: "core", State: ChartDeployed})
+	assert.Equal(t, []HelmAppChartRelease{
+		{Name: "crds", State: ChartReady},
+		{Name: "core", State: ChartDeployed},
+	}, status.ChartReleases)
+
+	status.RemoveChartRelease("crds")
+	status.RemoveChartRelease("addons")
+	assert.Equal(t, []HelmAppChartRelease{{Name: "core", State: ChartDeployed}}, status.ChartReleases)
+}
+
 func TestStatusForEmpty(t *testing.T) {
 	status := StatusFor(newTestResource())
 
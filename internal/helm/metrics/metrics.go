@@ -15,7 +15,12 @@
 package metrics
 
 import (
+	"fmt"
+	"sync"
+
 	"github.com/prometheus/client_golang/prometheus"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	sdkVersion "github.com/operator-framework/operator-sdk/internal/version"
 )
@@ -24,6 +29,20 @@ const (
 	subsystem = "helm_operator"
 )
 
+// Release states.
+const (
+	ReleaseDeployed = "deployed"
+	ReleaseFailed   = "failed"
+)
+
+// Release operations.
+const (
+	OperationInstall   = "install"
+	OperationUpgrade   = "upgrade"
+	OperationReconcile = "reconcile"
+	OperationUninstall = "uninstall"
+)
+
 var (
 	buildInfo = prometheus.NewGauge(
 		prometheus.GaugeOpts{
@@ -36,9 +55,188 @@ var (
 			},
 		},
 	)
+
+	driftCorrections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "drift_corrections_total",
+			Help:      "Number of release resources that drifted from the release manifest and were corrected.",
+		},
+		[]string{
+			"GVK",
+			"resource_GVK",
+		},
+	)
+
+	releases = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "releases",
+			Help:      "Number of releases by state.",
+		},
+		[]string{
+			"GVK",
+			"state",
+		},
+	)
+
+	releaseRevisions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      "release_revision",
+			Help:      "Revision of the deployed release of a custom resource.",
+		},
+		[]string{
+			"GVK",
+			"namespace",
+			"release",
+		},
+	)
+
+	releaseOperations = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      "release_operation_duration_seconds",
+			Help:      "How long in seconds release operations take.",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+		},
+		[]string{
+			"GVK",
+			"operation",
+		},
+	)
+
+	chartCacheResults = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "chart_cache_requests_total",
+			Help:      "Number of chart cache lookups and whether they hit or missed the cache.",
+		},
+		[]string{
+			"chart_dir",
+			"result",
+		},
+	)
+)
+
+// releaseStates are the last recorded states of releases by GVK and by
+// namespace and name, from which the releases gauge is computed.
+var (
+	releaseStatesMu sync.Mutex
+	releaseStates   = map[string]map[string]string{}
 )
 
+func init() {
+	metrics.Registry.MustRegister(driftCorrections)
+	metrics.Registry.MustRegister(releases)
+	metrics.Registry.MustRegister(releaseRevisions)
+	metrics.Registry.MustRegister(releaseOperations)
+	metrics.Registry.MustRegister(chartCacheResults)
+}
+
+// We will never want to panic our app because of metric saving.
+// Therefore, we will recover our panics here and error log them
+// for later diagnosis but will never fail the app.
+func recoverMetricPanic() {
+	if r := recover(); r != nil {
+		logf.Log.WithName("metrics").Error(fmt.Errorf("%v", r),
+			"Recovering from metric function")
+	}
+}
+
 func RegisterBuildInfo(r prometheus.Registerer) {
 	buildInfo.Set(1)
 	r.MustRegister(buildInfo)
 }
+
+// DriftCorrected records that a resource of kind resourceGVK, deployed by a
+// release for a custom resource of kind gvk, was corrected.
+func DriftCorrected(gvk, resourceGVK string) {
+	defer recoverMetricPanic()
+	driftCorrections.WithLabelValues(gvk, resourceGVK).Inc()
+}
+
+// ReleaseState records the state of the release of a custom resource of kind
+// gvk in namespace, and its revision if it is deployed.
+func ReleaseState(gvk, namespace, release, state string, revision int) {
+	defer recoverMetricPanic()
+	if state == ReleaseDeployed {
+		releaseRevisions.WithLabelValues(gvk, namespace, release).Set(float64(revision))
+	}
+
+	releaseStatesMu.Lock()
+	defer releaseStatesMu.Unlock()
+	states, ok := releaseStates[gvk]
+	if !ok {
+		states = map[string]string{}
+		releaseStates[gvk] = states
+	}
+	key := namespace + "/" + release
+	previous, ok := states[key]
+	if ok && previous == state {
+		return
+	}
+	states[key] = state
+	if ok {
+		setReleaseCount(gvk, previous)
+	}
+	setReleaseCount(gvk, state)
+}
+
+// ReleaseUninstalled removes the release of a custom resource of kind gvk in
+// namespace from the release metrics.
+func ReleaseUninstalled(gvk, namespace, release string) {
+	defer recoverMetricPanic()
+	releaseRevisions.DeleteLabelValues(gvk, namespace, release)
+
+	releaseStatesMu.Lock()
+	defer releaseStatesMu.Unlock()
+	key := namespace + "/" + release
+	previous, ok := releaseStates[gvk][key]
+	if !ok {
+		return
+	}
+	delete(releaseStates[gvk], key)
+	if len(releaseStates[gvk]) == 0 {
+		delete(releaseStates, gvk)
+	}
+	setReleaseCount(gvk, previous)
+}
+
+// setReleaseCount sets the releases gauge of gvk and state to the number of
+// releases in state, and removes it when there are none so that states no
+// release is in are not exported. releaseStatesMu must be held.
+func setReleaseCount(gvk, state string) {
+	count := 0
+	for _, s := range releaseStates[gvk] {
+		if s == state {
+			count++
+		}
+	}
+	if count == 0 {
+		releases.DeleteLabelValues(gvk, state)
+		return
+	}
+	releases.WithLabelValues(gvk, state).Set(float64(count))
+}
+
+// ReleaseOperationTimer returns a timer that records the duration of a
+// release operation for a custom resource of kind gvk when it is stopped.
+func ReleaseOperationTimer(gvk, operation string) *prometheus.Timer {
+	defer recoverMetricPanic()
+	return prometheus.NewTimer(prometheus.ObserverFunc(func(duration float64) {
+		releaseOperations.WithLabelValues(gvk, operation).Observe(duration)
+	}))
+}
+
+// ChartCacheHit records a chart lookup that was served from the cache.
+func ChartCacheHit(chartDir string) {
+	defer recoverMetricPanic()
+	chartCacheResults.WithLabelValues(chartDir, "hit").Inc()
+}
+
+// ChartCacheMiss records a chart lookup that required loading the chart.
+func ChartCacheMiss(chartDir string) {
+	defer recoverMetricPanic()
+	chartCacheResults.WithLabelValues(chartDir, "miss").Inc()
+}
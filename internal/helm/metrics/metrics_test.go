@@ -0,0 +1,114 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const testGVK = "example.com/v1, Kind=Test"
+
+// resetReleaseMetrics clears the release metrics recorded by other tests.
+func resetReleaseMetrics() {
+	releaseStatesMu.Lock()
+	defer releaseStatesMu.Unlock()
+	releaseStates = map[string]map[string]string{}
+	releases.Reset()
+	releaseRevisions.Reset()
+}
+
+// assertReleaseMetrics checks the releases and release_revision metrics in
+// the registry of the manager against expected, in the text format.
+func assertReleaseMetrics(t *testing.T, expected string) {
+	header := `# HELP helm_operator_release_revision Revision of the deployed release of a custom resource.
+# TYPE helm_operator_release_revision gauge
+# HELP helm_operator_releases Number of releases by state.
+# TYPE helm_operator_releases gauge
+`
+	if expected == "" {
+		header = ""
+	}
+	err := testutil.GatherAndCompare(metrics.Registry, strings.NewReader(header+expected),
+		"helm_operator_releases", "helm_operator_release_revision")
+	assert.NoError(t, err)
+}
+
+func TestReleaseState(t *testing.T) {
+	resetReleaseMetrics()
+
+	ReleaseState(testGVK, "ns", "a", ReleaseDeployed, 1)
+	ReleaseState(testGVK, "ns", "b", ReleaseDeployed, 3)
+	ReleaseState(testGVK, "ns", "a", ReleaseDeployed, 2)
+	assertReleaseMetrics(t, `helm_operator_release_revision{GVK="example.com/v1, Kind=Test",namespace="ns",release="a"} 2
+helm_operator_release_revision{GVK="example.com/v1, Kind=Test",namespace="ns",release="b"} 3
+helm_operator_releases{GVK="example.com/v1, Kind=Test",state="deployed"} 2
+`)
+
+	// A failed upgrade keeps the revision of the deployed release.
+	ReleaseState(testGVK, "ns", "a", ReleaseFailed, 0)
+	assertReleaseMetrics(t, `helm_operator_release_revision{GVK="example.com/v1, Kind=Test",namespace="ns",release="a"} 2
+helm_operator_release_revision{GVK="example.com/v1, Kind=Test",namespace="ns",release="b"} 3
+helm_operator_releases{GVK="example.com/v1, Kind=Test",state="deployed"} 1
+helm_operator_releases{GVK="example.com/v1, Kind=Test",state="failed"} 1
+`)
+
+	// States that no release is in anymore are not exported.
+	ReleaseState(testGVK, "ns", "a", ReleaseDeployed, 3)
+	assertReleaseMetrics(t, `helm_operator_release_revision{GVK="example.com/v1, Kind=Test",namespace="ns",release="a"} 3
+helm_operator_release_revision{GVK="example.com/v1, Kind=Test",namespace="ns",release="b"} 3
+helm_operator_releases{GVK="example.com/v1, Kind=Test",state="deployed"} 2
+`)
+}
+
+func TestReleaseUninstalled(t *testing.T) {
+	resetReleaseMetrics()
+
+	ReleaseState(testGVK, "ns", "a", ReleaseDeployed, 1)
+	ReleaseState(testGVK, "ns", "b", ReleaseFailed, 0)
+	ReleaseUninstalled(testGVK, "ns", "a")
+	assertReleaseMetrics(t, `helm_operator_releases{GVK="example.com/v1, Kind=Test",state="failed"} 1
+`)
+
+	ReleaseUninstalled(testGVK, "ns", "b")
+	assertReleaseMetrics(t, "")
+	assert.Empty(t, releaseStates)
+
+	// Uninstalling an unknown release is a no-op.
+	ReleaseUninstalled(testGVK, "ns", "c")
+	assertReleaseMetrics(t, "")
+}
+
+func TestReleaseOperationTimer(t *testing.T) {
+	releaseOperations.Reset()
+
+	ReleaseOperationTimer(testGVK, OperationInstall).ObserveDuration()
+	ReleaseOperationTimer(testGVK, OperationInstall).ObserveDuration()
+	assert.Equal(t, 1, testutil.CollectAndCount(releaseOperations))
+}
+
+func TestDriftCorrected(t *testing.T) {
+	driftCorrections.Reset()
+
+	DriftCorrected(testGVK, "apps/v1, Kind=Deployment")
+	DriftCorrected(testGVK, "apps/v1, Kind=Deployment")
+	DriftCorrected(testGVK, "/v1, Kind=Service")
+	assert.Equal(t, 2.0, testutil.ToFloat64(driftCorrections.WithLabelValues(testGVK, "apps/v1, Kind=Deployment")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(driftCorrections.WithLabelValues(testGVK, "/v1, Kind=Service")))
+}
@@ -0,0 +1,186 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/kube"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// FieldManager is the field manager used to apply release resources in
+// server-side apply mode.
+const FieldManager = "helm-operator"
+
+// Conflict describes a release resource with fields that are managed by
+// other field managers, and that server-side apply did not overwrite.
+type Conflict struct {
+	GroupVersionKind schema.GroupVersionKind
+	Namespace        string
+	Name             string
+	// Message is the conflict message returned by the API server, which
+	// lists the conflicting fields and their managers.
+	Message string
+}
+
+// ConflictError is returned when release resources could not be applied
+// because of field conflicts.
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	msgs := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		msgs = append(msgs, fmt.Sprintf("%s %s: %s", c.GroupVersionKind.Kind, c.Name, c.Message))
+	}
+	return fmt.Sprintf("field conflicts in %d resource(s): %s", len(e.Conflicts), strings.Join(msgs, "; "))
+}
+
+// applyRelease server-side applies every resource in expectedManifest with
+// FieldManager. Resources that were missing or whose fields were changed by
+// the apply are returned as drifts. Applies that only change the managers of
+// fields, like the first apply after an install or upgrade, are not drifts.
+func applyRelease(_ context.Context, kubeClient kube.Interface, expectedManifest string) ([]Drift, error) {
+	expectedInfos, err := kubeClient.Build(bytes.NewBufferString(expectedManifest), false)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		drifts    []Drift
+		conflicts []Conflict
+	)
+	force := false
+	err = expectedInfos.Visit(func(expected *resource.Info, err error) error {
+		if err != nil {
+			return fmt.Errorf("visit error: %w", err)
+		}
+
+		drift := Drift{
+			GroupVersionKind: expected.Object.GetObjectKind().GroupVersionKind(),
+			Namespace:        expected.Namespace,
+			Name:             expected.Name,
+			PatchType:        apitypes.ApplyPatchType,
+		}
+
+		helper := resource.NewHelper(expected.Client, expected.Mapping).WithFieldManager(FieldManager)
+		existing, err := helper.Get(expected.Namespace, expected.Name)
+		switch {
+		case apierrors.IsNotFound(err):
+			drift.Action = DriftActionCreated
+		case err != nil:
+			return fmt.Errorf("could not get object: %w", err)
+		}
+
+		data, err := json.Marshal(expected.Object)
+		if err != nil {
+			return fmt.Errorf("could not marshal object: %w", err)
+		}
+		applied, err := helper.Patch(expected.Namespace, expected.Name, apitypes.ApplyPatchType, data,
+			&metav1.PatchOptions{Force: &force})
+		if apierrors.IsConflict(err) {
+			conflicts = append(conflicts, Conflict{
+				GroupVersionKind: drift.GroupVersionKind,
+				Namespace:        drift.Namespace,
+				Name:             drift.Name,
+				Message:          err.Error(),
+			})
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("apply error: %w", err)
+		}
+
+		if drift.Action == "" {
+			modified, err := appliedChanges(existing, applied)
+			if err != nil {
+				return err
+			}
+			if !modified {
+				return nil
+			}
+			drift.Action = DriftActionPatched
+		}
+		drifts = append(drifts, drift)
+		return nil
+	})
+	if err != nil {
+		return drifts, err
+	}
+	if len(conflicts) > 0 {
+		return drifts, &ConflictError{Conflicts: conflicts}
+	}
+	return drifts, nil
+}
+
+// appliedChanges reports whether applying a resource changed it from existing
+// to applied. Changes to the managers of its fields also change its resource
+// version, so both are ignored.
+func appliedChanges(existing, applied runtime.Object) (bool, error) {
+	existingVersion, err := resourceVersion(existing)
+	if err != nil {
+		return false, err
+	}
+	appliedVersion, err := resourceVersion(applied)
+	if err != nil {
+		return false, err
+	}
+	// The resource version only changes if the apply modified the resource.
+	if appliedVersion == existingVersion {
+		return false, nil
+	}
+
+	existingFields, err := withoutFieldManagers(existing)
+	if err != nil {
+		return false, err
+	}
+	appliedFields, err := withoutFieldManagers(applied)
+	if err != nil {
+		return false, err
+	}
+	return !equality.Semantic.DeepEqual(existingFields, appliedFields), nil
+}
+
+// withoutFieldManagers returns the fields of obj, without its managed fields
+// and resource version.
+func withoutFieldManagers(obj runtime.Object) (map[string]interface{}, error) {
+	fields, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, fmt.Errorf("could not convert object: %w", err)
+	}
+	unstructured.RemoveNestedField(fields, "metadata", "managedFields")
+	unstructured.RemoveNestedField(fields, "metadata", "resourceVersion")
+	return fields, nil
+}
+
+func resourceVersion(obj interface{}) (string, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "", fmt.Errorf("could not access object metadata: %w", err)
+	}
+	return accessor.GetResourceVersion(), nil
+}
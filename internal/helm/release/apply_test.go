@@ -0,0 +1,217 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"helm.sh/helm/v3/pkg/kube"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/resource"
+	"k8s.io/client-go/kubernetes/scheme"
+	restfake "k8s.io/client-go/rest/fake"
+)
+
+func TestConflictError(t *testing.T) {
+	err := fmt.Errorf("failed to reconcile release: %w", &ConflictError{Conflicts: []Conflict{
+		{
+			GroupVersionKind: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+			Namespace:        "ns",
+			Name:             "web",
+			Message:          `Apply failed with 1 conflict: conflict with "kube-controller-manager": .spec.replicas`,
+		},
+		{
+			GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "Service"},
+			Namespace:        "ns",
+			Name:             "web",
+			Message:          `Apply failed with 1 conflict: conflict with "kubectl": .spec.type`,
+		},
+	}})
+
+	var conflictErr *ConflictError
+	assert.True(t, errors.As(err, &conflictErr))
+	assert.Len(t, conflictErr.Conflicts, 2)
+	assert.Equal(t, "field conflicts in 2 resource(s): "+
+		`Deployment web: Apply failed with 1 conflict: conflict with "kube-controller-manager": .spec.replicas; `+
+		`Service web: Apply failed with 1 conflict: conflict with "kubectl": .spec.type`, conflictErr.Error())
+}
+
+// buildKubeClient is a kube.Interface that builds the same resources from
+// any manifest.
+type buildKubeClient struct {
+	kube.Interface
+	resources kube.ResourceList
+}
+
+func (c buildKubeClient) Build(io.Reader, bool) (kube.ResourceList, error) {
+	return c.resources, nil
+}
+
+// fakeConfigMapServer serves the config maps of the namespace "ns" like the
+// API server does for server-side apply. Applying a config map whose
+// "conflict" key is set fails with a conflict. Applying one increments its
+// resource version if it changes its data, or if its fields are not managed
+// by FieldManager yet, which only changes its managed fields.
+type fakeConfigMapServer struct {
+	existing map[string]map[string]string
+	managed  map[string]bool
+	patches  []*http.Request
+}
+
+func (s *fakeConfigMapServer) serve(req *http.Request) (*http.Response, error) {
+	name := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+	gr := schema.GroupResource{Resource: "configmaps"}
+	switch req.Method {
+	case http.MethodGet:
+		data, ok := s.existing[name]
+		if !ok {
+			return s.respondError(apierrors.NewNotFound(gr, name))
+		}
+		return s.respond(http.StatusOK, s.configMap(name, "1", data, s.managed[name]))
+	case http.MethodPatch:
+		s.patches = append(s.patches, req)
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		applied := &corev1.ConfigMap{}
+		if err := runtime.DecodeInto(scheme.Codecs.UniversalDecoder(), body, applied); err != nil {
+			return nil, err
+		}
+		if _, ok := applied.Data["conflict"]; ok {
+			msg := `Apply failed with 1 conflict: conflict with "kubectl": .data.conflict`
+			return s.respondError(apierrors.NewConflict(gr, name, errors.New(msg)))
+		}
+		version := "1"
+		if data, ok := s.existing[name]; !ok || !reflect.DeepEqual(data, applied.Data) || !s.managed[name] {
+			version = "2"
+		}
+		return s.respond(http.StatusOK, s.configMap(name, version, applied.Data, true))
+	}
+	return nil, fmt.Errorf("unexpected request %s %s", req.Method, req.URL)
+}
+
+func (s *fakeConfigMapServer) respondError(err *apierrors.StatusError) (*http.Response, error) {
+	status := err.ErrStatus
+	return s.respond(int(status.Code), &status)
+}
+
+func (s *fakeConfigMapServer) respond(code int, obj runtime.Object) (*http.Response, error) {
+	body, err := runtime.Encode(scheme.Codecs.LegacyCodec(corev1.SchemeGroupVersion), obj)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{"Content-Type": []string{runtime.ContentTypeJSON}}
+	return &http.Response{StatusCode: code, Header: header, Body: ioutil.NopCloser(bytes.NewReader(body))}, nil
+}
+
+// configMap returns the config map name, whose fields are managed by
+// FieldManager if managed is set, or by Helm otherwise.
+func (s *fakeConfigMapServer) configMap(name, resourceVersion string, data map[string]string, managed bool) *corev1.ConfigMap {
+	cm := newTestConfigMap(name, resourceVersion, data)
+	manager := "helm"
+	if managed {
+		manager = FieldManager
+	}
+	cm.ManagedFields = []metav1.ManagedFieldsEntry{{Manager: manager, Operation: metav1.ManagedFieldsOperationApply}}
+	return cm
+}
+
+func newTestConfigMap(name, resourceVersion string, data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, ResourceVersion: resourceVersion},
+		Data:       data,
+	}
+}
+
+func TestApplyRelease(t *testing.T) {
+	server := &fakeConfigMapServer{
+		existing: map[string]map[string]string{
+			"unchanged":  {"key": "value"},
+			"adopted":    {"key": "value"},
+			"changed":    {"key": "drifted"},
+			"conflicted": {"key": "value"},
+		},
+		managed: map[string]bool{"unchanged": true, "changed": true, "conflicted": true},
+	}
+	client := &restfake.RESTClient{
+		NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		GroupVersion:         corev1.SchemeGroupVersion,
+		Client:               restfake.CreateHTTPClient(server.serve),
+	}
+	mapping := &meta.RESTMapping{
+		Resource:         corev1.SchemeGroupVersion.WithResource("configmaps"),
+		GroupVersionKind: corev1.SchemeGroupVersion.WithKind("ConfigMap"),
+		Scope:            meta.RESTScopeNamespace,
+	}
+	var resources kube.ResourceList
+	for name, data := range map[string]map[string]string{
+		"created":    {"key": "value"},
+		"unchanged":  {"key": "value"},
+		"adopted":    {"key": "value"},
+		"changed":    {"key": "value"},
+		"conflicted": {"conflict": "value"},
+	} {
+		resources = append(resources, &resource.Info{
+			Client:    client,
+			Mapping:   mapping,
+			Namespace: "ns",
+			Name:      name,
+			Object:    newTestConfigMap(name, "", data),
+		})
+	}
+
+	drifts, err := applyRelease(context.TODO(), buildKubeClient{resources: resources}, "")
+
+	gvk := corev1.SchemeGroupVersion.WithKind("ConfigMap")
+	assert.ElementsMatch(t, []Drift{
+		{GroupVersionKind: gvk, Namespace: "ns", Name: "created", Action: DriftActionCreated, PatchType: apitypes.ApplyPatchType},
+		{GroupVersionKind: gvk, Namespace: "ns", Name: "changed", Action: DriftActionPatched, PatchType: apitypes.ApplyPatchType},
+	}, drifts)
+
+	var conflictErr *ConflictError
+	if assert.True(t, errors.As(err, &conflictErr)) && assert.Len(t, conflictErr.Conflicts, 1) {
+		conflict := conflictErr.Conflicts[0]
+		assert.Equal(t, "conflicted", conflict.Name)
+		assert.Equal(t, gvk, conflict.GroupVersionKind)
+		assert.Contains(t, conflict.Message, `conflict with "kubectl": .data.conflict`)
+	}
+
+	// Every resource is applied, without forcing conflicts. Taking over the
+	// fields of a resource from Helm is not a drift.
+	assert.Len(t, server.patches, 5)
+	for _, req := range server.patches {
+		assert.Equal(t, string(apitypes.ApplyPatchType), req.Header.Get("Content-Type"))
+		assert.Equal(t, FieldManager, req.URL.Query().Get("fieldManager"))
+		assert.Equal(t, "false", req.URL.Query().Get("force"))
+	}
+}
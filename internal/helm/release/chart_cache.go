@@ -0,0 +1,167 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	cpb "helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/metrics"
+)
+
+// chartCache is a concurrency-safe cache of a chart loaded from a chart
+// directory. The chart is only reloaded when the digest of the chart
+// directory changes.
+type chartCache struct {
+	chartDir string
+
+	mu     sync.RWMutex
+	digest string
+	chart  *cpb.Chart
+}
+
+func newChartCache(chartDir string) *chartCache {
+	return &chartCache{chartDir: chartDir}
+}
+
+// Get returns a copy of the chart in the cache's chart directory, loading it
+// first if it has not been loaded yet or if any file in the chart directory
+// has changed since it was last loaded.
+//
+// Helm mutates the dependencies and values of the charts it renders, so each
+// caller receives its own copy of the cached chart.
+func (c *chartCache) Get() (*cpb.Chart, error) {
+	digest, err := dirDigest(c.chartDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute chart dir digest: %w", err)
+	}
+
+	c.mu.RLock()
+	if c.chart != nil && c.digest == digest {
+		chrt := copyChart(c.chart)
+		c.mu.RUnlock()
+		metrics.ChartCacheHit(c.chartDir)
+		return chrt, nil
+	}
+	c.mu.RUnlock()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Another caller may have loaded the chart while we were waiting for the lock.
+	if c.chart != nil && c.digest == digest {
+		metrics.ChartCacheHit(c.chartDir)
+		return copyChart(c.chart), nil
+	}
+
+	metrics.ChartCacheMiss(c.chartDir)
+	chrt, err := loader.LoadDir(c.chartDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chart dir: %w", err)
+	}
+	c.chart, c.digest = chrt, digest
+	return copyChart(chrt), nil
+}
+
+// dirDigest returns a digest of the path, size, mode and modification time
+// of every file under dir. It is much cheaper to compute than loading the
+// chart, and changes whenever a file in the chart is added, removed or
+// modified.
+func dirDigest(dir string) (string, error) {
+	h := sha256.New()
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// Follow symlinks, as the chart loader does.
+		if info.Mode()&os.ModeSymlink != 0 {
+			if info, err = os.Stat(path); err != nil {
+				return err
+			}
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(h, "%s\x00%d\x00%s\x00%d\n", rel, info.Size(), info.Mode(), info.ModTime().UnixNano())
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// copyChart returns a copy of c and its dependencies that can be safely
+// passed to Helm actions. Chart templates and files are never modified by
+// Helm, so they are shared with the original chart.
+func copyChart(c *cpb.Chart) *cpb.Chart {
+	if c == nil {
+		return nil
+	}
+	out := *c
+	if c.Metadata != nil {
+		md := *c.Metadata
+		if c.Metadata.Dependencies != nil {
+			md.Dependencies = make([]*cpb.Dependency, len(c.Metadata.Dependencies))
+			for i, d := range c.Metadata.Dependencies {
+				dep := *d
+				md.Dependencies[i] = &dep
+			}
+		}
+		out.Metadata = &md
+	}
+	out.Values = copyValues(c.Values)
+
+	deps := make([]*cpb.Chart, 0, len(c.Dependencies()))
+	for _, dep := range c.Dependencies() {
+		deps = append(deps, copyChart(dep))
+	}
+	out.SetDependencies(deps...)
+	return &out
+}
+
+func copyValues(in map[string]interface{}) map[string]interface{} {
+	if in == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(in))
+	for k, v := range in {
+		out[k] = copyValue(v)
+	}
+	return out
+}
+
+func copyValue(in interface{}) interface{} {
+	switch v := in.(type) {
+	case map[string]interface{}:
+		return copyValues(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i := range v {
+			out[i] = copyValue(v[i])
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"helm.sh/helm/v3/pkg/chart/loader"
+)
+
+const testChartDir = "../../../internal/plugins/helm/v1/chartutil/testdata/test-chart"
+
+// copyTestChart copies the test chart into a temporary directory so that
+// tests can modify it.
+func copyTestChart(t testing.TB) string {
+	dir, err := ioutil.TempDir("", "chart-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	err = filepath.Walk(testChartDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(testChartDir, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dir, rel), info.Mode())
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(dir, rel), b, info.Mode())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestChartCacheGet(t *testing.T) {
+	dir := copyTestChart(t)
+	c := newChartCache(dir)
+
+	first, err := c.Get()
+	assert.NoError(t, err)
+	digest := c.digest
+
+	// Modifying the returned chart must not modify the cached chart.
+	first.Values["replicaCount"] = 100
+	first.Metadata.Version = "9.9.9"
+
+	second, err := c.Get()
+	assert.NoError(t, err)
+	assert.Equal(t, digest, c.digest)
+	assert.NotEqual(t, 100, second.Values["replicaCount"])
+	assert.NotEqual(t, "9.9.9", second.Metadata.Version)
+
+	// Changing a file in the chart directory must reload the chart.
+	chartYAML := filepath.Join(dir, "Chart.yaml")
+	b, err := ioutil.ReadFile(chartYAML)
+	assert.NoError(t, err)
+	b = append(b, []byte("\nkeywords:\n- cached\n")...)
+	assert.NoError(t, ioutil.WriteFile(chartYAML, b, 0644))
+	future := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(chartYAML, future, future))
+
+	third, err := c.Get()
+	assert.NoError(t, err)
+	assert.NotEqual(t, digest, c.digest)
+	assert.Equal(t, []string{"cached"}, third.Metadata.Keywords)
+}
+
+func TestChartCacheGetConcurrent(t *testing.T) {
+	c := newChartCache(copyTestChart(t))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			chrt, err := c.Get()
+			assert.NoError(t, err)
+			chrt.Values["replicaCount"] = 2
+		}()
+	}
+	wg.Wait()
+}
+
+func TestChartCacheGetInvalidDir(t *testing.T) {
+	_, err := newChartCache("does-not-exist").Get()
+	assert.Error(t, err)
+}
+
+func BenchmarkChartLoadDir(b *testing.B) {
+	dir := copyTestChart(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := loader.LoadDir(dir); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkChartCacheGet(b *testing.B) {
+	c := newChartCache(copyTestChart(b))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Get(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -21,11 +21,13 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	jsonpatch "gomodules.xyz/jsonpatch/v3"
 	"helm.sh/helm/v3/pkg/action"
 	cpb "helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/postrender"
 	rpb "helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/releaseutil"
 	"helm.sh/helm/v3/pkg/storage"
@@ -35,6 +37,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	apitypes "k8s.io/apimachinery/pkg/types"
 	apiutilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/strategicpatch"
@@ -54,9 +57,14 @@ type Manager interface {
 	Sync(context.Context) error
 	InstallRelease(context.Context, ...InstallOption) (*rpb.Release, error)
 	UpgradeRelease(context.Context, ...UpgradeOption) (*rpb.Release, *rpb.Release, error)
-	ReconcileRelease(context.Context) (*rpb.Release, error)
+	DryRunRelease(context.Context) (*rpb.Release, *rpb.Release, error)
+	RollbackRelease(context.Context, ...RollbackOption) (*rpb.Release, error)
+	ReleaseHistory(context.Context) ([]*rpb.Release, error)
+	TestRelease(context.Context, ...TestOption) ([]TestResult, error)
+	ReconcileRelease(context.Context) (*rpb.Release, []Drift, error)
 	UninstallRelease(context.Context, ...UninstallOption) (*rpb.Release, error)
 	CleanupRelease(context.Context, string) (bool, error)
+	ResourceStatuses(context.Context, string) ([]ResourceStatus, error)
 }
 
 type manager struct {
@@ -64,8 +72,12 @@ type manager struct {
 	storageBackend *storage.Storage
 	kubeClient     kube.Interface
 
-	releaseName string
-	namespace   string
+	releaseName     string
+	namespace       string
+	maxHistory      int
+	serverSideApply bool
+	postRenderer    postrender.PostRenderer
+	timeout         time.Duration
 
 	values map[string]interface{}
 	status *types.HelmAppStatus
@@ -76,9 +88,30 @@ type manager struct {
 	chart             *cpb.Chart
 }
 
+// Drift describes a live resource that no longer matched the deployed
+// release manifest and was corrected during release reconciliation.
+type Drift struct {
+	GroupVersionKind schema.GroupVersionKind
+	Namespace        string
+	Name             string
+
+	// Action is either DriftActionCreated, when the resource was missing and
+	// has been recreated, or DriftActionPatched, when the resource was patched.
+	Action string
+	// Patch and PatchType are only set when the resource was patched.
+	Patch     []byte
+	PatchType apitypes.PatchType
+}
+
+const (
+	DriftActionCreated = "Created"
+	DriftActionPatched = "Patched"
+)
+
 type InstallOption func(*action.Install) error
 type UpgradeOption func(*action.Upgrade) error
 type UninstallOption func(*action.Uninstall) error
+type RollbackOption func(*action.Rollback) error
 
 // ReleaseName returns the name of the release.
 func (m manager) ReleaseName() string {
@@ -102,16 +135,31 @@ func (m *manager) Sync(ctx context.Context) error {
 		return fmt.Errorf("failed to retrieve release history: %w", err)
 	}
 
-	// Cleanup non-deployed release versions. If all release versions are
+	// Cleanup non-deployed release versions, keeping only the most recent
+	// superseded versions as release history. If all release versions are
 	// non-deployed, this will ensure that failed installations are correctly
 	// retried.
+	var superseded []*rpb.Release
 	for _, rel := range releases {
-		if rel.Info != nil && rel.Info.Status != rpb.StatusDeployed {
-			_, err := m.storageBackend.Delete(rel.Name, rel.Version)
-			if err != nil && !notFoundErr(err) {
-				return fmt.Errorf("failed to delete stale release version: %w", err)
-			}
+		if rel.Info == nil || rel.Info.Status == rpb.StatusDeployed {
+			continue
+		}
+		if rel.Info.Status == rpb.StatusSuperseded {
+			superseded = append(superseded, rel)
+			continue
+		}
+		if err := m.deleteReleaseVersion(rel); err != nil {
+			return err
+		}
+	}
+
+	// The deployed release counts towards maxHistory.
+	releaseutil.SortByRevision(superseded)
+	for len(superseded) > 0 && len(superseded) >= m.maxHistory {
+		if err := m.deleteReleaseVersion(superseded[0]); err != nil {
+			return err
 		}
+		superseded = superseded[1:]
 	}
 
 	// Load the most recently deployed release from the storage backend.
@@ -137,6 +185,14 @@ func (m *manager) Sync(ctx context.Context) error {
 	return nil
 }
 
+func (m manager) deleteReleaseVersion(rel *rpb.Release) error {
+	_, err := m.storageBackend.Delete(rel.Name, rel.Version)
+	if err != nil && !notFoundErr(err) {
+		return fmt.Errorf("failed to delete stale release version: %w", err)
+	}
+	return nil
+}
+
 func notFoundErr(err error) bool {
 	return err != nil && strings.Contains(err.Error(), "not found")
 }
@@ -157,6 +213,7 @@ func (m manager) getCandidateRelease(namespace, name string, chart *cpb.Chart,
 	upgrade := action.NewUpgrade(m.actionConfig)
 	upgrade.Namespace = namespace
 	upgrade.DryRun = true
+	upgrade.PostRenderer = m.postRenderer
 	return upgrade.Run(name, chart, values)
 }
 
@@ -165,6 +222,8 @@ func (m manager) InstallRelease(ctx context.Context, opts ...InstallOption) (*rp
 	install := action.NewInstall(m.actionConfig)
 	install.ReleaseName = m.releaseName
 	install.Namespace = m.namespace
+	install.PostRenderer = m.postRenderer
+	install.Timeout = m.timeout
 	for _, o := range opts {
 		if err := o(install); err != nil {
 			return nil, fmt.Errorf("failed to apply install option: %w", err)
@@ -206,6 +265,8 @@ func ForceUpgrade(force bool) UpgradeOption {
 func (m manager) UpgradeRelease(ctx context.Context, opts ...UpgradeOption) (*rpb.Release, *rpb.Release, error) {
 	upgrade := action.NewUpgrade(m.actionConfig)
 	upgrade.Namespace = m.namespace
+	upgrade.PostRenderer = m.postRenderer
+	upgrade.Timeout = m.timeout
 	for _, o := range opts {
 		if err := o(upgrade); err != nil {
 			return nil, nil, fmt.Errorf("failed to apply upgrade option: %w", err)
@@ -218,6 +279,7 @@ func (m manager) UpgradeRelease(ctx context.Context, opts ...UpgradeOption) (*rp
 		if upgradedRelease != nil {
 			rollback := action.NewRollback(m.actionConfig)
 			rollback.Force = true
+			rollback.Timeout = m.timeout
 
 			// As of Helm 2.13, if UpgradeRelease returns a non-nil release, that
 			// means the release was also recorded in the release store.
@@ -234,29 +296,172 @@ func (m manager) UpgradeRelease(ctx context.Context, opts ...UpgradeOption) (*rp
 	return m.deployedRelease, upgradedRelease, err
 }
 
+// RollbackVersion rolls the release back to version, instead of the revision
+// that was deployed when the manager was synced.
+func RollbackVersion(version int) RollbackOption {
+	return func(r *action.Rollback) error {
+		r.Version = version
+		return nil
+	}
+}
+
+// RollbackToPrevious rolls the release back to the revision superseded by its
+// latest deployed revision, e.g. after the resources of that revision failed
+// to become ready.
+func RollbackToPrevious() RollbackOption {
+	return func(r *action.Rollback) error {
+		r.Version = 0
+		return nil
+	}
+}
+
+// RollbackRelease rolls the release back to the revision that was deployed
+// when the manager was synced, e.g. after a failed upgrade. If that revision
+// is already the latest deployed revision, no rollback is performed.
+func (m manager) RollbackRelease(ctx context.Context, opts ...RollbackOption) (*rpb.Release, error) {
+	if m.deployedRelease == nil {
+		return nil, errors.New("failed to roll back release: no deployed release found")
+	}
+
+	rollback := action.NewRollback(m.actionConfig)
+	rollback.Version = m.deployedRelease.Version
+	rollback.Force = true
+	rollback.Timeout = m.timeout
+	for _, o := range opts {
+		if err := o(rollback); err != nil {
+			return nil, fmt.Errorf("failed to apply rollback option: %w", err)
+		}
+	}
+	if rollback.Version == 0 {
+		version, err := m.previousVersion()
+		if err != nil {
+			return nil, fmt.Errorf("failed to roll back release: %w", err)
+		}
+		rollback.Version = version
+	}
+
+	// A failed upgrade may already have been rolled back by UpgradeRelease.
+	latestRelease, err := m.storageBackend.Last(m.releaseName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest release: %w", err)
+	}
+	target, err := m.storageBackend.Get(m.releaseName, rollback.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get release version %d: %w", rollback.Version, err)
+	}
+	if latestRelease.Info != nil && latestRelease.Info.Status == rpb.StatusDeployed &&
+		latestRelease.Manifest == target.Manifest {
+		return latestRelease, nil
+	}
+
+	if err := rollback.Run(m.releaseName); err != nil {
+		return nil, fmt.Errorf("failed to roll back release to version %d: %w", rollback.Version, err)
+	}
+	return m.getDeployedRelease()
+}
+
+// ReleaseHistory returns the revisions of the release kept in the release
+// storage, oldest first.
+func (m manager) ReleaseHistory(ctx context.Context) ([]*rpb.Release, error) {
+	releases, _, err := releaseHistory(m.storageBackend, m.releaseName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve release history: %w", err)
+	}
+	releaseutil.SortByRevision(releases)
+	return releases, nil
+}
+
+// previousVersion returns the latest superseded version of the release
+// before its latest deployed version.
+func (m manager) previousVersion() (int, error) {
+	deployedRelease, err := m.getDeployedRelease()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get deployed release: %w", err)
+	}
+	releases, err := m.storageBackend.History(m.releaseName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve release history: %w", err)
+	}
+	version := 0
+	for _, rel := range releases {
+		if rel.Info != nil && rel.Info.Status == rpb.StatusSuperseded &&
+			rel.Version < deployedRelease.Version && rel.Version > version {
+			version = rel.Version
+		}
+	}
+	if version == 0 {
+		return 0, fmt.Errorf("no superseded revision before revision %d", deployedRelease.Version)
+	}
+	return version, nil
+}
+
+// DryRunRelease renders the release that would be created by installing or
+// upgrading the release, without making any changes to the cluster. It
+// returns the deployed release, which is nil if the release is not
+// installed, and the candidate release.
+func (m manager) DryRunRelease(ctx context.Context) (*rpb.Release, *rpb.Release, error) {
+	if m.isInstalled {
+		candidateRelease, err := m.getCandidateRelease(m.namespace, m.releaseName, m.chart, m.values)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get candidate release: %w", err)
+		}
+		return m.deployedRelease, candidateRelease, nil
+	}
+
+	install := action.NewInstall(m.actionConfig)
+	install.ReleaseName = m.releaseName
+	install.Namespace = m.namespace
+	install.DryRun = true
+	install.PostRenderer = m.postRenderer
+	candidateRelease, err := install.Run(m.chart, m.values)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get candidate release: %w", err)
+	}
+	return nil, candidateRelease, nil
+}
+
 // ReconcileRelease creates or patches resources as necessary to match the
-// deployed release's manifest.
-func (m manager) ReconcileRelease(ctx context.Context) (*rpb.Release, error) {
-	err := reconcileRelease(ctx, m.kubeClient, m.deployedRelease.Manifest)
-	return m.deployedRelease, err
+// deployed release's manifest. It returns every resource that had drifted
+// from the manifest, along with the correction that was applied.
+//
+// In server-side apply mode, fields of resources that are managed by other
+// field managers are not overwritten. Instead, a *ConflictError listing the
+// conflicting resources is returned after all other resources have been
+// reconciled.
+func (m manager) ReconcileRelease(ctx context.Context) (*rpb.Release, []Drift, error) {
+	if m.serverSideApply {
+		drifts, err := applyRelease(ctx, m.kubeClient, m.deployedRelease.Manifest)
+		return m.deployedRelease, drifts, err
+	}
+	drifts, err := reconcileRelease(ctx, m.kubeClient, m.deployedRelease.Manifest)
+	return m.deployedRelease, drifts, err
 }
 
-func reconcileRelease(_ context.Context, kubeClient kube.Interface, expectedManifest string) error {
+func reconcileRelease(_ context.Context, kubeClient kube.Interface, expectedManifest string) ([]Drift, error) {
 	expectedInfos, err := kubeClient.Build(bytes.NewBufferString(expectedManifest), false)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return expectedInfos.Visit(func(expected *resource.Info, err error) error {
+	var drifts []Drift
+	err = expectedInfos.Visit(func(expected *resource.Info, err error) error {
 		if err != nil {
 			return fmt.Errorf("visit error: %w", err)
 		}
 
+		drift := Drift{
+			GroupVersionKind: expected.Object.GetObjectKind().GroupVersionKind(),
+			Namespace:        expected.Namespace,
+			Name:             expected.Name,
+		}
+
 		helper := resource.NewHelper(expected.Client, expected.Mapping)
 		existing, err := helper.Get(expected.Namespace, expected.Name)
 		if apierrors.IsNotFound(err) {
 			if _, err := helper.Create(expected.Namespace, true, expected.Object); err != nil {
 				return fmt.Errorf("create error: %s", err)
 			}
+			drift.Action = DriftActionCreated
+			drifts = append(drifts, drift)
 			return nil
 		} else if err != nil {
 			return fmt.Errorf("could not get object: %w", err)
@@ -272,7 +477,7 @@ func reconcileRelease(_ context.Context, kubeClient kube.Interface, expectedMani
 			return fmt.Errorf("error creating patch: %w", err)
 		}
 
-		if patch == nil {
+		if patch == nil || string(patch) == "{}" {
 			// nothing to do
 			return nil
 		}
@@ -282,8 +487,13 @@ func reconcileRelease(_ context.Context, kubeClient kube.Interface, expectedMani
 		if err != nil {
 			return fmt.Errorf("patch error: %w", err)
 		}
+		drift.Action = DriftActionPatched
+		drift.Patch = patch
+		drift.PatchType = patchType
+		drifts = append(drifts, drift)
 		return nil
 	})
+	return drifts, err
 }
 
 func createPatch(existing runtime.Object, expected *resource.Info) ([]byte, apitypes.PatchType, error) {
@@ -356,6 +566,7 @@ func createJSONMergePatch(existingJSON, expectedJSON []byte) ([]byte, error) {
 // UninstallRelease performs a Helm release uninstall.
 func (m manager) UninstallRelease(ctx context.Context, opts ...UninstallOption) (*rpb.Release, error) {
 	uninstall := action.NewUninstall(m.actionConfig)
+	uninstall.Timeout = m.timeout
 	for _, o := range opts {
 		if err := o(uninstall); err != nil {
 			return nil, fmt.Errorf("failed to apply uninstall option: %w", err)
@@ -16,15 +16,19 @@ package release
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
+	lru "github.com/hashicorp/golang-lru"
 	"helm.sh/helm/v3/pkg/action"
-	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/postrender"
 	helmrelease "helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/storage"
 	"helm.sh/helm/v3/pkg/storage/driver"
 	"helm.sh/helm/v3/pkg/strvals"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
 	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	crmanager "sigs.k8s.io/controller-runtime/pkg/manager"
 
@@ -37,61 +41,201 @@ import (
 // improves decoupling between reconciliation logic and the Helm backend
 // components used to manage releases.
 type ManagerFactory interface {
-	NewManager(r *unstructured.Unstructured, overrideValues map[string]string) (Manager, error)
+	NewManager(r *unstructured.Unstructured, sourceValues map[string]interface{}, overrideValues map[string]string) (Manager, error)
 }
 
 type managerFactory struct {
-	mgr      crmanager.Manager
-	chartDir string
+	mgr             crmanager.Manager
+	chartDir        string
+	charts          *chartCache
+	maxHistory      int
+	serverSideApply bool
+	postRenderer    postrender.PostRenderer
+	releaseSuffix   string
+	targetNamespace TargetNamespace
+	timeout         time.Duration
+	ignoredFields   []string
+
+	// mu guards the clients below, which are created on first use and
+	// shared by all managers created by this factory. The clients of at most
+	// maxNamespaceClients namespaces are kept, the least recently used first
+	// evicted, so that they do not accumulate for namespaces whose custom
+	// resources were deleted.
+	mu         sync.Mutex
+	clientv1   v1.CoreV1Interface
+	namespaces *lru.Cache
+}
+
+// maxNamespaceClients is the maximum number of namespaces whose clients are
+// kept by a manager factory.
+const maxNamespaceClients = 64
+
+// namespaceClients are the clients used to manage releases in a namespace.
+type namespaceClients struct {
+	restClientGetter genericclioptions.RESTClientGetter
+	kubeClient       *kube.Client
+}
+
+// ManagerFactoryOption configures the Managers created by a ManagerFactory.
+type ManagerFactoryOption func(*managerFactory)
+
+// MaxHistory limits the number of release versions kept in the release
+// storage, including the deployed version. Versions beyond this limit are
+// pruned when a Manager is synced. If max is less than 1, only the deployed
+// version is kept.
+func MaxHistory(max int) ManagerFactoryOption {
+	return func(f *managerFactory) {
+		f.maxHistory = max
+	}
+}
+
+// ServerSideApply enables reconciling release resources with server-side
+// apply, using FieldManager as the field manager. Fields managed by other
+// field managers are then reported as conflicts instead of being overwritten.
+// Installs and upgrades still use Helm's client-side apply; server-side apply
+// is only used to correct drift from the deployed release.
+func ServerSideApply(enabled bool) ManagerFactoryOption {
+	return func(f *managerFactory) {
+		f.serverSideApply = enabled
+	}
+}
+
+// PostRenderer sets the post-renderer that modifies the manifests rendered
+// from the chart before releases are installed or upgraded.
+func PostRenderer(pr postrender.PostRenderer) ManagerFactoryOption {
+	return func(f *managerFactory) {
+		f.postRenderer = pr
+	}
+}
+
+// ReleaseNameSuffix appends suffix to the names of the releases, which are
+// otherwise named after their custom resource. It distinguishes the releases
+// of the charts composing a custom resource.
+func ReleaseNameSuffix(suffix string) ManagerFactoryOption {
+	return func(f *managerFactory) {
+		f.releaseSuffix = suffix
+	}
+}
+
+// ReleaseNamespace sets the namespace in which releases are installed and
+// stored, instead of the namespace of their custom resource.
+func ReleaseNamespace(targetNamespace TargetNamespace) ManagerFactoryOption {
+	return func(f *managerFactory) {
+		f.targetNamespace = targetNamespace
+	}
+}
+
+// ReleaseTimeout bounds the time that Helm waits for the Kubernetes operations
+// of install, upgrade, rollback and uninstall actions, such as hooks.
+func ReleaseTimeout(timeout time.Duration) ManagerFactoryOption {
+	return func(f *managerFactory) {
+		f.timeout = timeout
+	}
+}
+
+// IgnoredSpecFields sets top-level fields of the spec of custom resources
+// that are not passed to the chart as values, such as fields configuring the
+// operator itself.
+func IgnoredSpecFields(fields ...string) ManagerFactoryOption {
+	return func(f *managerFactory) {
+		f.ignoredFields = fields
+	}
 }
 
 // NewManagerFactory returns a new Helm manager factory capable of installing and uninstalling releases.
-func NewManagerFactory(mgr crmanager.Manager, chartDir string) ManagerFactory {
-	return &managerFactory{mgr, chartDir}
+func NewManagerFactory(mgr crmanager.Manager, chartDir string, opts ...ManagerFactoryOption) ManagerFactory {
+	// lru.New only fails for non-positive sizes.
+	namespaces, _ := lru.New(maxNamespaceClients)
+	f := &managerFactory{
+		mgr:        mgr,
+		chartDir:   chartDir,
+		charts:     newChartCache(chartDir),
+		namespaces: namespaces,
+	}
+	for _, o := range opts {
+		o(f)
+	}
+	return f
 }
 
-func (f managerFactory) NewManager(cr *unstructured.Unstructured, overrideValues map[string]string) (Manager, error) {
-	// Get both v2 and v3 storage backends
-	clientv1, err := v1.NewForConfig(f.mgr.GetConfig())
+// clientsFor returns the core/v1 client and the namespaced clients used to
+// manage releases in namespace ns, creating them if necessary.
+func (f *managerFactory) clientsFor(ns string) (v1.CoreV1Interface, *namespaceClients, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.clientv1 == nil {
+		clientv1, err := v1.NewForConfig(f.mgr.GetConfig())
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get core/v1 client: %w", err)
+		}
+		f.clientv1 = clientv1
+	}
+
+	if nc, ok := f.namespaces.Get(ns); ok {
+		return f.clientv1, nc.(*namespaceClients), nil
+	}
+	rcg, err := client.NewRESTClientGetter(f.mgr, ns)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get core/v1 client: %w", err)
+		return nil, nil, fmt.Errorf("failed to get REST client getter from manager: %w", err)
 	}
-	storageBackend := storage.Init(driver.NewSecrets(clientv1.Secrets(cr.GetNamespace())))
+	nc := &namespaceClients{
+		restClientGetter: rcg,
+		kubeClient:       kube.New(rcg),
+	}
+	f.namespaces.Add(ns, nc)
+	return f.clientv1, nc, nil
+}
 
-	// Get the necessary clients and client getters. Use a client that injects the CR
-	// as an owner reference into all resources templated by the chart.
-	rcg, err := client.NewRESTClientGetter(f.mgr, cr.GetNamespace())
+// NewManager returns a Manager for the release of cr. The release values are
+// the spec of cr, merged with sourceValues and then overrideValues.
+func (f *managerFactory) NewManager(cr *unstructured.Unstructured, sourceValues map[string]interface{},
+	overrideValues map[string]string) (Manager, error) {
+	ns, err := f.targetNamespace.For(cr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get REST client getter from manager: %w", err)
+		return nil, err
+	}
+	// Releases stay in the namespace they were installed in until they are
+	// uninstalled, since the resources of a release cannot be moved.
+	if deployedNs := deployedNamespace(types.StatusFor(cr), f.releaseSuffix); deployedNs != "" && deployedNs != ns {
+		if cr.GetDeletionTimestamp() == nil {
+			return nil, fmt.Errorf("cannot move release from namespace %q to %q", deployedNs, ns)
+		}
+		ns = deployedNs
 	}
 
-	kubeClient := kube.New(rcg)
+	clientv1, nc, err := f.clientsFor(ns)
+	if err != nil {
+		return nil, err
+	}
+	// Get both v2 and v3 storage backends
+	storageBackend := storage.Init(driver.NewSecrets(clientv1.Secrets(ns)))
+
+	// Get the necessary clients and client getters. Use a client that injects the CR
+	// as an owner reference into all resources templated by the chart. Resources
+	// that cannot be owned by the CR, such as resources in another namespace than
+	// the CR, are annotated with the CR instead.
+	rcg := nc.restClientGetter
 	restMapper := f.mgr.GetRESTMapper()
-	ownerRefClient, err := client.NewOwnerRefInjectingClient(*kubeClient, restMapper, cr)
+	ownerRefClient, err := client.NewOwnerRefInjectingClient(*nc.kubeClient, restMapper, cr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to inject owner references: %w", err)
 	}
 
-	crChart, err := loader.LoadDir(f.chartDir)
+	crChart, err := f.charts.Get()
 	if err != nil {
-		return nil, fmt.Errorf("failed to load chart dir: %w", err)
+		return nil, err
 	}
 
-	releaseName, err := getReleaseName(storageBackend, crChart.Name(), cr)
+	releaseName, err := getReleaseName(storageBackend, crChart.Name(), f.releaseName(cr, ns))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get helm release name: %w", err)
 	}
 
-	crValues, ok := cr.Object["spec"].(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("failed to get spec: expected map[string]interface{}")
-	}
-
-	expOverrides, err := parseOverrides(overrideValues)
+	values, err := f.values(cr, sourceValues, overrideValues)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse override values: %w", err)
+		return nil, err
 	}
-	values := mergeMaps(crValues, expOverrides)
 
 	actionConfig := &action.Configuration{
 		RESTClientGetter: rcg,
@@ -105,8 +249,12 @@ func (f managerFactory) NewManager(cr *unstructured.Unstructured, overrideValues
 		storageBackend: storageBackend,
 		kubeClient:     ownerRefClient,
 
-		releaseName: releaseName,
-		namespace:   cr.GetNamespace(),
+		releaseName:     releaseName,
+		namespace:       ns,
+		maxHistory:      f.maxHistory,
+		serverSideApply: f.serverSideApply,
+		postRenderer:    f.postRenderer,
+		timeout:         f.timeout,
 
 		chart:  crChart,
 		values: values,
@@ -114,11 +262,37 @@ func (f managerFactory) NewManager(cr *unstructured.Unstructured, overrideValues
 	}, nil
 }
 
+// releaseName returns the name of the release of cr in namespace ns.
+func (f *managerFactory) releaseName(cr *unstructured.Unstructured, ns string) string {
+	releaseName := defaultReleaseName(cr, ns)
+	if f.releaseSuffix != "" {
+		releaseName = fmt.Sprintf("%s-%s", releaseName, f.releaseSuffix)
+	}
+	return releaseName
+}
+
+// values returns the values of the release of cr: its spec, without the
+// ignored fields, merged with sourceValues and then overrideValues.
+func (f *managerFactory) values(cr *unstructured.Unstructured, sourceValues map[string]interface{},
+	overrideValues map[string]string) (map[string]interface{}, error) {
+	crValues, err := f.targetNamespace.values(cr)
+	if err != nil {
+		return nil, err
+	}
+	crValues = withoutFields(crValues, f.ignoredFields...)
+
+	expOverrides, err := parseOverrides(overrideValues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse override values: %w", err)
+	}
+	return mergeMaps(mergeMaps(crValues, sourceValues), expOverrides), nil
+}
+
 // getReleaseName returns a release name for the CR.
 //
-// getReleaseName searches for a release using the CR name. If a release
-// cannot be found, or if it is found and was created by the chart managed
-// by this manager, the CR name is returned.
+// getReleaseName searches for a release using releaseName, which is derived
+// from the CR name. If a release cannot be found, or if it is found and was
+// created by the chart managed by this manager, releaseName is returned.
 //
 // If a release is found but it was created by another chart, that means we
 // have a release name collision, so return an error. This case is possible
@@ -131,9 +305,8 @@ func (f managerFactory) NewManager(cr *unstructured.Unstructured, overrideValues
 //   collision. As is, the only indication of collision will be in the CR status
 //   and operator logs.
 func getReleaseName(storageBackend *storage.Storage, crChartName string,
-	cr *unstructured.Unstructured) (string, error) {
-	// If a release with the CR name does not exist, return the CR name.
-	releaseName := cr.GetName()
+	releaseName string) (string, error) {
+	// If a release with the name does not exist, return the name.
 	history, exists, err := releaseHistory(storageBackend, releaseName)
 	if err != nil {
 		return "", err
@@ -0,0 +1,58 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/rest"
+	crmanager "sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// fakeManager is a controller-runtime manager that only provides a REST
+// config and mapper.
+type fakeManager struct {
+	crmanager.Manager
+}
+
+func (fakeManager) GetConfig() *rest.Config        { return &rest.Config{Host: "https://127.0.0.1:6443"} }
+func (fakeManager) GetRESTMapper() meta.RESTMapper { return meta.NewDefaultRESTMapper(nil) }
+
+func TestClientsForEvictsNamespaces(t *testing.T) {
+	f := NewManagerFactory(fakeManager{}, "").(*managerFactory)
+
+	_, first, err := f.clientsFor("ns-0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, nc, err := f.clientsFor("ns-0")
+	assert.NoError(t, err)
+	assert.Same(t, first, nc, "clients are shared")
+
+	for i := 1; i <= maxNamespaceClients; i++ {
+		_, _, err := f.clientsFor(fmt.Sprintf("ns-%d", i))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, maxNamespaceClients, f.namespaces.Len())
+	assert.False(t, f.namespaces.Contains("ns-0"), "least recently used namespace is evicted")
+	assert.True(t, f.namespaces.Contains(fmt.Sprintf("ns-%d", maxNamespaceClients)))
+
+	_, nc, err = f.clientsFor("ns-0")
+	assert.NoError(t, err)
+	assert.NotSame(t, first, nc, "clients of evicted namespaces are created again")
+}
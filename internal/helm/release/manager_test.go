@@ -15,8 +15,18 @@
 package release
 
 import (
+	"context"
+	"fmt"
+	"io/ioutil"
 	"testing"
 
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	rpb "helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	apitypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/cli-runtime/pkg/resource"
@@ -213,3 +223,188 @@ func TestManagerGenerateStrategicMergePatch(t *testing.T) {
 		assert.Equal(t, test.patch, string(diff))
 	}
 }
+
+func TestManagerPreviousVersion(t *testing.T) {
+	newManager := func(statuses ...rpb.Status) manager {
+		storageBackend := storage.Init(driver.NewMemory())
+		for i, status := range statuses {
+			assert.NoError(t, storageBackend.Create(&rpb.Release{
+				Name:    "test",
+				Version: i + 1,
+				Info:    &rpb.Info{Status: status},
+			}))
+		}
+		return manager{storageBackend: storageBackend, releaseName: "test"}
+	}
+
+	m := newManager(rpb.StatusSuperseded, rpb.StatusSuperseded, rpb.StatusFailed, rpb.StatusDeployed)
+	version, err := m.previousVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, version)
+
+	m = newManager(rpb.StatusDeployed)
+	_, err = m.previousVersion()
+	assert.Error(t, err)
+}
+
+// newTestHistoryManager returns a manager of the release "test" of the test
+// chart, whose revisions have statuses, and whose resources are managed by a
+// fake kube client. The manifest of each revision is its version.
+func newTestHistoryManager(t *testing.T, maxHistory int, statuses ...rpb.Status) manager {
+	crChart, err := loader.Load(testChartDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storageBackend := storage.Init(driver.NewMemory())
+	for i, status := range statuses {
+		assert.NoError(t, storageBackend.Create(&rpb.Release{
+			Name:      "test",
+			Namespace: "ns",
+			Version:   i + 1,
+			Chart:     crChart,
+			Config:    map[string]interface{}{},
+			Manifest:  fmt.Sprintf("revision-%d", i+1),
+			Info:      &rpb.Info{Status: status},
+		}))
+	}
+	actionConfig := &action.Configuration{
+		Releases:     storageBackend,
+		KubeClient:   &kubefake.PrintingKubeClient{Out: ioutil.Discard},
+		Capabilities: chartutil.DefaultCapabilities,
+		Log:          func(string, ...interface{}) {},
+	}
+	return manager{
+		actionConfig:   actionConfig,
+		storageBackend: storageBackend,
+		releaseName:    "test",
+		namespace:      "ns",
+		maxHistory:     maxHistory,
+		chart:          crChart,
+		values:         map[string]interface{}{},
+	}
+}
+
+// releaseStatuses returns the statuses of the revisions of the release of m
+// by version.
+func releaseStatuses(t *testing.T, m manager) map[int]rpb.Status {
+	releases, err := m.storageBackend.History(m.releaseName)
+	assert.NoError(t, err)
+	statuses := map[int]rpb.Status{}
+	for _, rel := range releases {
+		statuses[rel.Version] = rel.Info.Status
+	}
+	return statuses
+}
+
+func TestManagerSyncMaxHistory(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxHistory int
+		statuses   []rpb.Status
+		expected   map[int]rpb.Status
+	}{
+		{
+			name:       "superseded revisions beyond max history",
+			maxHistory: 3,
+			statuses:   []rpb.Status{rpb.StatusSuperseded, rpb.StatusSuperseded, rpb.StatusSuperseded, rpb.StatusSuperseded, rpb.StatusDeployed},
+			expected:   map[int]rpb.Status{3: rpb.StatusSuperseded, 4: rpb.StatusSuperseded, 5: rpb.StatusDeployed},
+		},
+		{
+			name:       "failed revisions",
+			maxHistory: 3,
+			statuses:   []rpb.Status{rpb.StatusSuperseded, rpb.StatusDeployed, rpb.StatusFailed},
+			expected:   map[int]rpb.Status{1: rpb.StatusSuperseded, 2: rpb.StatusDeployed},
+		},
+		{
+			name:       "deployed revision only",
+			maxHistory: 1,
+			statuses:   []rpb.Status{rpb.StatusSuperseded, rpb.StatusSuperseded, rpb.StatusDeployed},
+			expected:   map[int]rpb.Status{3: rpb.StatusDeployed},
+		},
+		{
+			name:       "unset max history",
+			maxHistory: 0,
+			statuses:   []rpb.Status{rpb.StatusSuperseded, rpb.StatusDeployed},
+			expected:   map[int]rpb.Status{2: rpb.StatusDeployed},
+		},
+		{
+			name:       "deployed revision before superseded revisions",
+			maxHistory: 2,
+			statuses:   []rpb.Status{rpb.StatusDeployed, rpb.StatusSuperseded, rpb.StatusSuperseded, rpb.StatusSuperseded},
+			expected:   map[int]rpb.Status{1: rpb.StatusDeployed, 4: rpb.StatusSuperseded},
+		},
+	}
+
+	for _, test := range tests {
+		m := newTestHistoryManager(t, test.maxHistory, test.statuses...)
+		assert.NoError(t, m.Sync(context.TODO()), test.name)
+		assert.Equal(t, test.expected, releaseStatuses(t, m), test.name)
+		assert.True(t, m.IsInstalled(), test.name)
+	}
+}
+
+func TestManagerRollbackRelease(t *testing.T) {
+	m := newTestHistoryManager(t, 10, rpb.StatusSuperseded, rpb.StatusDeployed)
+	assert.NoError(t, m.Sync(context.TODO()))
+
+	// The upgrade to revision 3 failed after the manager was synced, so the
+	// release is rolled back to the deployed revision 2.
+	failed, err := m.storageBackend.Get("test", 2)
+	assert.NoError(t, err)
+	failed = &rpb.Release{Name: "test", Namespace: "ns", Version: 3, Chart: failed.Chart, Manifest: "revision-3",
+		Info: &rpb.Info{Status: rpb.StatusFailed}}
+	assert.NoError(t, m.storageBackend.Create(failed))
+
+	rolledBack, err := m.RollbackRelease(context.TODO())
+	assert.NoError(t, err)
+	if assert.NotNil(t, rolledBack) {
+		assert.Equal(t, 4, rolledBack.Version)
+		assert.Equal(t, "revision-2", rolledBack.Manifest)
+		assert.Equal(t, rpb.StatusDeployed, rolledBack.Info.Status)
+	}
+	assert.Equal(t, map[int]rpb.Status{
+		1: rpb.StatusSuperseded,
+		2: rpb.StatusSuperseded,
+		3: rpb.StatusFailed,
+		4: rpb.StatusDeployed,
+	}, releaseStatuses(t, m))
+}
+
+func TestManagerRollbackReleaseToPrevious(t *testing.T) {
+	// The resources of the deployed revision 2 did not become ready, so the
+	// release is rolled back to the superseded revision 1.
+	m := newTestHistoryManager(t, 10, rpb.StatusSuperseded, rpb.StatusDeployed)
+	assert.NoError(t, m.Sync(context.TODO()))
+	rolledBack, err := m.RollbackRelease(context.TODO(), RollbackToPrevious())
+	assert.NoError(t, err)
+	if assert.NotNil(t, rolledBack) {
+		assert.Equal(t, 3, rolledBack.Version)
+		assert.Equal(t, "revision-1", rolledBack.Manifest)
+		assert.Equal(t, rpb.StatusDeployed, rolledBack.Info.Status)
+	}
+
+	// Without a superseded revision, there is nothing to roll back to.
+	m = newTestHistoryManager(t, 10, rpb.StatusDeployed)
+	assert.NoError(t, m.Sync(context.TODO()))
+	_, err = m.RollbackRelease(context.TODO(), RollbackToPrevious())
+	assert.Error(t, err)
+}
+
+func TestManagerRollbackReleaseAlreadyRolledBack(t *testing.T) {
+	// The failed upgrade to revision 3 was already rolled back to revision 2
+	// as revision 4, so no rollback is performed.
+	m := newTestHistoryManager(t, 10, rpb.StatusSuperseded, rpb.StatusSuperseded, rpb.StatusFailed, rpb.StatusDeployed)
+	m.deployedRelease, _ = m.storageBackend.Get("test", 2)
+	latest, err := m.storageBackend.Get("test", 4)
+	assert.NoError(t, err)
+	latest.Manifest = "revision-2"
+	assert.NoError(t, m.storageBackend.Update(latest))
+
+	rolledBack, err := m.RollbackRelease(context.TODO())
+	assert.NoError(t, err)
+	if assert.NotNil(t, rolledBack) {
+		assert.Equal(t, 4, rolledBack.Version)
+	}
+	_, err = m.storageBackend.Get("test", 5)
+	assert.Error(t, err)
+}
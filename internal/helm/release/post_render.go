@@ -0,0 +1,193 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"helm.sh/helm/v3/pkg/postrender"
+	"sigs.k8s.io/kustomize/api/filesys"
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/yaml"
+)
+
+// KustomizeManifestsFile is the file, added to the resources of a kustomize
+// post-renderer's kustomization, that contains the manifests rendered by
+// Helm.
+const KustomizeManifestsFile = "helm-release.yaml"
+
+// PostRendererConfig configures a post-renderer, which modifies the manifests
+// rendered from a chart before they are installed or upgraded. Exactly one of
+// Kustomize and Exec must be set.
+type PostRendererConfig struct {
+	// Kustomize is the path of a kustomize overlay directory. The rendered
+	// manifests are added to the resources of its kustomization, and the
+	// output of the build is installed.
+	Kustomize string `json:"kustomize,omitempty"`
+	// Exec is the path of an executable that receives the rendered manifests
+	// on stdin and writes the manifests to install to stdout.
+	Exec string `json:"exec,omitempty"`
+}
+
+// Validate returns an error if c is not a valid post-renderer configuration.
+func (c PostRendererConfig) Validate() error {
+	switch {
+	case c.Kustomize != "" && c.Exec != "":
+		return errors.New("only one of kustomize or exec may be set")
+	case c.Kustomize != "":
+		if _, err := kustomizationFile(c.Kustomize); err != nil {
+			return err
+		}
+		return nil
+	case c.Exec != "":
+		_, err := postrender.NewExec(c.Exec)
+		return err
+	default:
+		return errors.New("one of kustomize or exec must be set")
+	}
+}
+
+// New returns the post-renderer configured by c. The files of a kustomize
+// overlay are read once, so changes to the overlay directory are only taken
+// into account by a new post-renderer.
+func (c PostRendererConfig) New() (postrender.PostRenderer, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	if c.Exec != "" {
+		return postrender.NewExec(c.Exec)
+	}
+	return newKustomizePostRenderer(c.Kustomize)
+}
+
+// kustomizeOverlayRoot is the directory of the overlay in the in-memory file
+// systems of kustomize builds.
+const kustomizeOverlayRoot = "/overlay"
+
+// kustomizePostRenderer post-renders manifests with the kustomize overlay in
+// dir.
+type kustomizePostRenderer struct {
+	dir string
+	// files are the contents of the files of the overlay by path relative to
+	// dir, with the rendered manifests added to the resources of its
+	// kustomization.
+	files map[string][]byte
+}
+
+func newKustomizePostRenderer(dir string) (*kustomizePostRenderer, error) {
+	files, err := readDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kustomize overlay: %w", err)
+	}
+	kustomization, err := kustomizationFile(dir)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(dir, kustomization)
+	if err != nil {
+		return nil, err
+	}
+	if files[rel], err = addKustomizationResource(files[rel], KustomizeManifestsFile); err != nil {
+		return nil, err
+	}
+	return &kustomizePostRenderer{dir: dir, files: files}, nil
+}
+
+// Run builds the overlay in memory, with the rendered manifests as an
+// additional resource of its kustomization. The overlay directory itself is
+// never modified, so it can be read-only.
+func (r *kustomizePostRenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	fSys := filesys.MakeFsInMemory()
+	for rel, b := range r.files {
+		if err := fSys.WriteFile(filepath.Join(kustomizeOverlayRoot, rel), b); err != nil {
+			return nil, fmt.Errorf("failed to copy kustomize overlay: %w", err)
+		}
+	}
+	manifests := filepath.Join(kustomizeOverlayRoot, KustomizeManifestsFile)
+	if err := fSys.WriteFile(manifests, renderedManifests.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to write rendered manifests: %w", err)
+	}
+
+	resources, err := krusty.MakeKustomizer(fSys, krusty.MakeDefaultOptions()).Run(kustomizeOverlayRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kustomize overlay %s: %w", r.dir, err)
+	}
+	out, err := resources.AsYaml()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kustomize overlay %s: %w", r.dir, err)
+	}
+	return bytes.NewBuffer(out), nil
+}
+
+// kustomizationFile returns the path of the kustomization file in dir.
+func kustomizationFile(dir string) (string, error) {
+	for _, name := range konfig.RecognizedKustomizationFileNames() {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("no kustomization file found in %s", dir)
+}
+
+// addKustomizationResource adds resource to the resources of the
+// kustomization b.
+func addKustomizationResource(b []byte, resource string) ([]byte, error) {
+	k := map[string]interface{}{}
+	if err := yaml.Unmarshal(b, &k); err != nil {
+		return nil, fmt.Errorf("failed to parse kustomization: %w", err)
+	}
+	resources, _ := k["resources"].([]interface{})
+	k["resources"] = append(resources, resource)
+	return yaml.Marshal(k)
+}
+
+// readDir returns the contents of the files under dir by path relative to
+// dir. Symbolic links to files, like those of ConfigMap volumes, are followed.
+func readDir(dir string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if info, err = os.Stat(path); err != nil {
+				return err
+			}
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[rel] = b
+		return nil
+	})
+	return files, err
+}
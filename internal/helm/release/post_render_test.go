@@ -0,0 +1,174 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+)
+
+const testRenderedManifests = `---
+# Source: test/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+data:
+  key: value
+`
+
+const testKustomization = `commonLabels:
+  org: example
+patchesStrategicMerge:
+- patch.yaml
+`
+
+const testKustomizationPatch = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+data:
+  injected: "true"
+`
+
+func newTestOverlay(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "post-render-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "kustomization.yaml"), []byte(testKustomization), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "patch.yaml"), []byte(testKustomizationPatch), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestPostRendererConfigValidate(t *testing.T) {
+	overlay := newTestOverlay(t)
+	empty, err := ioutil.TempDir("", "post-render-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(empty)
+
+	testCases := []struct {
+		name      string
+		config    PostRendererConfig
+		expectErr bool
+	}{
+		{
+			name:   "kustomize",
+			config: PostRendererConfig{Kustomize: overlay},
+		},
+		{
+			name:   "exec",
+			config: PostRendererConfig{Exec: "/bin/sh"},
+		},
+		{
+			name:      "none",
+			expectErr: true,
+		},
+		{
+			name:      "both",
+			config:    PostRendererConfig{Kustomize: overlay, Exec: "/bin/sh"},
+			expectErr: true,
+		},
+		{
+			name:      "no kustomization",
+			config:    PostRendererConfig{Kustomize: empty},
+			expectErr: true,
+		},
+		{
+			name:      "missing executable",
+			config:    PostRendererConfig{Exec: filepath.Join(empty, "missing")},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			if tc.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestKustomizePostRenderer(t *testing.T) {
+	overlay := newTestOverlay(t)
+	pr, err := PostRendererConfig{Kustomize: overlay}.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := pr.Run(bytes.NewBufferString(testRenderedManifests))
+	if !assert.NoError(t, err) {
+		return
+	}
+	cm := map[string]interface{}{}
+	assert.NoError(t, yaml.Unmarshal(out.Bytes(), &cm))
+	assert.Equal(t, map[string]interface{}{"org": "example"}, cm["metadata"].(map[string]interface{})["labels"])
+	assert.Equal(t, map[string]interface{}{"key": "value", "injected": "true"}, cm["data"])
+
+	// The overlay is not modified.
+	b, err := ioutil.ReadFile(filepath.Join(overlay, "kustomization.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, testKustomization, string(b))
+	_, err = os.Stat(filepath.Join(overlay, KustomizeManifestsFile))
+	assert.True(t, os.IsNotExist(err))
+
+	// The overlay is read once, when the post-renderer is created.
+	assert.NoError(t, os.Remove(filepath.Join(overlay, "patch.yaml")))
+	out, err = pr.Run(bytes.NewBufferString(testRenderedManifests))
+	if assert.NoError(t, err) {
+		cm = map[string]interface{}{}
+		assert.NoError(t, yaml.Unmarshal(out.Bytes(), &cm))
+		assert.Equal(t, map[string]interface{}{"key": "value", "injected": "true"}, cm["data"])
+	}
+}
+
+func TestExecPostRenderer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "post-render-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	script := filepath.Join(dir, "post-render.sh")
+	if err := ioutil.WriteFile(script, []byte("#!/bin/sh\nsed 's/value/rendered/'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	pr, err := PostRendererConfig{Exec: script}.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := pr.Run(bytes.NewBufferString(testRenderedManifests))
+	if assert.NoError(t, err) {
+		assert.Contains(t, out.String(), "key: rendered")
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/resource"
+)
+
+// ResourceStatus is the readiness of a resource in a release.
+type ResourceStatus struct {
+	GroupVersionKind schema.GroupVersionKind
+	Namespace        string
+	Name             string
+	Ready            bool
+	// Message explains why the resource is not ready.
+	Message string
+}
+
+// ResourceStatuses returns the readiness of every resource in manifest.
+func (m manager) ResourceStatuses(_ context.Context, manifest string) ([]ResourceStatus, error) {
+	infos, err := m.kubeClient.Build(strings.NewReader(manifest), false)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []ResourceStatus
+	err = infos.Visit(func(info *resource.Info, err error) error {
+		if err != nil {
+			return fmt.Errorf("visit error: %w", err)
+		}
+		status := ResourceStatus{
+			GroupVersionKind: info.Object.GetObjectKind().GroupVersionKind(),
+			Namespace:        info.Namespace,
+			Name:             info.Name,
+		}
+
+		helper := resource.NewHelper(info.Client, info.Mapping)
+		obj, err := helper.Get(info.Namespace, info.Name)
+		if apierrors.IsNotFound(err) {
+			status.Message = "resource not found"
+			statuses = append(statuses, status)
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("could not get object: %w", err)
+		}
+
+		u, err := toUnstructured(obj)
+		if err != nil {
+			return err
+		}
+		status.Ready, status.Message = resourceReady(u)
+		statuses = append(statuses, status)
+		return nil
+	})
+	return statuses, err
+}
+
+func toUnstructured(obj runtime.Object) (*unstructured.Unstructured, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u, nil
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert object: %w", err)
+	}
+	return &unstructured.Unstructured{Object: content}, nil
+}
+
+// resourceReady returns whether u is ready and, if it is not, why.
+// Deployments must be available, StatefulSets and DaemonSets ready, Jobs
+// complete and PersistentVolumeClaims bound. Resources of other kinds are
+// ready as soon as they exist.
+func resourceReady(u *unstructured.Unstructured) (bool, string) {
+	gk := u.GroupVersionKind().GroupKind()
+	switch gk {
+	case schema.GroupKind{Group: "apps", Kind: "Deployment"}:
+		if msg, ok := observedGeneration(u); !ok {
+			return false, msg
+		}
+		replicas := replicasOf(u)
+		updated, _, _ := unstructured.NestedInt64(u.Object, "status", "updatedReplicas")
+		available, _, _ := unstructured.NestedInt64(u.Object, "status", "availableReplicas")
+		if updated < replicas || available < replicas {
+			return false, fmt.Sprintf("%d of %d updated replicas available", minInt64(updated, available), replicas)
+		}
+	case schema.GroupKind{Group: "apps", Kind: "StatefulSet"}:
+		if msg, ok := observedGeneration(u); !ok {
+			return false, msg
+		}
+		replicas := replicasOf(u)
+		ready, _, _ := unstructured.NestedInt64(u.Object, "status", "readyReplicas")
+		if ready < replicas {
+			return false, fmt.Sprintf("%d of %d replicas ready", ready, replicas)
+		}
+	case schema.GroupKind{Group: "apps", Kind: "DaemonSet"}:
+		if msg, ok := observedGeneration(u); !ok {
+			return false, msg
+		}
+		desired, _, _ := unstructured.NestedInt64(u.Object, "status", "desiredNumberScheduled")
+		ready, _, _ := unstructured.NestedInt64(u.Object, "status", "numberReady")
+		if ready < desired {
+			return false, fmt.Sprintf("%d of %d pods ready", ready, desired)
+		}
+	case schema.GroupKind{Group: "batch", Kind: "Job"}:
+		if hasCondition(u, "Failed") {
+			return false, "job failed"
+		}
+		if !hasCondition(u, "Complete") {
+			return false, "job not complete"
+		}
+	case schema.GroupKind{Kind: "PersistentVolumeClaim"}:
+		phase, _, _ := unstructured.NestedString(u.Object, "status", "phase")
+		if phase != "Bound" {
+			return false, fmt.Sprintf("claim is %s", strings.ToLower(phaseOrPending(phase)))
+		}
+	}
+	return true, ""
+}
+
+// observedGeneration returns false if the controller of u has not yet
+// observed its latest spec.
+func observedGeneration(u *unstructured.Unstructured) (string, bool) {
+	observed, _, _ := unstructured.NestedInt64(u.Object, "status", "observedGeneration")
+	if observed < u.GetGeneration() {
+		return "waiting for the latest generation to be observed", false
+	}
+	return "", true
+}
+
+func replicasOf(u *unstructured.Unstructured) int64 {
+	replicas, found, _ := unstructured.NestedInt64(u.Object, "spec", "replicas")
+	if !found {
+		return 1
+	}
+	return replicas
+}
+
+func hasCondition(u *unstructured.Unstructured, conditionType string) bool {
+	conditions, _, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+	for _, c := range conditions {
+		c, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if c["type"] == conditionType && c["status"] == "True" {
+			return true
+		}
+	}
+	return false
+}
+
+func phaseOrPending(phase string) string {
+	if phase == "" {
+		return "Pending"
+	}
+	return phase
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
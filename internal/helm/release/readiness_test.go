@@ -0,0 +1,142 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newReadinessTestObject(apiVersion, kind string, generation int64, spec, status map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"name":       "test",
+			"namespace":  "ns",
+			"generation": generation,
+		},
+	}}
+	if spec != nil {
+		u.Object["spec"] = spec
+	}
+	if status != nil {
+		u.Object["status"] = status
+	}
+	return u
+}
+
+func TestResourceReady(t *testing.T) {
+	tests := []struct {
+		name          string
+		obj           *unstructured.Unstructured
+		expectReady   bool
+		expectMessage string
+	}{
+		{
+			name: "available deployment",
+			obj: newReadinessTestObject("apps/v1", "Deployment", 2,
+				map[string]interface{}{"replicas": int64(3)},
+				map[string]interface{}{"observedGeneration": int64(2), "updatedReplicas": int64(3), "availableReplicas": int64(3)}),
+			expectReady: true,
+		},
+		{
+			name: "deployment with default replicas",
+			obj: newReadinessTestObject("apps/v1", "Deployment", 1, nil,
+				map[string]interface{}{"observedGeneration": int64(1), "updatedReplicas": int64(1), "availableReplicas": int64(1)}),
+			expectReady: true,
+		},
+		{
+			name: "unavailable deployment",
+			obj: newReadinessTestObject("apps/v1", "Deployment", 2,
+				map[string]interface{}{"replicas": int64(3)},
+				map[string]interface{}{"observedGeneration": int64(2), "updatedReplicas": int64(3), "availableReplicas": int64(1)}),
+			expectMessage: "1 of 3 updated replicas available",
+		},
+		{
+			name: "deployment generation not observed",
+			obj: newReadinessTestObject("apps/v1", "Deployment", 3,
+				map[string]interface{}{"replicas": int64(1)},
+				map[string]interface{}{"observedGeneration": int64(2), "updatedReplicas": int64(1), "availableReplicas": int64(1)}),
+			expectMessage: "waiting for the latest generation to be observed",
+		},
+		{
+			name: "ready statefulset",
+			obj: newReadinessTestObject("apps/v1", "StatefulSet", 1,
+				map[string]interface{}{"replicas": int64(2)},
+				map[string]interface{}{"observedGeneration": int64(1), "readyReplicas": int64(2)}),
+			expectReady: true,
+		},
+		{
+			name: "unready statefulset",
+			obj: newReadinessTestObject("apps/v1", "StatefulSet", 1,
+				map[string]interface{}{"replicas": int64(2)},
+				map[string]interface{}{"observedGeneration": int64(1)}),
+			expectMessage: "0 of 2 replicas ready",
+		},
+		{
+			name: "unready daemonset",
+			obj: newReadinessTestObject("apps/v1", "DaemonSet", 1, nil,
+				map[string]interface{}{"observedGeneration": int64(1), "desiredNumberScheduled": int64(3), "numberReady": int64(2)}),
+			expectMessage: "2 of 3 pods ready",
+		},
+		{
+			name: "complete job",
+			obj: newReadinessTestObject("batch/v1", "Job", 1, nil,
+				map[string]interface{}{"conditions": []interface{}{
+					map[string]interface{}{"type": "Complete", "status": "True"},
+				}}),
+			expectReady: true,
+		},
+		{
+			name:          "running job",
+			obj:           newReadinessTestObject("batch/v1", "Job", 1, nil, map[string]interface{}{"active": int64(1)}),
+			expectMessage: "job not complete",
+		},
+		{
+			name: "failed job",
+			obj: newReadinessTestObject("batch/v1", "Job", 1, nil,
+				map[string]interface{}{"conditions": []interface{}{
+					map[string]interface{}{"type": "Failed", "status": "True"},
+				}}),
+			expectMessage: "job failed",
+		},
+		{
+			name:        "bound pvc",
+			obj:         newReadinessTestObject("v1", "PersistentVolumeClaim", 0, nil, map[string]interface{}{"phase": "Bound"}),
+			expectReady: true,
+		},
+		{
+			name:          "pending pvc",
+			obj:           newReadinessTestObject("v1", "PersistentVolumeClaim", 0, nil, nil),
+			expectMessage: "claim is pending",
+		},
+		{
+			name:        "configmap",
+			obj:         newReadinessTestObject("v1", "ConfigMap", 0, nil, nil),
+			expectReady: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ready, message := resourceReady(test.obj)
+			assert.Equal(t, test.expectReady, ready)
+			assert.Equal(t, test.expectMessage, message)
+		})
+	}
+}
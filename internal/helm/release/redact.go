@@ -0,0 +1,117 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// redactedValue replaces the values of Secret data that must not be logged
+// or recorded in the status of custom resources.
+const redactedValue = "REDACTED"
+
+// secretDataFields are the fields of Secrets that hold their data.
+var secretDataFields = []string{"data", "stringData"}
+
+func isSecret(gvk schema.GroupVersionKind) bool {
+	return gvk.Group == "" && gvk.Kind == "Secret"
+}
+
+// RedactedPatch returns the patch of d, with the values of the data and
+// stringData keys replaced if the drifted resource is a Secret. It returns
+// nil if the patch of a Secret cannot be parsed.
+func (d Drift) RedactedPatch() []byte {
+	if len(d.Patch) == 0 || !isSecret(d.GroupVersionKind) {
+		return d.Patch
+	}
+	patch := map[string]interface{}{}
+	if err := json.Unmarshal(d.Patch, &patch); err != nil {
+		return nil
+	}
+	redactSecretData(patch)
+	redacted, err := json.Marshal(patch)
+	if err != nil {
+		return nil
+	}
+	return redacted
+}
+
+// RedactManifest returns manifest with the values of the data and stringData
+// keys of its Secrets replaced, so that it can be reported without exposing
+// the data of the Secrets. The resources of manifest are returned in order,
+// each preceded by a document separator.
+func RedactManifest(manifest string) string {
+	manifests := releaseutil.SplitManifests(manifest)
+	keys := make([]string, 0, len(manifests))
+	for k := range manifests {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString("---\n")
+		b.WriteString(redactSecretManifest(manifests[k]))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// redactSecretManifest returns the manifest of a single resource, with its
+// data redacted if it is a Secret. Other manifests are returned as is.
+func redactSecretManifest(manifest string) string {
+	obj := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(manifest), &obj); err != nil {
+		return manifest
+	}
+	u := &unstructured.Unstructured{Object: obj}
+	if !isSecret(u.GroupVersionKind()) {
+		return manifest
+	}
+	redactSecretData(obj)
+	out, err := yaml.Marshal(obj)
+	if err != nil {
+		return manifest
+	}
+	redacted := strings.TrimSuffix(string(out), "\n")
+	if source := manifestSource(manifest); source != "" {
+		redacted = sourceCommentPrefix + source + "\n" + redacted
+	}
+	return redacted
+}
+
+// redactSecretData replaces the values of the data and stringData keys of
+// the Secret obj in place. Removed keys, which are null in patches, are kept
+// as is.
+func redactSecretData(obj map[string]interface{}) {
+	for _, field := range secretDataFields {
+		data, ok := obj[field].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for k, v := range data {
+			if v != nil {
+				data[k] = redactedValue
+			}
+		}
+	}
+}
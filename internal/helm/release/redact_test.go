@@ -0,0 +1,111 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestDriftRedactedPatch(t *testing.T) {
+	secret := schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+	configMap := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+
+	tests := []struct {
+		name     string
+		drift    Drift
+		expected string
+	}{
+		{
+			name:     "secret",
+			drift:    Drift{GroupVersionKind: secret, Patch: []byte(`{"data":{"password":"c2VjcmV0","removed":null},"stringData":{"token":"secret"},"type":"Opaque"}`)},
+			expected: `{"data":{"password":"REDACTED","removed":null},"stringData":{"token":"REDACTED"},"type":"Opaque"}`,
+		},
+		{
+			name:     "secret metadata",
+			drift:    Drift{GroupVersionKind: secret, Patch: []byte(`{"metadata":{"labels":{"app":"test"}}}`)},
+			expected: `{"metadata":{"labels":{"app":"test"}}}`,
+		},
+		{
+			name:     "invalid secret patch",
+			drift:    Drift{GroupVersionKind: secret, Patch: []byte(`{"data":`)},
+			expected: "",
+		},
+		{
+			name:     "config map",
+			drift:    Drift{GroupVersionKind: configMap, Patch: []byte(`{"data":{"key":"value"}}`)},
+			expected: `{"data":{"key":"value"}}`,
+		},
+		{
+			name:     "custom secret kind",
+			drift:    Drift{GroupVersionKind: schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Secret"}, Patch: []byte(`{"data":{"key":"value"}}`)},
+			expected: `{"data":{"key":"value"}}`,
+		},
+		{
+			name:     "created",
+			drift:    Drift{GroupVersionKind: secret, Action: DriftActionCreated},
+			expected: "",
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, string(test.drift.RedactedPatch()), test.name)
+	}
+}
+
+func TestRedactManifest(t *testing.T) {
+	manifest := `---
+# Source: test-chart/templates/secret.yaml
+apiVersion: v1
+kind: Secret
+metadata:
+  name: test-credentials
+data:
+  password: c2VjcmV0
+stringData:
+  token: secret
+---
+# Source: test-chart/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test-config
+data:
+  key: value
+`
+	expected := `---
+# Source: test-chart/templates/secret.yaml
+apiVersion: v1
+data:
+  password: REDACTED
+kind: Secret
+metadata:
+  name: test-credentials
+stringData:
+  token: REDACTED
+---
+# Source: test-chart/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test-config
+data:
+  key: value
+`
+	assert.Equal(t, expected, RedactManifest(manifest))
+	assert.Equal(t, "", RedactManifest(""))
+}
@@ -0,0 +1,232 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/client"
+)
+
+// sourceCommentPrefix precedes the template path of rendered manifests.
+const sourceCommentPrefix = "# Source: "
+
+// renderUID is the UID of custom resources rendered without one, which
+// owner references require.
+const renderUID apitypes.UID = "00000000-0000-0000-0000-000000000000"
+
+// clusterScopedKinds are the built-in kinds that are not namespaced.
+var clusterScopedKinds = map[schema.GroupKind]bool{
+	{Kind: "ComponentStatus"}:  true,
+	{Kind: "Namespace"}:        true,
+	{Kind: "Node"}:             true,
+	{Kind: "PersistentVolume"}: true,
+	{Group: "admissionregistration.k8s.io", Kind: "MutatingWebhookConfiguration"}:   true,
+	{Group: "admissionregistration.k8s.io", Kind: "ValidatingWebhookConfiguration"}: true,
+	{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}:               true,
+	{Group: "apiregistration.k8s.io", Kind: "APIService"}:                           true,
+	{Group: "certificates.k8s.io", Kind: "CertificateSigningRequest"}:               true,
+	{Group: "flowcontrol.apiserver.k8s.io", Kind: "FlowSchema"}:                     true,
+	{Group: "flowcontrol.apiserver.k8s.io", Kind: "PriorityLevelConfiguration"}:     true,
+	{Group: "networking.k8s.io", Kind: "IngressClass"}:                              true,
+	{Group: "node.k8s.io", Kind: "RuntimeClass"}:                                    true,
+	{Group: "policy", Kind: "PodSecurityPolicy"}:                                    true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}:                       true,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}:                true,
+	{Group: "scheduling.k8s.io", Kind: "PriorityClass"}:                             true,
+	{Group: "storage.k8s.io", Kind: "CSIDriver"}:                                    true,
+	{Group: "storage.k8s.io", Kind: "CSINode"}:                                      true,
+	{Group: "storage.k8s.io", Kind: "StorageClass"}:                                 true,
+	{Group: "storage.k8s.io", Kind: "VolumeAttachment"}:                             true,
+}
+
+// Renderer renders the manifests that the Managers created by a
+// ManagerFactory apply for custom resources, without contacting the cluster.
+type Renderer struct {
+	factory *managerFactory
+}
+
+// NewRenderer returns a Renderer for the chart in chartDir, configured with
+// the same options as a ManagerFactory. Options that only apply to releases
+// in the cluster, such as MaxHistory, are ignored.
+func NewRenderer(chartDir string, opts ...ManagerFactoryOption) *Renderer {
+	f := &managerFactory{
+		chartDir: chartDir,
+		charts:   newChartCache(chartDir),
+	}
+	for _, o := range opts {
+		o(f)
+	}
+	return &Renderer{factory: f}
+}
+
+// Render writes to w the manifests of the release of cr, including its hooks,
+// as they would be applied when the release is installed. The release values
+// are the spec of cr, merged with sourceValues and then overrideValues, and
+// owner references or annotations referring to cr are injected into the
+// manifests. Resources whose kinds are not built-in and not defined by the
+// chart's CRDs are assumed to be namespaced.
+func (r *Renderer) Render(w io.Writer, cr *unstructured.Unstructured, sourceValues map[string]interface{},
+	overrideValues map[string]string) error {
+	f := r.factory
+	ns, err := f.targetNamespace.For(cr)
+	if err != nil {
+		return err
+	}
+	values, err := f.values(cr, sourceValues, overrideValues)
+	if err != nil {
+		return err
+	}
+	crChart, err := f.charts.Get()
+	if err != nil {
+		return err
+	}
+
+	install := action.NewInstall(&action.Configuration{Log: func(_ string, _ ...interface{}) {}})
+	install.ReleaseName = f.releaseName(cr, ns)
+	install.Namespace = ns
+	install.DryRun = true
+	install.ClientOnly = true
+	install.Replace = true
+	install.PostRenderer = f.postRenderer
+	rel, err := install.Run(crChart, values)
+	if err != nil {
+		return fmt.Errorf("failed to render chart: %w", err)
+	}
+
+	owner := cr.DeepCopy()
+	if owner.GetUID() == "" {
+		owner.SetUID(renderUID)
+	}
+	restMapper := newLocalRESTMapper(owner, crChart)
+	kubeClient := kube.New(client.NewLocalRESTClientGetter(restMapper, ns))
+	ownerRefClient, err := client.NewOwnerRefInjectingClient(*kubeClient, restMapper, owner)
+	if err != nil {
+		return fmt.Errorf("failed to inject owner references: %w", err)
+	}
+
+	manifests := releaseutil.SplitManifests(rel.Manifest)
+	keys := make([]string, 0, len(manifests))
+	for k := range manifests {
+		keys = append(keys, k)
+	}
+	sort.Sort(releaseutil.BySplitManifestsOrder(keys))
+	for _, k := range keys {
+		if err := writeManifest(w, ownerRefClient, manifestSource(manifests[k]), manifests[k]); err != nil {
+			return err
+		}
+	}
+	for _, hook := range rel.Hooks {
+		if err := writeManifest(w, ownerRefClient, hook.Path, hook.Manifest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeManifest writes to w the resources of manifest built by kubeClient,
+// preceded by the source of manifest.
+func writeManifest(w io.Writer, kubeClient kube.Interface, source, manifest string) error {
+	resources, err := kubeClient.Build(bytes.NewBufferString(manifest), false)
+	if err != nil {
+		return fmt.Errorf("failed to build resources from %s: %w", source, err)
+	}
+	for _, info := range resources {
+		out, err := yaml.Marshal(info.Object)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s%s\n%s", sourceCommentPrefix, source, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// manifestSource returns the path of the template that manifest was rendered
+// from, which Helm records in a comment.
+func manifestSource(manifest string) string {
+	for _, line := range strings.Split(manifest, "\n") {
+		if strings.HasPrefix(line, sourceCommentPrefix) {
+			return strings.TrimPrefix(line, sourceCommentPrefix)
+		}
+	}
+	return ""
+}
+
+// localRESTMapper maps the kinds of rendered resources to guessed resources
+// without discovery. Kinds are namespaced, unless they are built-in
+// cluster-scoped kinds, cluster-scoped kinds defined by the CRDs of the
+// chart, or the kind of a cluster-scoped owner.
+type localRESTMapper struct {
+	meta.RESTMapper
+	clusterScoped map[schema.GroupKind]bool
+}
+
+func newLocalRESTMapper(owner *unstructured.Unstructured, crChart *chart.Chart) *localRESTMapper {
+	clusterScoped := make(map[schema.GroupKind]bool, len(clusterScopedKinds))
+	for gk := range clusterScopedKinds {
+		clusterScoped[gk] = true
+	}
+	if owner.GetNamespace() == "" {
+		clusterScoped[owner.GroupVersionKind().GroupKind()] = true
+	}
+	for _, crd := range crChart.CRDObjects() {
+		for _, manifest := range releaseutil.SplitManifests(string(crd.File.Data)) {
+			obj := map[string]interface{}{}
+			if err := yaml.Unmarshal([]byte(manifest), &obj); err != nil {
+				continue
+			}
+			scope, _, _ := unstructured.NestedString(obj, "spec", "scope")
+			if scope != "Cluster" {
+				continue
+			}
+			group, _, _ := unstructured.NestedString(obj, "spec", "group")
+			kind, _, _ := unstructured.NestedString(obj, "spec", "names", "kind")
+			clusterScoped[schema.GroupKind{Group: group, Kind: kind}] = true
+		}
+	}
+	return &localRESTMapper{
+		RESTMapper:    meta.NewDefaultRESTMapper(nil),
+		clusterScoped: clusterScoped,
+	}
+}
+
+func (m *localRESTMapper) RESTMapping(gk schema.GroupKind, versions ...string) (*meta.RESTMapping, error) {
+	if len(versions) == 0 || versions[0] == "" {
+		return nil, &meta.NoKindMatchError{GroupKind: gk, SearchedVersions: versions}
+	}
+	gvk := gk.WithVersion(versions[0])
+	resource, _ := meta.UnsafeGuessKindToResource(gvk)
+	scope := meta.RESTScopeNamespace
+	if m.clusterScoped[gk] {
+		scope = meta.RESTScopeRoot
+	}
+	return &meta.RESTMapping{Resource: resource, GroupVersionKind: gvk, Scope: scope}, nil
+}
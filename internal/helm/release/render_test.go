@@ -0,0 +1,110 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"helm.sh/helm/v3/pkg/chart"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+func TestRendererRender(t *testing.T) {
+	cr := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Test",
+		"metadata":   map[string]interface{}{"name": "test", "namespace": "ns"},
+		"spec":       map[string]interface{}{"replicaCount": int64(2), "targetRevision": int64(1)},
+	}}
+
+	var out bytes.Buffer
+	r := NewRenderer(testChartDir, IgnoredSpecFields("targetRevision"))
+	err := r.Render(&out, cr, map[string]interface{}{"replicaCount": int64(3)}, map[string]string{"image.repository": "example/nginx"})
+	assert.NoError(t, err)
+
+	objs := map[string]*unstructured.Unstructured{}
+	for _, manifest := range bytes.Split(out.Bytes(), []byte("---\n")) {
+		if len(bytes.TrimSpace(manifest)) == 0 {
+			continue
+		}
+		// Decode with the unstructured JSON decoder, which keeps integers as int64.
+		j, err := yaml.YAMLToJSON(manifest)
+		assert.NoError(t, err)
+		obj := &unstructured.Unstructured{}
+		assert.NoError(t, obj.UnmarshalJSON(j))
+		objs[obj.GetKind()+"/"+obj.GetName()] = obj
+	}
+	assert.Contains(t, out.String(), "# Source: test-chart/templates/deployment.yaml\n")
+	assert.Contains(t, objs, "Pod/test-test-chart-test-connection", "hooks are rendered")
+
+	deployment := objs["Deployment/test-test-chart"]
+	if assert.NotNil(t, deployment) {
+		assert.Equal(t, "ns", deployment.GetNamespace())
+		replicas, _, _ := unstructured.NestedInt64(deployment.Object, "spec", "replicas")
+		assert.Equal(t, int64(3), replicas, "source values are merged over the spec")
+		containers, _, _ := unstructured.NestedSlice(deployment.Object, "spec", "template", "spec", "containers")
+		if assert.Len(t, containers, 1) {
+			assert.Equal(t, "example/nginx:1.16.0", containers[0].(map[string]interface{})["image"])
+		}
+		if ownerRefs := deployment.GetOwnerReferences(); assert.Len(t, ownerRefs, 1) {
+			assert.Equal(t, "Test", ownerRefs[0].Kind)
+			assert.Equal(t, "test", ownerRefs[0].Name)
+			assert.Equal(t, renderUID, ownerRefs[0].UID)
+		}
+	}
+	// The custom resource is not modified.
+	assert.Empty(t, cr.GetUID())
+}
+
+func TestLocalRESTMapper(t *testing.T) {
+	crd := []byte(`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: clusterthings.example.com
+spec:
+  group: example.com
+  scope: Cluster
+  names:
+    kind: ClusterThing
+`)
+	crChart := &chart.Chart{Files: []*chart.File{{Name: "crds/clusterthing.yaml", Data: crd}}}
+	owner := &unstructured.Unstructured{}
+	owner.SetGroupVersionKind(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Test"})
+	m := newLocalRESTMapper(owner, crChart)
+
+	testCases := []struct {
+		gvk         schema.GroupVersionKind
+		expectScope meta.RESTScopeName
+	}{
+		{schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNameNamespace},
+		{schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}, meta.RESTScopeNameRoot},
+		{schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "ClusterThing"}, meta.RESTScopeNameRoot},
+		{schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Test"}, meta.RESTScopeNameRoot},
+		{schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Other"}, meta.RESTScopeNameNamespace},
+	}
+	for _, tc := range testCases {
+		mapping, err := m.RESTMapping(tc.gvk.GroupKind(), tc.gvk.Version)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expectScope, mapping.Scope.Name(), tc.gvk.String())
+	}
+
+	_, err := m.RESTMapping(schema.GroupKind{Kind: "ConfigMap"})
+	assert.True(t, meta.IsNoMatchError(err))
+}
@@ -0,0 +1,135 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/internal/types"
+)
+
+// TargetNamespace determines the namespace in which the releases of custom
+// resources are installed and stored. By default, releases of namespaced
+// custom resources are installed in their namespace.
+type TargetNamespace struct {
+	// Name is the namespace of all releases.
+	Name string
+	// SpecField is a top-level field of the spec of custom resources which,
+	// when set, overrides Name. It is not passed to the chart as a value.
+	SpecField string
+}
+
+// Validate returns an error if t is not a valid target namespace.
+func (t TargetNamespace) Validate() error {
+	if t.Name != "" {
+		if errs := validation.IsDNS1123Label(t.Name); len(errs) > 0 {
+			return fmt.Errorf("invalid namespace %q: %s", t.Name, strings.Join(errs, ", "))
+		}
+	}
+	if strings.Contains(t.SpecField, ".") {
+		return fmt.Errorf("spec field %q must be a top-level field", t.SpecField)
+	}
+	return nil
+}
+
+// For returns the namespace of the releases of cr.
+func (t TargetNamespace) For(cr *unstructured.Unstructured) (string, error) {
+	ns := t.Name
+	if t.SpecField != "" {
+		specNs, _, err := unstructured.NestedString(cr.Object, "spec", t.SpecField)
+		if err != nil {
+			return "", fmt.Errorf("invalid target namespace: %w", err)
+		}
+		if specNs != "" {
+			ns = specNs
+		}
+	}
+	if ns == "" {
+		ns = cr.GetNamespace()
+	}
+	if ns == "" {
+		return "", errors.New("a target namespace is required for the releases of cluster-scoped resources")
+	}
+	if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+		return "", fmt.Errorf("invalid target namespace %q: %s", ns, strings.Join(errs, ", "))
+	}
+	return ns, nil
+}
+
+// values returns the spec of cr, without SpecField.
+func (t TargetNamespace) values(cr *unstructured.Unstructured) (map[string]interface{}, error) {
+	crValues, ok := cr.Object["spec"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("failed to get spec: expected map[string]interface{}")
+	}
+	if t.SpecField == "" {
+		return crValues, nil
+	}
+	return withoutFields(crValues, t.SpecField), nil
+}
+
+// withoutFields returns values without the top-level fields. values is not
+// modified.
+func withoutFields(values map[string]interface{}, fields ...string) map[string]interface{} {
+	out, copied := values, false
+	for _, field := range fields {
+		if _, ok := out[field]; !ok {
+			continue
+		}
+		if !copied {
+			out = make(map[string]interface{}, len(values))
+			for k, v := range values {
+				out[k] = v
+			}
+			copied = true
+		}
+		delete(out, field)
+	}
+	return out
+}
+
+// defaultReleaseName returns the name of the release of cr in namespace ns. The
+// releases of custom resources in other namespaces are prefixed with the
+// namespace of the custom resource, so that the releases of custom resources
+// with the same name do not collide.
+func defaultReleaseName(cr *unstructured.Unstructured, ns string) string {
+	if cr.GetNamespace() == "" || cr.GetNamespace() == ns {
+		return cr.GetName()
+	}
+	return fmt.Sprintf("%s-%s", cr.GetNamespace(), cr.GetName())
+}
+
+// deployedNamespace returns the namespace of the deployed release of the
+// named chart recorded in status, or of the deployed release if chart is
+// empty. It is empty if no release namespace was recorded.
+func deployedNamespace(status *types.HelmAppStatus, chart string) string {
+	if chart == "" {
+		if status.DeployedRelease != nil {
+			return status.DeployedRelease.Namespace
+		}
+		return ""
+	}
+	for _, chartRelease := range status.ChartReleases {
+		if chartRelease.Name == chart && chartRelease.DeployedRelease != nil {
+			return chartRelease.DeployedRelease.Namespace
+		}
+	}
+	return ""
+}
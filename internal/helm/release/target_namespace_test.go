@@ -0,0 +1,142 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/internal/types"
+)
+
+func newTestCR(namespace string, spec map[string]interface{}) *unstructured.Unstructured {
+	cr := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	cr.SetNamespace(namespace)
+	cr.SetName("test")
+	return cr
+}
+
+func TestTargetNamespaceFor(t *testing.T) {
+	testCases := []struct {
+		name            string
+		targetNamespace TargetNamespace
+		cr              *unstructured.Unstructured
+		expectNamespace string
+		expectErr       bool
+	}{
+		{
+			name:            "namespace of custom resource",
+			cr:              newTestCR("ns", map[string]interface{}{}),
+			expectNamespace: "ns",
+		},
+		{
+			name:            "target namespace",
+			targetNamespace: TargetNamespace{Name: "target"},
+			cr:              newTestCR("ns", map[string]interface{}{}),
+			expectNamespace: "target",
+		},
+		{
+			name:            "target namespace from spec",
+			targetNamespace: TargetNamespace{Name: "target", SpecField: "namespace"},
+			cr:              newTestCR("ns", map[string]interface{}{"namespace": "from-spec"}),
+			expectNamespace: "from-spec",
+		},
+		{
+			name:            "unset spec field",
+			targetNamespace: TargetNamespace{Name: "target", SpecField: "namespace"},
+			cr:              newTestCR("ns", map[string]interface{}{}),
+			expectNamespace: "target",
+		},
+		{
+			name:            "cluster-scoped",
+			targetNamespace: TargetNamespace{SpecField: "namespace"},
+			cr:              newTestCR("", map[string]interface{}{"namespace": "from-spec"}),
+			expectNamespace: "from-spec",
+		},
+		{
+			name:      "cluster-scoped without target namespace",
+			cr:        newTestCR("", map[string]interface{}{}),
+			expectErr: true,
+		},
+		{
+			name:            "invalid namespace in spec",
+			targetNamespace: TargetNamespace{SpecField: "namespace"},
+			cr:              newTestCR("ns", map[string]interface{}{"namespace": "Not_Valid"}),
+			expectErr:       true,
+		},
+		{
+			name:            "non-string namespace in spec",
+			targetNamespace: TargetNamespace{SpecField: "namespace"},
+			cr:              newTestCR("ns", map[string]interface{}{"namespace": int64(1)}),
+			expectErr:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ns, err := tc.targetNamespace.For(tc.cr)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectNamespace, ns)
+		})
+	}
+}
+
+func TestTargetNamespaceValues(t *testing.T) {
+	cr := newTestCR("ns", map[string]interface{}{"namespace": "target", "replicaCount": int64(2)})
+
+	values, err := TargetNamespace{}.values(cr)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"namespace": "target", "replicaCount": int64(2)}, values)
+
+	values, err = TargetNamespace{SpecField: "namespace"}.values(cr)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"replicaCount": int64(2)}, values)
+	// The spec of the custom resource is not modified.
+	assert.Contains(t, cr.Object["spec"], "namespace")
+}
+
+func TestWithoutFields(t *testing.T) {
+	values := map[string]interface{}{"namespace": "target", "targetRevision": int64(2), "replicaCount": int64(2)}
+	assert.Equal(t, values, withoutFields(values))
+	assert.Equal(t, values, withoutFields(values, "missing"))
+	assert.Equal(t, map[string]interface{}{"replicaCount": int64(2)}, withoutFields(values, "namespace", "targetRevision"))
+	assert.Len(t, values, 3)
+}
+
+func TestDefaultReleaseName(t *testing.T) {
+	assert.Equal(t, "test", defaultReleaseName(newTestCR("ns", nil), "ns"))
+	assert.Equal(t, "test", defaultReleaseName(newTestCR("", nil), "target"))
+	assert.Equal(t, "ns-test", defaultReleaseName(newTestCR("ns", nil), "target"))
+}
+
+func TestDeployedNamespace(t *testing.T) {
+	status := &types.HelmAppStatus{
+		DeployedRelease: &types.HelmAppRelease{Name: "test", Namespace: "target"},
+		ChartReleases: []types.HelmAppChartRelease{
+			{Name: "core", DeployedRelease: &types.HelmAppRelease{Name: "test-core", Namespace: "core-target"}},
+			{Name: "addons", State: types.ChartPending},
+		},
+	}
+	assert.Equal(t, "target", deployedNamespace(status, ""))
+	assert.Equal(t, "core-target", deployedNamespace(status, "core"))
+	assert.Equal(t, "", deployedNamespace(status, "addons"))
+	assert.Equal(t, "", deployedNamespace(&types.HelmAppStatus{}, ""))
+}
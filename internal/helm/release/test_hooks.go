@@ -0,0 +1,95 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"helm.sh/helm/v3/pkg/action"
+	rpb "helm.sh/helm/v3/pkg/release"
+)
+
+// DefaultTestTimeout is the default time to wait for the tests of a release
+// to complete.
+const DefaultTestTimeout = 5 * time.Minute
+
+type TestOption func(*action.ReleaseTesting) error
+
+// TestResult is the result of a test hook of a release.
+type TestResult struct {
+	Name  string
+	Phase rpb.HookPhase
+}
+
+// Passed returns true if the test succeeded.
+func (r TestResult) Passed() bool {
+	return r.Phase == rpb.HookPhaseSucceeded
+}
+
+// NotRun returns true if the test did not run, e.g. because an earlier test
+// failed.
+func (r TestResult) NotRun() bool {
+	return r.Phase == "" || r.Phase == rpb.HookPhaseUnknown
+}
+
+// TestRelease runs the test hooks of the deployed release, and returns the
+// result of each test. An error is returned if any test fails, along with
+// the results of the tests that were run.
+func (m manager) TestRelease(ctx context.Context, opts ...TestOption) ([]TestResult, error) {
+	if m.deployedRelease == nil {
+		return nil, errors.New("failed to test release: no deployed release found")
+	}
+
+	test := action.NewReleaseTesting(m.actionConfig)
+	test.Namespace = m.namespace
+	test.Timeout = DefaultTestTimeout
+	for _, o := range opts {
+		if err := o(test); err != nil {
+			return nil, fmt.Errorf("failed to apply test option: %w", err)
+		}
+	}
+
+	testedRelease, err := test.Run(m.releaseName)
+	return testResults(testedRelease), err
+}
+
+// testResults returns the results of the test hooks of rel, sorted by name.
+func testResults(rel *rpb.Release) []TestResult {
+	if rel == nil {
+		return nil
+	}
+	var results []TestResult
+	for _, h := range rel.Hooks {
+		if !isTestHook(h) {
+			continue
+		}
+		results = append(results, TestResult{Name: h.Name, Phase: h.LastRun.Phase})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return results
+}
+
+func isTestHook(h *rpb.Hook) bool {
+	for _, e := range h.Events {
+		if e == rpb.HookTest {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	rpb "helm.sh/helm/v3/pkg/release"
+)
+
+func TestTestResults(t *testing.T) {
+	rel := &rpb.Release{Hooks: []*rpb.Hook{
+		{
+			Name:    "test-b",
+			Events:  []rpb.HookEvent{rpb.HookTest},
+			LastRun: rpb.HookExecution{Phase: rpb.HookPhaseFailed},
+		},
+		{
+			Name:    "pre-install",
+			Events:  []rpb.HookEvent{rpb.HookPreInstall},
+			LastRun: rpb.HookExecution{Phase: rpb.HookPhaseSucceeded},
+		},
+		{
+			Name:    "test-a",
+			Events:  []rpb.HookEvent{rpb.HookPreUpgrade, rpb.HookTest},
+			LastRun: rpb.HookExecution{Phase: rpb.HookPhaseSucceeded},
+		},
+	}}
+
+	results := testResults(rel)
+	assert.Equal(t, []TestResult{
+		{Name: "test-a", Phase: rpb.HookPhaseSucceeded},
+		{Name: "test-b", Phase: rpb.HookPhaseFailed},
+	}, results)
+	assert.True(t, results[0].Passed())
+	assert.False(t, results[1].Passed())
+	assert.False(t, results[1].NotRun())
+	assert.True(t, TestResult{Name: "test-c"}.NotRun())
+	assert.True(t, TestResult{Name: "test-c", Phase: rpb.HookPhaseUnknown}.NotRun())
+
+	assert.Nil(t, testResults(nil))
+	assert.Nil(t, testResults(&rpb.Release{}))
+}
@@ -0,0 +1,104 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const notesFileSuffix = "NOTES.txt"
+
+// Validator checks that the values of custom resources can be used to
+// install the chart in a chart directory, without contacting the cluster.
+type Validator struct {
+	charts          *chartCache
+	overrideValues  map[string]string
+	targetNamespace TargetNamespace
+	ignoredFields   []string
+}
+
+// NewValidator returns a Validator for the chart in chartDir. Like the
+// Managers created by a ManagerFactory, it merges overrideValues into the
+// values of every custom resource it validates, without the spec fields in
+// ignoredFields, and renders the chart in the namespace set by
+// targetNamespace.
+func NewValidator(chartDir string, overrideValues map[string]string, targetNamespace TargetNamespace,
+	ignoredFields []string) *Validator {
+	return &Validator{
+		charts:          newChartCache(chartDir),
+		overrideValues:  overrideValues,
+		targetNamespace: targetNamespace,
+		ignoredFields:   ignoredFields,
+	}
+}
+
+// Validate returns an error if the spec of cr, merged with sourceValues and
+// the validator's override values, does not meet the specifications of the
+// chart's values.schema.json files, or if the chart cannot be rendered into
+// valid manifests with those values.
+func (v *Validator) Validate(cr *unstructured.Unstructured, sourceValues map[string]interface{}) error {
+	crValues, err := v.targetNamespace.values(cr)
+	if err != nil {
+		return err
+	}
+	crValues = withoutFields(crValues, v.ignoredFields...)
+	ns, err := v.targetNamespace.For(cr)
+	if err != nil {
+		return err
+	}
+	expOverrides, err := parseOverrides(v.overrideValues)
+	if err != nil {
+		return fmt.Errorf("failed to parse override values: %w", err)
+	}
+	values := mergeMaps(mergeMaps(crValues, sourceValues), expOverrides)
+
+	chrt, err := v.charts.Get()
+	if err != nil {
+		return err
+	}
+	if err := chartutil.ProcessDependencies(chrt, values); err != nil {
+		return err
+	}
+	options := chartutil.ReleaseOptions{
+		Name:      defaultReleaseName(cr, ns),
+		Namespace: ns,
+		Revision:  1,
+		IsInstall: true,
+	}
+	renderValues, err := chartutil.ToRenderValues(chrt, values, options, chartutil.DefaultCapabilities)
+	if err != nil {
+		return err
+	}
+	files, err := engine.Render(chrt, renderValues)
+	if err != nil {
+		return err
+	}
+	// Like Helm, ignore rendered notes, which are not manifests.
+	for name := range files {
+		if strings.HasSuffix(name, notesFileSuffix) {
+			delete(files, name)
+		}
+	}
+	if _, _, err := releaseutil.SortManifests(files, chartutil.DefaultCapabilities.APIVersions, releaseutil.InstallOrder); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const testValuesSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "properties": {
+    "replicaCount": {"type": "integer", "minimum": 1},
+    "service": {
+      "type": "object",
+      "properties": {
+        "type": {"type": "string", "enum": ["ClusterIP", "NodePort", "LoadBalancer"]}
+      }
+    }
+  }
+}
+`
+
+func TestValidatorValidate(t *testing.T) {
+	dir := copyTestChart(t)
+	if err := ioutil.WriteFile(filepath.Join(dir, "values.schema.json"), []byte(testValuesSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	newCR := func(spec interface{}) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "example.com/v1",
+			"kind":       "Test",
+			"metadata":   map[string]interface{}{"name": "test", "namespace": "ns"},
+		}}
+		if spec != nil {
+			u.Object["spec"] = spec
+		}
+		return u
+	}
+
+	testCases := []struct {
+		name           string
+		spec           interface{}
+		sourceValues   map[string]interface{}
+		overrideValues map[string]string
+		expectErr      string
+	}{
+		{
+			name: "empty spec",
+			spec: map[string]interface{}{},
+		},
+		{
+			name: "valid spec",
+			spec: map[string]interface{}{"replicaCount": int64(3), "service": map[string]interface{}{"type": "NodePort"}},
+		},
+		{
+			name:      "missing spec",
+			expectErr: "failed to get spec",
+		},
+		{
+			name:      "schema violation",
+			spec:      map[string]interface{}{"replicaCount": int64(0)},
+			expectErr: "values don't meet the specifications of the schema",
+		},
+		{
+			name:         "schema violation in source values",
+			spec:         map[string]interface{}{},
+			sourceValues: map[string]interface{}{"replicaCount": "two"},
+			expectErr:    "values don't meet the specifications of the schema",
+		},
+		{
+			name:           "override values take precedence",
+			spec:           map[string]interface{}{"service": map[string]interface{}{"type": "Invalid"}},
+			overrideValues: map[string]string{"service.type": "ClusterIP"},
+		},
+		{
+			name:           "schema violation in override values",
+			spec:           map[string]interface{}{},
+			overrideValues: map[string]string{"service.type": "Invalid"},
+			expectErr:      "values don't meet the specifications of the schema",
+		},
+		{
+			name:      "render error",
+			spec:      map[string]interface{}{"image": "nginx"},
+			expectErr: "can't evaluate field repository",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := NewValidator(dir, tc.overrideValues, TargetNamespace{}, nil)
+			err := v.Validate(newCR(tc.spec), tc.sourceValues)
+			if tc.expectErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tc.expectErr)
+				}
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
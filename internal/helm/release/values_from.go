@@ -0,0 +1,144 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apitypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ValuesSourceConfigMap is the kind of ConfigMap values sources.
+	ValuesSourceConfigMap = "ConfigMap"
+	// ValuesSourceSecret is the kind of Secret values sources.
+	ValuesSourceSecret = "Secret"
+
+	// DefaultValuesKey is the key of a values source read when no key is set.
+	DefaultValuesKey = "values.yaml"
+)
+
+// ValuesSource references a ConfigMap or Secret in the namespace of a custom
+// resource. The value of its ValuesKey is a YAML document of release values.
+type ValuesSource struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	ValuesKey string `json:"valuesKey,omitempty"`
+	// Optional sources that do not exist, or do not contain ValuesKey, are
+	// ignored.
+	Optional bool `json:"optional,omitempty"`
+}
+
+// Key returns the key of the values in the source.
+func (s ValuesSource) Key() string {
+	if s.ValuesKey == "" {
+		return DefaultValuesKey
+	}
+	return s.ValuesKey
+}
+
+// Validate returns an error if s is not a valid values source.
+func (s ValuesSource) Validate() error {
+	if s.Kind != ValuesSourceConfigMap && s.Kind != ValuesSourceSecret {
+		return fmt.Errorf("kind must be one of %q or %q, got %q", ValuesSourceConfigMap, ValuesSourceSecret, s.Kind)
+	}
+	if s.Name == "" {
+		return errors.New("name must not be empty")
+	}
+	return nil
+}
+
+// LoadValuesFrom reads the values in sources from namespace, and merges them
+// in order. Values in later sources take precedence over values in earlier
+// sources.
+func LoadValuesFrom(ctx context.Context, c client.Reader, namespace string, sources []ValuesSource) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	for _, s := range sources {
+		data, found, err := readValuesSource(ctx, c, namespace, s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read values from %s %s/%s: %w", s.Kind, namespace, s.Name, err)
+		}
+		if !found {
+			if s.Optional {
+				continue
+			}
+			return nil, fmt.Errorf("%s %s/%s does not contain values key %q", s.Kind, namespace, s.Name, s.Key())
+		}
+		sourceValues := map[string]interface{}{}
+		if err := yaml.Unmarshal(data, &sourceValues); err != nil {
+			return nil, fmt.Errorf("failed to parse values from %s %s/%s key %q: %w", s.Kind, namespace, s.Name, s.Key(), err)
+		}
+		values = mergeMaps(values, sourceValues)
+	}
+	return values, nil
+}
+
+// LoadValuesFiles reads the values in files, and merges them in order, like
+// LoadValuesFrom. It is used instead of LoadValuesFrom outside of a cluster.
+func LoadValuesFiles(files []string) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read values file: %w", err)
+		}
+		fileValues := map[string]interface{}{}
+		if err := yaml.Unmarshal(data, &fileValues); err != nil {
+			return nil, fmt.Errorf("failed to parse values from %s: %w", file, err)
+		}
+		values = mergeMaps(values, fileValues)
+	}
+	return values, nil
+}
+
+// readValuesSource returns the data of the values key of s. It returns false
+// if the source does not exist or does not contain the key.
+func readValuesSource(ctx context.Context, c client.Reader, namespace string, s ValuesSource) ([]byte, bool, error) {
+	key := apitypes.NamespacedName{Namespace: namespace, Name: s.Name}
+	switch s.Kind {
+	case ValuesSourceConfigMap:
+		cm := &corev1.ConfigMap{}
+		if err := c.Get(ctx, key, cm); err != nil {
+			if apierrors.IsNotFound(err) && s.Optional {
+				return nil, false, nil
+			}
+			return nil, false, err
+		}
+		if data, ok := cm.Data[s.Key()]; ok {
+			return []byte(data), true, nil
+		}
+		data, ok := cm.BinaryData[s.Key()]
+		return data, ok, nil
+	case ValuesSourceSecret:
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, key, secret); err != nil {
+			if apierrors.IsNotFound(err) && s.Optional {
+				return nil, false, nil
+			}
+			return nil, false, err
+		}
+		data, ok := secret.Data[s.Key()]
+		return data, ok, nil
+	default:
+		return nil, false, s.Validate()
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package release
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLoadValuesFrom(t *testing.T) {
+	c := fakeclient.NewClientBuilder().WithObjects(
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "values", Namespace: "ns"},
+			Data: map[string]string{
+				"values.yaml": "replicaCount: 2\nimage:\n  repository: nginx\n  tag: stable\n",
+				"other.yaml":  "replicaCount: 3\n",
+				"invalid":     "- not\n- a map\n",
+			},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "ns"},
+			Data: map[string][]byte{
+				"values.yaml": []byte("image:\n  tag: latest\n  pullSecret: secret\n"),
+			},
+		},
+	).Build()
+
+	testCases := []struct {
+		name         string
+		sources      []ValuesSource
+		expectValues map[string]interface{}
+		expectErr    bool
+	}{
+		{
+			name:         "no sources",
+			expectValues: map[string]interface{}{},
+		},
+		{
+			name: "merged in order",
+			sources: []ValuesSource{
+				{Kind: ValuesSourceConfigMap, Name: "values"},
+				{Kind: ValuesSourceSecret, Name: "credentials"},
+			},
+			expectValues: map[string]interface{}{
+				"replicaCount": float64(2),
+				"image": map[string]interface{}{
+					"repository": "nginx",
+					"tag":        "latest",
+					"pullSecret": "secret",
+				},
+			},
+		},
+		{
+			name: "values key",
+			sources: []ValuesSource{
+				{Kind: ValuesSourceConfigMap, Name: "values", ValuesKey: "other.yaml"},
+			},
+			expectValues: map[string]interface{}{"replicaCount": float64(3)},
+		},
+		{
+			name: "optional missing source",
+			sources: []ValuesSource{
+				{Kind: ValuesSourceSecret, Name: "missing", Optional: true},
+				{Kind: ValuesSourceConfigMap, Name: "values", ValuesKey: "missing", Optional: true},
+				{Kind: ValuesSourceConfigMap, Name: "values", ValuesKey: "other.yaml"},
+			},
+			expectValues: map[string]interface{}{"replicaCount": float64(3)},
+		},
+		{
+			name:      "missing source",
+			sources:   []ValuesSource{{Kind: ValuesSourceSecret, Name: "missing"}},
+			expectErr: true,
+		},
+		{
+			name:      "missing key",
+			sources:   []ValuesSource{{Kind: ValuesSourceConfigMap, Name: "values", ValuesKey: "missing"}},
+			expectErr: true,
+		},
+		{
+			name:      "invalid values",
+			sources:   []ValuesSource{{Kind: ValuesSourceConfigMap, Name: "values", ValuesKey: "invalid"}},
+			expectErr: true,
+		},
+		{
+			name:      "invalid kind",
+			sources:   []ValuesSource{{Kind: "Pod", Name: "values"}},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			values, err := LoadValuesFrom(context.TODO(), c, "ns", tc.sources)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectValues, values)
+		})
+	}
+}
+
+func TestLoadValuesFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, ioutil.WriteFile(path, []byte(data), 0644))
+		return path
+	}
+	values := writeFile("values.yaml", "replicaCount: 2\nimage:\n  repository: nginx\n  tag: stable\n")
+	overrides := writeFile("overrides.yaml", "image:\n  tag: latest\n")
+	invalid := writeFile("invalid.yaml", "- not\n- a map\n")
+
+	merged, err := LoadValuesFiles([]string{values, overrides})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"replicaCount": float64(2),
+		"image":        map[string]interface{}{"repository": "nginx", "tag": "latest"},
+	}, merged)
+
+	_, err = LoadValuesFiles([]string{invalid})
+	assert.Error(t, err)
+	_, err = LoadValuesFiles([]string{filepath.Join(dir, "missing.yaml")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,160 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watches
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/getter"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/repo"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/internal/oci"
+)
+
+// isRemoteChart returns true if the watch references a chart that must be
+// fetched from an OCI registry or a chart repository.
+func isRemoteChart(w Watch) bool {
+	return w.Repository != "" || oci.IsOCI(w.ChartDir) ||
+		strings.HasPrefix(w.ChartDir, "http://") || strings.HasPrefix(w.ChartDir, "https://")
+}
+
+func verifyRemoteChart(w Watch) error {
+	if w.Verify && w.Keyring == "" {
+		return errors.New("keyring must be set when verify is enabled")
+	}
+	if oci.IsOCI(w.ChartDir) {
+		if w.Repository != "" {
+			return errors.New("repository must not be set for OCI charts")
+		}
+		if w.ChartVersion == "" {
+			return errors.New("chartVersion must be set for OCI charts")
+		}
+		if w.Verify {
+			return errors.New("provenance verification is not supported for OCI charts")
+		}
+	}
+	return nil
+}
+
+// FetchCharts downloads the remote charts referenced by watches, which must
+// have been loaded and verified by Load or LoadReader, into the operator's
+// chart cache. The ChartDir of each watch with a remote chart is set to the
+// directory of the expanded chart.
+//
+// Charts are fetched every time they are called for, so that charts
+// referenced without a version are kept up to date across operator restarts.
+func FetchCharts(watches []Watch) error {
+	for i, w := range watches {
+		if len(w.Charts) > 0 || !isRemoteChart(w) {
+			continue
+		}
+		chartDir, err := fetchChart(w)
+		if err != nil {
+			return fmt.Errorf("could not fetch chart %s for GVK %s: %w", w.ChartDir, w.GroupVersionKind, err)
+		}
+		if _, err := chartutil.IsChartDir(chartDir); err != nil {
+			return fmt.Errorf("invalid chart directory %s: %w", chartDir, err)
+		}
+		// The watch now references the fetched chart, which is local.
+		watches[i].ChartDir = chartDir
+		watches[i].Repository = ""
+	}
+	return nil
+}
+
+// fetchChart downloads the remote chart referenced by w into the operator's
+// chart cache, and returns the directory of the expanded chart.
+func fetchChart(w Watch) (string, error) {
+	key := sha256.Sum256([]byte(strings.Join([]string{w.Repository, w.ChartDir, w.ChartVersion}, "\x00")))
+	cacheDir := helmpath.CachePath("operator", "charts", hex.EncodeToString(key[:8]))
+	if err := os.MkdirAll(filepath.Dir(cacheDir), 0755); err != nil {
+		return "", fmt.Errorf("failed to create chart cache: %w", err)
+	}
+
+	tmpDir, err := ioutil.TempDir(filepath.Dir(cacheDir), "download")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var archive string
+	if oci.IsOCI(w.ChartDir) {
+		archive, err = oci.Client{}.Pull(w.ChartDir, w.ChartVersion, tmpDir)
+	} else {
+		archive, err = downloadChart(w, tmpDir)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	chrt, err := loader.Load(archive)
+	if err != nil {
+		return "", fmt.Errorf("failed to load chart archive: %w", err)
+	}
+	if err := chartutil.ExpandFile(tmpDir, archive); err != nil {
+		return "", fmt.Errorf("failed to expand chart archive: %w", err)
+	}
+
+	// Replace the previously cached chart, if any, with the expanded chart.
+	if err := os.RemoveAll(cacheDir); err != nil {
+		return "", fmt.Errorf("failed to remove cached chart: %w", err)
+	}
+	if err := os.Rename(filepath.Join(tmpDir, chrt.Name()), cacheDir); err != nil {
+		return "", fmt.Errorf("failed to cache chart: %w", err)
+	}
+	return cacheDir, nil
+}
+
+func downloadChart(w Watch, destDir string) (string, error) {
+	settings := cli.New()
+	getters := getter.All(settings)
+	c := downloader.ChartDownloader{
+		Out:              ioutil.Discard,
+		Getters:          getters,
+		Keyring:          w.Keyring,
+		RepositoryConfig: settings.RepositoryConfig,
+		RepositoryCache:  settings.RepositoryCache,
+	}
+	if w.Verify {
+		c.Verify = downloader.VerifyAlways
+	}
+
+	ref := w.ChartDir
+	if w.Repository != "" {
+		chartURL, err := repo.FindChartInRepoURL(w.Repository, w.ChartDir, w.ChartVersion, "", "", "", getters)
+		if err != nil {
+			return "", err
+		}
+		ref = chartURL
+	}
+
+	archive, _, err := c.DownloadTo(ref, w.ChartVersion, destDir)
+	if err != nil {
+		return "", err
+	}
+	return archive, nil
+}
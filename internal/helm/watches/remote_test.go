@@ -0,0 +1,341 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package watches
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+const testChartDir = "../../../internal/plugins/helm/v1/chartutil/testdata/test-chart"
+
+// packageTestChart packages the test chart into dir and returns the path of
+// the chart archive.
+func packageTestChart(t *testing.T, dir string) string {
+	chrt, err := loader.LoadDir(testChartDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := chartutil.Save(chrt, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return archive
+}
+
+// newChartRepo returns a chart repository server serving the test chart.
+func newChartRepo(t *testing.T) *httptest.Server {
+	dir, err := ioutil.TempDir("", "chart-repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	packageTestChart(t, dir)
+
+	srv := httptest.NewServer(http.FileServer(http.Dir(dir)))
+	t.Cleanup(srv.Close)
+
+	index, err := repo.IndexDirectory(dir, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := index.WriteFile(filepath.Join(dir, "index.yaml"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+// newOCIRegistry returns a registry stand-in serving the test chart as
+// charts/test-chart:1.2.3.
+func newOCIRegistry(t *testing.T) *httptest.Server {
+	dir, err := ioutil.TempDir("", "oci-registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	content, err := ioutil.ReadFile(packageTestChart(t, dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	digestOf := func(b []byte) string {
+		sum := sha256.Sum256(b)
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+	config := []byte("{}")
+	manifest := []byte(fmt.Sprintf(`{"schemaVersion":2,`+
+		`"config":{"mediaType":"application/vnd.cncf.helm.config.v1+json","digest":%q,"size":%d},`+
+		`"layers":[{"mediaType":"application/tar+gzip","digest":%q,"size":%d}]}`,
+		digestOf(config), len(config), digestOf(content), len(content)))
+
+	mux := http.NewServeMux()
+	serve := func(p, mediaType string, b []byte) {
+		mux.HandleFunc(p, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", mediaType)
+			w.Header().Set("Content-Length", fmt.Sprint(len(b)))
+			if r.Method != http.MethodHead {
+				_, _ = w.Write(b)
+			}
+		})
+	}
+	const manifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	serve("/v2/charts/test-chart/manifests/1.2.3", manifestMediaType, manifest)
+	serve("/v2/charts/test-chart/manifests/"+digestOf(manifest), manifestMediaType, manifest)
+	serve("/v2/charts/test-chart/blobs/"+digestOf(config), "application/octet-stream", config)
+	serve("/v2/charts/test-chart/blobs/"+digestOf(content), "application/octet-stream", content)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetchCharts(t *testing.T) {
+	cacheHome, err := ioutil.TempDir("", "helm-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cacheHome)
+	defer os.Unsetenv("HELM_CACHE_HOME")
+	if err := os.Setenv("HELM_CACHE_HOME", cacheHome); err != nil {
+		t.Fatal(err)
+	}
+
+	chartRepo := newChartRepo(t)
+	registry := newOCIRegistry(t)
+	registryHost := strings.TrimPrefix(registry.URL, "http://")
+
+	keyring := filepath.Join(cacheHome, "pubring.gpg")
+	if err := ioutil.WriteFile(keyring, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name      string
+		data      string
+		expectErr bool
+	}{
+		{
+			name: "chart repository",
+			data: fmt.Sprintf(`---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: test-chart
+  repository: %s
+  chartVersion: 1.2.3
+`, chartRepo.URL),
+		},
+		{
+			name: "chart repository latest version",
+			data: fmt.Sprintf(`---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: test-chart
+  repository: %s
+`, chartRepo.URL),
+		},
+		{
+			name: "chart URL",
+			data: fmt.Sprintf(`---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: %s/test-chart-1.2.3.tgz
+`, chartRepo.URL),
+		},
+		{
+			name: "OCI chart",
+			data: fmt.Sprintf(`---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: oci://%s/charts/test-chart
+  chartVersion: 1.2.3
+`, registryHost),
+		},
+		{
+			name: "unknown chart version",
+			data: fmt.Sprintf(`---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: test-chart
+  repository: %s
+  chartVersion: 4.5.6
+`, chartRepo.URL),
+			expectErr: true,
+		},
+		{
+			name: "OCI chart without version",
+			data: fmt.Sprintf(`---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: oci://%s/charts/test-chart
+`, registryHost),
+			expectErr: true,
+		},
+		{
+			name: "OCI chart with verify",
+			data: fmt.Sprintf(`---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: oci://%s/charts/test-chart
+  chartVersion: 1.2.3
+  verify: true
+  keyring: %s
+`, registryHost, keyring),
+			expectErr: true,
+		},
+		{
+			name: "verify without keyring",
+			data: fmt.Sprintf(`---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: test-chart
+  repository: %s
+  chartVersion: 1.2.3
+  verify: true
+`, chartRepo.URL),
+			expectErr: true,
+		},
+		{
+			name: "verify without provenance file",
+			data: fmt.Sprintf(`---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: test-chart
+  repository: %s
+  chartVersion: 1.2.3
+  verify: true
+  keyring: %s
+`, chartRepo.URL, keyring),
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			watches, err := LoadReader(bytes.NewBufferString(tc.data))
+			if err == nil {
+				err = FetchCharts(watches)
+			}
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			if !assert.NoError(t, err) || !assert.Len(t, watches, 1) {
+				return
+			}
+			chartDir := watches[0].ChartDir
+			assert.True(t, strings.HasPrefix(chartDir, cacheHome), "chart %s not in cache %s", chartDir, cacheHome)
+			chrt, err := loader.LoadDir(chartDir)
+			assert.NoError(t, err)
+			assert.Equal(t, "test-chart", chrt.Name())
+			assert.Equal(t, "1.2.3", chrt.Metadata.Version)
+		})
+	}
+}
+
+func TestFetchChartsRefetch(t *testing.T) {
+	cacheHome, err := ioutil.TempDir("", "helm-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(cacheHome)
+	defer os.Unsetenv("HELM_CACHE_HOME")
+	if err := os.Setenv("HELM_CACHE_HOME", cacheHome); err != nil {
+		t.Fatal(err)
+	}
+
+	data := fmt.Sprintf(`---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: %s/test-chart-1.2.3.tgz
+`, newChartRepo(t).URL)
+
+	// Fetching the charts of the same watches file twice must replace the
+	// cached chart.
+	first, err := LoadReader(bytes.NewBufferString(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := FetchCharts(first); err != nil {
+		t.Fatal(err)
+	}
+	second, err := LoadReader(bytes.NewBufferString(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := FetchCharts(second); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, first[0].ChartDir, second[0].ChartDir)
+	_, err = chartutil.IsChartDir(second[0].ChartDir)
+	assert.NoError(t, err)
+}
+
+func TestLoadReaderRemoteChartNotFetched(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+	chartURL := srv.URL + "/test-chart-1.2.3.tgz"
+
+	// Remote charts are not fetched while the watches are verified.
+	watches, err := LoadReader(bytes.NewBufferString(fmt.Sprintf(`---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: %s
+`, chartURL)))
+	assert.NoError(t, err)
+	if assert.Len(t, watches, 1) {
+		assert.Equal(t, chartURL, watches[0].ChartDir)
+	}
+
+	// Invalid watches files are rejected before any chart is fetched.
+	_, err = LoadReader(bytes.NewBufferString(fmt.Sprintf(`---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: %s
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: %s
+`, chartURL, testChartDir)))
+	assert.EqualError(t, err, "duplicate GVK: mygroup/v1alpha1, Kind=MyKind")
+	assert.Equal(t, 0, requests)
+}
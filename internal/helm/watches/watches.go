@@ -20,14 +20,28 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"strings"
 
 	"helm.sh/helm/v3/pkg/chartutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"sigs.k8s.io/yaml"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/release"
 )
 
 const WatchesFile = "watches.yaml"
 
+const (
+	// OnFailureRetry retries a failed upgrade on the next reconciliation.
+	// This is the default upgrade failure policy.
+	OnFailureRetry = "retry"
+	// OnFailureRollback rolls a release back to its last deployed revision
+	// when an upgrade fails.
+	OnFailureRollback = "rollback"
+)
+
 // Watch defines options for configuring a watch for a Helm-based
 // custom resource.
 type Watch struct {
@@ -35,6 +49,82 @@ type Watch struct {
 	ChartDir                string            `json:"chart"`
 	WatchDependentResources *bool             `json:"watchDependentResources,omitempty"`
 	OverrideValues          map[string]string `json:"overrideValues,omitempty"`
+	OnFailure               string            `json:"onFailure,omitempty"`
+	MaxHistory              int               `json:"maxHistory,omitempty"`
+	// Wait reports releases as deployed only once their resources are
+	// ready, or fails them after Timeout. Atomic implies Wait, and also
+	// rolls back upgrades and uninstalls installs that fail or time out.
+	Wait    bool             `json:"wait,omitempty"`
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	Atomic  bool             `json:"atomic,omitempty"`
+	// Selector restricts the custom resources reconciled by the operator to
+	// those matching the label selector.
+	Selector metav1.LabelSelector `json:"selector,omitempty"`
+	// ReconcilePeriod and MaxConcurrentReconciles override the values set
+	// by the operator's flags for this watch.
+	ReconcilePeriod         *metav1.Duration `json:"reconcilePeriod,omitempty"`
+	MaxConcurrentReconciles int              `json:"maxConcurrentReconciles,omitempty"`
+	// ServerSideApply reconciles release resources with server-side apply
+	// instead of patches. Installs and upgrades are not affected.
+	ServerSideApply bool `json:"serverSideApply,omitempty"`
+	// Blacklist lists the GVKs of dependent resources that are not watched.
+	Blacklist []schema.GroupVersionKind `json:"blacklist,omitempty"`
+
+	// ValuesFrom are ConfigMaps and Secrets in the namespace of each custom
+	// resource containing values merged over the custom resource's spec.
+	ValuesFrom []release.ValuesSource `json:"valuesFrom,omitempty"`
+
+	// TargetNamespace is the namespace in which releases are installed,
+	// instead of the namespace of their custom resource. It is required for
+	// cluster-scoped custom resources, unless TargetNamespaceFromSpec is set.
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+	// TargetNamespaceFromSpec is a top-level field of the spec of custom
+	// resources which, when set, overrides TargetNamespace.
+	TargetNamespaceFromSpec string `json:"targetNamespaceFromSpec,omitempty"`
+
+	// TargetRevisionFromSpec is a top-level field of the spec of custom
+	// resources which, when set, pins their release to a revision. It is not
+	// passed to the chart as a value.
+	TargetRevisionFromSpec string `json:"targetRevisionFromSpec,omitempty"`
+
+	// PostRenderer modifies the manifests rendered from the chart before
+	// they are installed or upgraded.
+	PostRenderer *release.PostRendererConfig `json:"postRenderer,omitempty"`
+
+	// Charts compose each custom resource of several charts, installed as
+	// separate releases in order. It is mutually exclusive with ChartDir.
+	Charts []Chart `json:"charts,omitempty"`
+
+	// Repository is the URL of the chart repository containing the chart.
+	Repository string `json:"repository,omitempty"`
+	// ChartVersion is the version of a remote chart. It is required for OCI
+	// charts.
+	ChartVersion string `json:"chartVersion,omitempty"`
+	// Verify enables provenance verification of charts fetched from chart
+	// repositories, using the public keys in Keyring.
+	Verify  bool   `json:"verify,omitempty"`
+	Keyring string `json:"keyring,omitempty"`
+}
+
+// Chart is one of the charts composing the custom resources of a watch.
+type Chart struct {
+	// Name identifies the chart in the watch. The release of the chart is
+	// named after the custom resource, suffixed with Name.
+	Name     string `json:"name"`
+	ChartDir string `json:"chart"`
+	// DependsOn lists the names of charts, listed before this chart, whose
+	// releases must be deployed and ready before this chart's release is
+	// installed or upgraded.
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// ReleaseNamespace returns the namespace in which the releases of the watch
+// are installed.
+func (w Watch) ReleaseNamespace() release.TargetNamespace {
+	return release.TargetNamespace{
+		Name:      w.TargetNamespace,
+		SpecField: w.TargetNamespaceFromSpec,
+	}
 }
 
 // UnmarshalYAML unmarshals an individual watch from the Helm watches.yaml file
@@ -78,6 +168,9 @@ func Load(path string) ([]Watch, error) {
 // LoadReader loads a slice of Watches from the provided reader. For each entry
 // in the watches file, it verifies the configuration. If an error is
 // encountered reading or verifying the configuration, it will be returned.
+//
+// Remote charts are not fetched, so their ChartDir is not a local directory
+// until FetchCharts is called.
 func LoadReader(reader io.Reader) ([]Watch, error) {
 	b, err := ioutil.ReadAll(reader)
 	if err != nil {
@@ -98,10 +191,46 @@ func LoadReader(reader io.Reader) ([]Watch, error) {
 			return nil, fmt.Errorf("invalid GVK: %s: %w", gvk, err)
 		}
 
-		if _, err := chartutil.IsChartDir(w.ChartDir); err != nil {
+		if len(w.Charts) > 0 {
+			if err := verifyCharts(w); err != nil {
+				return nil, fmt.Errorf("invalid charts for GVK %s: %w", gvk, err)
+			}
+		} else if isRemoteChart(w) {
+			if err := verifyRemoteChart(w); err != nil {
+				return nil, fmt.Errorf("invalid remote chart %s: %w", w.ChartDir, err)
+			}
+		} else if _, err := chartutil.IsChartDir(w.ChartDir); err != nil {
 			return nil, fmt.Errorf("invalid chart directory %s: %w", w.ChartDir, err)
 		}
 
+		if err := verifyFailurePolicy(w); err != nil {
+			return nil, fmt.Errorf("invalid failure policy for GVK %s: %w", gvk, err)
+		}
+
+		if err := verifyReconcileOptions(w); err != nil {
+			return nil, fmt.Errorf("invalid reconcile options for GVK %s: %w", gvk, err)
+		}
+
+		for _, s := range w.ValuesFrom {
+			if err := s.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid valuesFrom for GVK %s: %w", gvk, err)
+			}
+		}
+
+		if err := w.ReleaseNamespace().Validate(); err != nil {
+			return nil, fmt.Errorf("invalid target namespace for GVK %s: %w", gvk, err)
+		}
+
+		if err := verifyTargetRevision(w); err != nil {
+			return nil, fmt.Errorf("invalid target revision for GVK %s: %w", gvk, err)
+		}
+
+		if w.PostRenderer != nil {
+			if err := w.PostRenderer.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid postRenderer for GVK %s: %w", gvk, err)
+			}
+		}
+
 		if _, ok := watchesMap[gvk]; ok {
 			return nil, fmt.Errorf("duplicate GVK: %s", gvk)
 		}
@@ -110,6 +239,11 @@ func LoadReader(reader io.Reader) ([]Watch, error) {
 			trueVal := true
 			w.WatchDependentResources = &trueVal
 		}
+		// Releases whose resources are not ready in time are rolled back to
+		// their previous revision, which must be kept in the release storage.
+		if w.Atomic && w.MaxHistory == 0 {
+			w.MaxHistory = 2
+		}
 		w.OverrideValues = expandOverrideEnvs(w.OverrideValues)
 		watches[i] = w
 	}
@@ -139,3 +273,83 @@ func verifyGVK(gvk schema.GroupVersionKind) error {
 	}
 	return nil
 }
+
+func verifyCharts(w Watch) error {
+	if w.ChartDir != "" || isRemoteChart(w) {
+		return errors.New("chart and repository must not be set when charts are set")
+	}
+	names := make(map[string]struct{}, len(w.Charts))
+	for _, c := range w.Charts {
+		if errs := validation.IsDNS1123Label(c.Name); len(errs) > 0 {
+			return fmt.Errorf("invalid chart name %q: %s", c.Name, strings.Join(errs, ", "))
+		}
+		if _, ok := names[c.Name]; ok {
+			return fmt.Errorf("duplicate chart name %q", c.Name)
+		}
+		for _, dep := range c.DependsOn {
+			if _, ok := names[dep]; !ok {
+				return fmt.Errorf("chart %q depends on %q, which is not listed before it", c.Name, dep)
+			}
+		}
+		if _, err := chartutil.IsChartDir(c.ChartDir); err != nil {
+			return fmt.Errorf("invalid chart directory %s: %w", c.ChartDir, err)
+		}
+		names[c.Name] = struct{}{}
+	}
+	return nil
+}
+
+func verifyFailurePolicy(w Watch) error {
+	switch w.OnFailure {
+	case "", OnFailureRetry, OnFailureRollback:
+	default:
+		return fmt.Errorf("onFailure must be one of %q or %q, got %q", OnFailureRetry, OnFailureRollback, w.OnFailure)
+	}
+	if w.MaxHistory < 0 {
+		return fmt.Errorf("maxHistory must not be negative, got %d", w.MaxHistory)
+	}
+	if w.Atomic && w.OnFailure == OnFailureRetry {
+		return fmt.Errorf("onFailure must not be %q when atomic is set", OnFailureRetry)
+	}
+	if w.Atomic && w.MaxHistory == 1 {
+		return errors.New("maxHistory must be at least 2 when atomic is set")
+	}
+	if w.Timeout != nil && w.Timeout.Duration <= 0 {
+		return fmt.Errorf("timeout must be positive, got %s", w.Timeout.Duration)
+	}
+	return nil
+}
+
+func verifyTargetRevision(w Watch) error {
+	if w.TargetRevisionFromSpec == "" {
+		return nil
+	}
+	if strings.Contains(w.TargetRevisionFromSpec, ".") {
+		return fmt.Errorf("spec field %q must be a top-level field", w.TargetRevisionFromSpec)
+	}
+	if w.TargetRevisionFromSpec == w.TargetNamespaceFromSpec {
+		return fmt.Errorf("spec field %q is also targetNamespaceFromSpec", w.TargetRevisionFromSpec)
+	}
+	if len(w.Charts) > 0 {
+		return errors.New("targetRevisionFromSpec is not supported with charts")
+	}
+	return nil
+}
+
+func verifyReconcileOptions(w Watch) error {
+	if _, err := metav1.LabelSelectorAsSelector(&w.Selector); err != nil {
+		return fmt.Errorf("invalid selector: %w", err)
+	}
+	if w.ReconcilePeriod != nil && w.ReconcilePeriod.Duration < 0 {
+		return fmt.Errorf("reconcilePeriod must not be negative, got %s", w.ReconcilePeriod.Duration)
+	}
+	if w.MaxConcurrentReconciles < 0 {
+		return fmt.Errorf("maxConcurrentReconciles must not be negative, got %d", w.MaxConcurrentReconciles)
+	}
+	for _, gvk := range w.Blacklist {
+		if err := verifyGVK(gvk); err != nil {
+			return fmt.Errorf("invalid blacklist GVK %s: %w", gvk, err)
+		}
+	}
+	return nil
+}
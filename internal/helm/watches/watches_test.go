@@ -19,9 +19,13 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/release"
 )
 
 func TestLoadReader(t *testing.T) {
@@ -102,6 +106,410 @@ func TestLoadReader(t *testing.T) {
 			},
 			expectErr: false,
 		},
+		{
+			name: "valid with rollback on failure",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  onFailure: rollback
+  maxHistory: 5
+`,
+			expectWatches: []Watch{
+				{
+					GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyKind"},
+					ChartDir:                "../../../internal/plugins/helm/v1/chartutil/testdata/test-chart",
+					WatchDependentResources: &trueVal,
+					OnFailure:               OnFailureRollback,
+					MaxHistory:              5,
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "valid with wait options",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  wait: true
+  timeout: 10m
+  atomic: true
+`,
+			expectWatches: []Watch{
+				{
+					GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyKind"},
+					ChartDir:                "../../../internal/plugins/helm/v1/chartutil/testdata/test-chart",
+					WatchDependentResources: &trueVal,
+					Wait:                    true,
+					Timeout:                 &metav1.Duration{Duration: 10 * time.Minute},
+					Atomic:                  true,
+					MaxHistory:              2,
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "atomic with retry on failure",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  onFailure: retry
+  atomic: true
+`,
+			expectErr: true,
+		},
+		{
+			name: "atomic with maxHistory of 1",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  atomic: true
+  maxHistory: 1
+`,
+			expectErr: true,
+		},
+		{
+			name: "valid with target revision from spec",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  targetRevisionFromSpec: targetRevision
+`,
+			expectWatches: []Watch{
+				{
+					GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyKind"},
+					ChartDir:                "../../../internal/plugins/helm/v1/chartutil/testdata/test-chart",
+					WatchDependentResources: &trueVal,
+					TargetRevisionFromSpec:  "targetRevision",
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "target revision from nested spec field",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  targetRevisionFromSpec: release.revision
+`,
+			expectErr: true,
+		},
+		{
+			name: "target revision from target namespace spec field",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  targetNamespaceFromSpec: namespace
+  targetRevisionFromSpec: namespace
+`,
+			expectErr: true,
+		},
+		{
+			name: "zero timeout",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  wait: true
+  timeout: 0s
+`,
+			expectErr: true,
+		},
+		{
+			name: "invalid onFailure",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  onFailure: uninstall
+`,
+			expectErr: true,
+		},
+		{
+			name: "valid with values from sources",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  valuesFrom:
+  - kind: ConfigMap
+    name: my-values
+  - kind: Secret
+    name: my-credentials
+    valuesKey: credentials.yaml
+    optional: true
+`,
+			expectWatches: []Watch{
+				{
+					GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyKind"},
+					ChartDir:                "../../../internal/plugins/helm/v1/chartutil/testdata/test-chart",
+					WatchDependentResources: &trueVal,
+					ValuesFrom: []release.ValuesSource{
+						{Kind: "ConfigMap", Name: "my-values"},
+						{Kind: "Secret", Name: "my-credentials", ValuesKey: "credentials.yaml", Optional: true},
+					},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "invalid values from kind",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  valuesFrom:
+  - kind: Pod
+    name: my-values
+`,
+			expectErr: true,
+		},
+		{
+			name: "values from without name",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  valuesFrom:
+  - kind: Secret
+`,
+			expectErr: true,
+		},
+		{
+			name: "valid with reconcile options",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  selector:
+    matchLabels:
+      app: myapp
+    matchExpressions:
+    - key: tier
+      operator: In
+      values: [frontend]
+  reconcilePeriod: 30s
+  maxConcurrentReconciles: 4
+  serverSideApply: true
+  blacklist:
+  - group: apps
+    version: v1
+    kind: ReplicaSet
+`,
+			expectWatches: []Watch{
+				{
+					GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyKind"},
+					ChartDir:                "../../../internal/plugins/helm/v1/chartutil/testdata/test-chart",
+					WatchDependentResources: &trueVal,
+					Selector: metav1.LabelSelector{
+						MatchLabels: map[string]string{"app": "myapp"},
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"frontend"}},
+						},
+					},
+					ReconcilePeriod:         &metav1.Duration{Duration: 30 * time.Second},
+					MaxConcurrentReconciles: 4,
+					ServerSideApply:         true,
+					Blacklist:               []schema.GroupVersionKind{{Group: "apps", Version: "v1", Kind: "ReplicaSet"}},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "invalid selector",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  selector:
+    matchExpressions:
+    - key: tier
+      operator: Unknown
+`,
+			expectErr: true,
+		},
+		{
+			name: "negative reconcilePeriod",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  reconcilePeriod: -1m
+`,
+			expectErr: true,
+		},
+		{
+			name: "negative maxConcurrentReconciles",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  maxConcurrentReconciles: -1
+`,
+			expectErr: true,
+		},
+		{
+			name: "invalid blacklist GVK",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  blacklist:
+  - group: apps
+    kind: ReplicaSet
+`,
+			expectErr: true,
+		},
+		{
+			name: "invalid postRenderer",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  postRenderer:
+    kustomize: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+    exec: /bin/cat
+`,
+			expectErr: true,
+		},
+		{
+			name: "valid with charts",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  charts:
+  - name: crds
+    chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  - name: core
+    chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+    dependsOn: [crds]
+`,
+			expectWatches: []Watch{
+				{
+					GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyKind"},
+					WatchDependentResources: &trueVal,
+					Charts: []Chart{
+						{Name: "crds", ChartDir: "../../../internal/plugins/helm/v1/chartutil/testdata/test-chart"},
+						{
+							Name:      "core",
+							ChartDir:  "../../../internal/plugins/helm/v1/chartutil/testdata/test-chart",
+							DependsOn: []string{"crds"},
+						},
+					},
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "chart and charts",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  charts:
+  - name: core
+    chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+`,
+			expectErr: true,
+		},
+		{
+			name: "duplicate chart name",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  charts:
+  - name: core
+    chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  - name: core
+    chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+`,
+			expectErr: true,
+		},
+		{
+			name: "chart depends on later chart",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  charts:
+  - name: core
+    chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+    dependsOn: [addons]
+  - name: addons
+    chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+`,
+			expectErr: true,
+		},
+		{
+			name: "valid with target namespace",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  targetNamespace: my-namespace
+  targetNamespaceFromSpec: namespace
+`,
+			expectWatches: []Watch{
+				{
+					GroupVersionKind:        schema.GroupVersionKind{Group: "mygroup", Version: "v1alpha1", Kind: "MyKind"},
+					ChartDir:                "../../../internal/plugins/helm/v1/chartutil/testdata/test-chart",
+					WatchDependentResources: &trueVal,
+					TargetNamespace:         "my-namespace",
+					TargetNamespaceFromSpec: "namespace",
+				},
+			},
+			expectErr: false,
+		},
+		{
+			name: "invalid target namespace",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  targetNamespace: My_Namespace
+`,
+			expectErr: true,
+		},
+		{
+			name: "negative maxHistory",
+			data: `---
+- group: mygroup
+  version: v1alpha1
+  kind: MyKind
+  chart: ../../../internal/plugins/helm/v1/chartutil/testdata/test-chart
+  maxHistory: -1
+`,
+			expectErr: true,
+		},
 		{
 			name: "duplicate gvk",
 			data: `---
@@ -0,0 +1,130 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package webhook provides a validating admission webhook that rejects custom
+// resources whose values cannot be used to install the chart of their watch.
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	crwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/release"
+)
+
+var log = logf.Log.WithName("helm.webhook")
+
+// Options contains the values needed to validate custom resources of a GVK
+// watched by the helm operator. Custom resources are validated against each
+// of ChartDirs, the charts whose releases are installed for them.
+type Options struct {
+	GVK            schema.GroupVersionKind
+	ChartDirs      []string
+	OverrideValues map[string]string
+	ValuesFrom     []release.ValuesSource
+	// TargetNamespace is the namespace in which the charts are rendered.
+	TargetNamespace release.TargetNamespace
+	// IgnoredSpecFields are fields of the spec that are not chart values.
+	IgnoredSpecFields []string
+}
+
+// ValidatePath returns the path at which custom resources of gvk are
+// validated. It follows the path convention of kubebuilder webhooks.
+func ValidatePath(gvk schema.GroupVersionKind) string {
+	return fmt.Sprintf("/validate-%s-%s-%s",
+		strings.ReplaceAll(gvk.Group, ".", "-"), gvk.Version, strings.ToLower(gvk.Kind))
+}
+
+// Add registers a validating admission webhook for custom resources of
+// options.GVK with the webhook server of mgr.
+func Add(mgr manager.Manager, options Options) {
+	path := ValidatePath(options.GVK)
+	mgr.GetWebhookServer().Register(path, &crwebhook.Admission{
+		Handler: newValidator(mgr.GetAPIReader(), options),
+	})
+	log.Info("Validating resource", "apiVersion", options.GVK.GroupVersion(), "kind", options.GVK.Kind,
+		"path", path)
+}
+
+// validator validates the values of custom resources against their charts.
+type validator struct {
+	client     client.Reader
+	chartDirs  []string
+	validators []*release.Validator
+	valuesFrom []release.ValuesSource
+}
+
+func newValidator(c client.Reader, options Options) *validator {
+	v := &validator{
+		client:     c,
+		chartDirs:  options.ChartDirs,
+		valuesFrom: options.ValuesFrom,
+	}
+	for _, chartDir := range options.ChartDirs {
+		v.validators = append(v.validators, release.NewValidator(chartDir, options.OverrideValues,
+			options.TargetNamespace, options.IgnoredSpecFields))
+	}
+	return v
+}
+
+// Handle denies the creation or update of a custom resource if its values,
+// merged with the values of its values sources and the override values,
+// do not meet the specifications of the chart's values schema, or if the
+// chart cannot be rendered with them.
+func (v *validator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	cr := &unstructured.Unstructured{}
+	if err := cr.UnmarshalJSON(req.Object.Raw); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	// Resources being deleted are uninstalled, not rendered, so their values
+	// do not matter. Removing the uninstall finalizer must always be allowed.
+	if cr.GetDeletionTimestamp() != nil {
+		return admission.Allowed("")
+	}
+	if cr.GetNamespace() == "" {
+		cr.SetNamespace(req.Namespace)
+	}
+
+	sourceValues, err := release.LoadValuesFrom(ctx, v.client, cr.GetNamespace(), v.valuesFrom)
+	if err != nil {
+		// Values sources may be created after the custom resource, so do not
+		// reject it. The reconciler reports the error if the sources are
+		// still missing when the release is installed.
+		return admission.Allowed("").WithWarnings(fmt.Sprintf("values were not validated: %v", err))
+	}
+	for i, validator := range v.validators {
+		if err := validator.Validate(cr, sourceValues); err != nil {
+			if len(v.validators) > 1 {
+				err = fmt.Errorf("chart %s: %w", v.chartDirs[i], err)
+			}
+			return admission.Denied(fmt.Sprintf("invalid values: %v", err))
+		}
+	}
+	return admission.Allowed("")
+}
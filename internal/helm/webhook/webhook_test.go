@@ -0,0 +1,133 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/operator-framework/operator-sdk/internal/helm/release"
+)
+
+const testChartDir = "../../plugins/helm/v1/chartutil/testdata/test-chart"
+
+func TestValidatePath(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "cache.example.com", Version: "v1alpha1", Kind: "Memcached"}
+	assert.Equal(t, "/validate-cache-example-com-v1alpha1-memcached", ValidatePath(gvk))
+}
+
+func TestHandle(t *testing.T) {
+	c := fakeclient.NewClientBuilder().WithObjects(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "valid", Namespace: "ns"},
+			Data:       map[string]string{"values.yaml": "replicaCount: 2\n"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "invalid", Namespace: "ns"},
+			Data:       map[string]string{"values.yaml": "image: nginx\n"},
+		},
+	).Build()
+
+	newRequest := func(op admissionv1.Operation, obj map[string]interface{}) admission.Request {
+		raw, err := json.Marshal(obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: op,
+			Namespace: "ns",
+			Object:    runtime.RawExtension{Raw: raw},
+		}}
+	}
+	newCR := func(spec map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"apiVersion": "example.com/v1",
+			"kind":       "Test",
+			"metadata":   map[string]interface{}{"name": "test"},
+			"spec":       spec,
+		}
+	}
+	deleted := newCR(map[string]interface{}{"image": "nginx"})
+	deleted["metadata"] = map[string]interface{}{"name": "test", "deletionTimestamp": "2021-01-01T00:00:00Z"}
+
+	testCases := []struct {
+		name          string
+		valuesFrom    []release.ValuesSource
+		req           admission.Request
+		expectAllowed bool
+		expectWarning bool
+	}{
+		{
+			name:          "valid create",
+			req:           newRequest(admissionv1.Create, newCR(map[string]interface{}{"replicaCount": 2})),
+			expectAllowed: true,
+		},
+		{
+			name: "invalid create",
+			req:  newRequest(admissionv1.Create, newCR(map[string]interface{}{"image": "nginx"})),
+		},
+		{
+			name: "invalid update",
+			req:  newRequest(admissionv1.Update, newCR(map[string]interface{}{"image": "nginx"})),
+		},
+		{
+			name:          "delete",
+			req:           newRequest(admissionv1.Delete, newCR(map[string]interface{}{"image": "nginx"})),
+			expectAllowed: true,
+		},
+		{
+			name:          "update of deleted resource",
+			req:           newRequest(admissionv1.Update, deleted),
+			expectAllowed: true,
+		},
+		{
+			name:          "valid values source",
+			valuesFrom:    []release.ValuesSource{{Kind: release.ValuesSourceConfigMap, Name: "valid"}},
+			req:           newRequest(admissionv1.Create, newCR(map[string]interface{}{})),
+			expectAllowed: true,
+		},
+		{
+			name:       "invalid values source",
+			valuesFrom: []release.ValuesSource{{Kind: release.ValuesSourceConfigMap, Name: "invalid"}},
+			req:        newRequest(admissionv1.Create, newCR(map[string]interface{}{})),
+		},
+		{
+			name:          "missing values source",
+			valuesFrom:    []release.ValuesSource{{Kind: release.ValuesSourceConfigMap, Name: "missing"}},
+			req:           newRequest(admissionv1.Create, newCR(map[string]interface{}{"image": "nginx"})),
+			expectAllowed: true,
+			expectWarning: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := newValidator(c, Options{ChartDirs: []string{testChartDir}, ValuesFrom: tc.valuesFrom})
+			resp := v.Handle(context.TODO(), tc.req)
+			assert.Equal(t, tc.expectAllowed, resp.Allowed)
+			assert.Equal(t, tc.expectWarning, len(resp.Warnings) > 0)
+		})
+	}
+}
@@ -31,14 +31,16 @@ var (
 )
 
 var (
-	_ plugin.Plugin    = Plugin{}
-	_ plugin.Init      = Plugin{}
-	_ plugin.CreateAPI = Plugin{}
+	_ plugin.Plugin        = Plugin{}
+	_ plugin.Init          = Plugin{}
+	_ plugin.CreateAPI     = Plugin{}
+	_ plugin.CreateWebhook = Plugin{}
 )
 
 type Plugin struct {
 	initSubcommand
 	createAPISubcommand
+	createWebhookSubcommand
 }
 
 func (Plugin) Name() string                                         { return pluginName }
@@ -46,3 +48,6 @@ func (Plugin) Version() plugin.Version                              { return plu
 func (Plugin) SupportedProjectVersions() []config.Version           { return supportedProjectVersions }
 func (p Plugin) GetInitSubcommand() plugin.InitSubcommand           { return &p.initSubcommand }
 func (p Plugin) GetCreateAPISubcommand() plugin.CreateAPISubcommand { return &p.createAPISubcommand }
+func (p Plugin) GetCreateWebhookSubcommand() plugin.CreateWebhookSubcommand {
+	return &p.createWebhookSubcommand
+}
@@ -131,13 +131,23 @@ rules:
   - namespaces
   verbs:
   - get
-# We need to manage Helm release secrets
+# We need to manage Helm release secrets, and to get, list and watch the
+# secrets of valuesFrom sources
 - apiGroups:
   - ""
   resources:
   - secrets
   verbs:
   - "*"
+# We need to get, list and watch the configmaps of valuesFrom sources
+- apiGroups:
+  - ""
+  resources:
+  - configmaps
+  verbs:
+  - get
+  - list
+  - watch
 # We need to create events on CRs about things happening during reconciliation
 - apiGroups:
   - ""
@@ -0,0 +1,74 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scaffolds
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/kubebuilder/v3/pkg/config"
+	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+	"sigs.k8s.io/kubebuilder/v3/pkg/model/resource"
+	"sigs.k8s.io/kubebuilder/v3/pkg/plugins"
+
+	"github.com/operator-framework/operator-sdk/internal/plugins/helm/v1/scaffolds/internal/templates/config/webhook"
+)
+
+var _ plugins.Scaffolder = &webhookScaffolder{}
+
+// webhookScaffolder scaffolds the configuration of the validating webhook
+// served by the helm operator for a resource.
+type webhookScaffolder struct {
+	fs machinery.Filesystem
+
+	config   config.Config
+	resource resource.Resource
+}
+
+// NewWebhookScaffolder returns a new plugins.Scaffolder for webhook creation operations
+func NewWebhookScaffolder(cfg config.Config, res resource.Resource) plugins.Scaffolder {
+	return &webhookScaffolder{
+		config:   cfg,
+		resource: res,
+	}
+}
+
+// InjectFS implements plugins.Scaffolder
+func (s *webhookScaffolder) InjectFS(fs machinery.Filesystem) {
+	s.fs = fs
+}
+
+// Scaffold implements plugins.Scaffolder
+func (s *webhookScaffolder) Scaffold() error {
+	if err := s.config.UpdateResource(s.resource); err != nil {
+		return err
+	}
+
+	// Initialize the machinery.Scaffold that will write the files to disk
+	scaffold := machinery.NewScaffold(s.fs,
+		// NOTE: kubebuilder's default permissions are only for root users
+		machinery.WithDirectoryPermissions(0755),
+		machinery.WithFilePermissions(0644),
+		machinery.WithConfig(s.config),
+		machinery.WithResource(&s.resource),
+	)
+
+	if err := scaffold.Execute(
+		&webhook.Manifests{},
+	); err != nil {
+		return fmt.Errorf("error scaffolding webhook manifests: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,161 @@
+// Copyright 2021 The Operator-SDK Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/kubebuilder/v3/pkg/config"
+	"sigs.k8s.io/kubebuilder/v3/pkg/machinery"
+	"sigs.k8s.io/kubebuilder/v3/pkg/model/resource"
+	"sigs.k8s.io/kubebuilder/v3/pkg/plugin"
+
+	"github.com/operator-framework/operator-sdk/internal/plugins/helm/v1/scaffolds"
+	sdkutil "github.com/operator-framework/operator-sdk/internal/util"
+)
+
+const (
+	enableWebhookArg = "--enable-validating-webhook"
+
+	defaultWebhookVersion = "v1"
+)
+
+var _ plugin.CreateWebhookSubcommand = &createWebhookSubcommand{}
+
+type createWebhookSubcommand struct {
+	config   config.Config
+	resource *resource.Resource
+
+	// For help text.
+	commandName string
+}
+
+func (p *createWebhookSubcommand) UpdateMetadata(cliMeta plugin.CLIMetadata, subcmdMeta *plugin.SubcommandMetadata) {
+	subcmdMeta.Description = `Scaffold the configuration of a validating webhook for a Helm-backed API.
+
+The webhook rejects custom resources whose values, merged with the override
+values and values sources of their watch, do not meet the specifications of
+the chart's values.schema.json, or with which the chart cannot be rendered.
+
+Writes the following files:
+- a ValidatingWebhookConfiguration entry for the API in config/webhook/manifests.yaml
+- the webhook service and cert-manager configuration in config/webhook and config/certmanager
+
+It also adds the ` + enableWebhookArg + ` flag to the manager arguments.
+To deploy the webhook, uncomment the [WEBHOOK] and [CERTMANAGER] sections of
+config/default/kustomization.yaml.
+`
+	subcmdMeta.Examples = fmt.Sprintf(`  $ %s create webhook \
+      --group=apps --version=v1alpha1 \
+      --kind=AppService
+`, cliMeta.CommandName)
+
+	p.commandName = cliMeta.CommandName
+}
+
+func (p *createWebhookSubcommand) InjectConfig(c config.Config) error {
+	p.config = c
+
+	return nil
+}
+
+func (p *createWebhookSubcommand) InjectResource(res *resource.Resource) error {
+	p.resource = res
+
+	existing, err := p.config.GetResource(p.resource.GVK)
+	if err != nil || !existing.HasAPI() {
+		return fmt.Errorf("%s create webhook requires a previously created API", p.commandName)
+	}
+	if existing.Webhooks != nil && existing.Webhooks.Validation {
+		return errors.New("the validating webhook already exists")
+	}
+
+	if p.resource.Plural == "" {
+		p.resource.Plural = existing.Plural
+	}
+	p.resource.Webhooks = &resource.Webhooks{
+		WebhookVersion: defaultWebhookVersion,
+		Validation:     true,
+	}
+
+	return p.resource.Validate()
+}
+
+func (p *createWebhookSubcommand) Scaffold(fs machinery.Filesystem) error {
+	if err := addWebhookCustomizations(); err != nil {
+		return fmt.Errorf("error updating webhook manifests: %v", err)
+	}
+
+	scaffolder := scaffolds.NewWebhookScaffolder(p.config, *p.resource)
+	scaffolder.InjectFS(fs)
+	return scaffolder.Scaffold()
+}
+
+// addWebhookCustomizations will perform the required customizations for this plugin
+// on the webhook manifests scaffolded by the common base
+func addWebhookCustomizations() error {
+	// Serve the validating webhook from the manager.
+	err := insertArgOnce(filepath.Join("config", "manager", "manager.yaml"),
+		"- --leader-elect",
+		fmt.Sprintf("\n        - %s", enableWebhookArg))
+	if err != nil {
+		return err
+	}
+	err = insertArgOnce(filepath.Join("config", "default", "manager_auth_proxy_patch.yaml"),
+		"- \"--leader-elect\"",
+		fmt.Sprintf("\n        - \"%s\"", enableWebhookArg))
+	if err != nil {
+		return err
+	}
+
+	// Helm operators do not serve mutating webhooks, so the CA injection patch
+	// of the mutating webhook configuration would have no target.
+	caInjectionPatch := filepath.Join("config", "default", "webhookcainjection_patch.yaml")
+	b, err := ioutil.ReadFile(caInjectionPatch)
+	if err != nil {
+		return err
+	}
+	if strings.Contains(string(b), mutatingCAInjectionPatch) {
+		return sdkutil.ReplaceInFile(caInjectionPatch, mutatingCAInjectionPatch, "")
+	}
+
+	return nil
+}
+
+// insertArgOnce inserts code after target in the file at path, unless the
+// file already contains enableWebhookArg.
+func insertArgOnce(path, target, code string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if strings.Contains(string(b), enableWebhookArg) {
+		return nil
+	}
+	return sdkutil.InsertCode(path, target, code)
+}
+
+const mutatingCAInjectionPatch = `apiVersion: admissionregistration.k8s.io/` + defaultWebhookVersion + `
+kind: MutatingWebhookConfiguration
+metadata:
+  name: mutating-webhook-configuration
+  annotations:
+    cert-manager.io/inject-ca-from: $(CERTIFICATE_NAMESPACE)/$(CERTIFICATE_NAME)
+---
+`